@@ -0,0 +1,780 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// カラーIDと色名のマッピング
+var colorNames = map[string]string{
+	"1":  "薄紫",
+	"2":  "緑",
+	"3":  "紫",
+	"4":  "赤",
+	"5":  "黄",
+	"6":  "オレンジ",
+	"7":  "水色",
+	"8":  "グレー",
+	"9":  "青紫",
+	"10": "バジル",
+	"11": "トマト",
+}
+
+// 1日の始まり（--day-start、0時からの経過時間）
+var dayStart time.Duration
+
+// 日付を指定して1日分の予定を表示する（デフォルトのコマンド）
+func setupAgenda(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Date to fetch events (format: YYYY-MM-DD, or \"YYYY-MM-DD HH:MM\" to show events from that time)")
+	fromTime := fs.String("from-time", "", "Show only events that end after this time of day (HH:MM, or now)")
+	toTime := fs.String("to-time", "", "Show only events that start before this time of day (HH:MM)")
+	failOnEmpty := fs.Bool("fail-on-empty", false, "Exit with status 3 when there are no events")
+	nextBusiness := fs.Bool("next-business-day", false, "Show the next business day after --date (skipping weekends and holidays)")
+	withTomorrow := fs.Bool("with-tomorrow", false, "Also show the day after --date, fetched in the same request")
+	autoTomorrow := fs.String("auto-tomorrow", "", "Without --date, show tomorrow after this time of day (HH:MM, e.g. 18:00)")
+	fromFixture := fs.String("from-fixture", "", "Render events from a saved API response instead of calling Google")
+	opts := agendaOptions{}
+	fs.StringVar(&opts.format, "format", "text", "Output format: text, block (fixed-width text for Conky and GeekTool) or timeline (overlapping events side by side)")
+	fs.IntVar(&opts.block.width, "block-width", 40, "With --format block, the width of each line in columns")
+	fs.IntVar(&opts.block.lines, "block-lines", 0, "With --format block, always print this many event lines (0 for one per event)")
+	fs.BoolVar(&opts.block.border, "border", false, "With --format block, draw a box around the block")
+	fs.StringVar(&opts.saveFixture, "save-fixture", "", "Save the (sanitized) API response to this file")
+	fs.BoolVar(&opts.journal, "journal", false, "For past dates, print what actually happened (moved events and attendance)")
+	addDisplayFlags(fs, &opts)
+	return func(args []string) error {
+		// --date に時刻を付けた場合は、その時刻以降を表示する（--from-time が優先）
+		date, clock, _ := strings.Cut(strings.TrimSpace(*dateStr), " ")
+		if clock != "" && *fromTime == "" {
+			*fromTime = strings.TrimSpace(clock)
+		}
+		targetDate, err := parseDateFlag(date)
+		if err != nil {
+			return err
+		}
+		if err := opts.parseTimeRange(*fromTime, *toTime); err != nil {
+			return err
+		}
+		// --auto-tomorrow の時刻を過ぎていれば翌日（--next-business-day なら次の営業日）を表示する
+		tomorrow := *nextBusiness
+		if *autoTomorrow != "" {
+			cutoff, err := parseClock(*autoTomorrow)
+			if err != nil {
+				return fmt.Errorf("Invalid --auto-tomorrow: %w", err)
+			}
+			tomorrow = date == "" && !time.Now().Before(atClock(targetDate, cutoff))
+		}
+		if err := opts.loadConfig(); err != nil {
+			return err
+		}
+		if err := opts.checkFormat(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		switch {
+		case tomorrow && *nextBusiness:
+			day, skipped, err := nextBusinessDay(ctx, client, opts.holidays, targetDate)
+			if err != nil {
+				return err
+			}
+			targetDate = day
+			if len(skipped) > 0 {
+				opts.warnings = append(opts.warnings, fmt.Sprintf("（%s は休日のため、次の営業日を表示しています）", strings.Join(skipped, "、")))
+			} else if *autoTomorrow != "" {
+				opts.warnings = append(opts.warnings, fmt.Sprintf("（%s を過ぎたため、明日の予定を表示しています）", *autoTomorrow))
+			}
+		case tomorrow:
+			targetDate = targetDate.AddDate(0, 0, 1)
+			opts.warnings = append(opts.warnings, fmt.Sprintf("（%s を過ぎたため、明日の予定を表示しています）", *autoTomorrow))
+		}
+		if opts.journal && !isPastDate(targetDate) {
+			return fmt.Errorf("--journal is only available for past dates")
+		}
+		days := []time.Time{targetDate}
+		if *withTomorrow {
+			days = append(days, targetDate.AddDate(0, 0, 1))
+		}
+		n, err := runAgenda(ctx, client, os.Stdout, days, opts)
+		if err != nil {
+			return err
+		}
+		if n == 0 && *failOnEmpty {
+			return errNoEvents
+		}
+		return nil
+	}
+}
+
+// --date の値を解釈する（空の場合は今日）
+func parseDateFlag(s string) (time.Time, error) {
+	if s == "" {
+		// 1日の始まりより前は前日として扱う
+		return time.Now().Add(-dayStart), nil
+	}
+	// 1日の区切りや終日の予定と比べるため、表示しているタイムゾーンの日付にする
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid date format. Please use YYYY-MM-DD format: %w", err)
+	}
+	return t, nil
+}
+
+type agendaOptions struct {
+	saveFixture string
+	journal     bool
+	// 出力の形式（--format、text か block）と block の設定
+	format string
+	block  blockOptions
+	// 非公開の予定の件名を伏せる
+	redactPrivate bool
+	// 出欠と主催者を併記する
+	markers bool
+	// 自分が主催する予定にほかの参加者の出欠の数を併記する
+	responses bool
+	// 各予定の通知の設定を表示する
+	verbose bool
+	// カレンダーの既定の通知（verbose の表示用）
+	defaultReminders []*calendar.EventReminder
+	configPath       string
+	// --hyperlinks（auto / always / never）と、それを解決した結果
+	hyperlinkMode string
+	hyperlinks    bool
+	// --theme（空なら設定ファイルの theme）と、それを解決した結果と ANSI の色を使うか
+	themeName string
+	theme     theme
+	ansi      bool
+	// 表示するタグ（--tag、いずれかが付いた予定だけを表示する）
+	tags stringList
+	// 表示する時間指定の予定の長さ（--min-duration / --max-duration、0 は制限なし）
+	minDuration time.Duration
+	maxDuration time.Duration
+	// 予定の行の最大の表示幅（--max-width、0 は制限なし）と、切り詰めずに折り返すか（--wrap）
+	maxWidthMode string
+	maxWidth     int
+	wrap         bool
+	// 同じ件名と色の続いた予定を1件にまとめるか（--merge-adjacent）と、続いているとみなす間隔（--merge-gap）
+	mergeAdjacent bool
+	mergeGap      time.Duration
+	// 並べ方（--sort）と逆順にするか（--reverse）
+	sortKey string
+	reverse bool
+	// 並べ替えた後に先頭から除く件数（--skip）と表示する最大の件数（--limit、0 は制限なし）
+	skip  int
+	limit int
+	// 見出しの曜日の言語（--lang）と和暦で表示するか（--era）
+	lang string
+	era  bool
+	// 設定ファイルの transforms、archive.dir、links と maps（loadConfig で読み込む）
+	transforms []transformRule
+	archiveDir string
+	links      linksConfig
+	maps       mapsConfig
+	focus      focusConfig
+	cost       costConfig
+	holidays   holidaysConfig
+	// 設定ファイルの transform_script（nil はスクリプトなし）
+	transformScript *transformScript
+	// 確認なしで取得する期間の日数（設定ファイルの max_range_days）
+	maxRangeDays int
+	// 表示する時間帯（--from-time / --to-time、0 は制限なし）
+	fromTime time.Duration
+	toTime   time.Duration
+	// 見出しの下に表示する警告（集中時間の目標に届かない場合など）
+	warnings []string
+}
+
+// 予定の表示方法に関するフラグを登録する（1日分の予定を表示するコマンドで共通）
+func addDisplayFlags(fs *flag.FlagSet, opts *agendaOptions) {
+	fs.BoolVar(&opts.redactPrivate, "redact-private", false, "Hide titles of private and confidential events")
+	fs.BoolVar(&opts.markers, "markers", false, "Show my response (✓ accepted, ? needs action, ✗ declined), the organizer, 🔒 for events I cannot modify and overlapping events")
+	fs.BoolVar(&opts.responses, "responses", false, "For events I organize, show how many guests accepted (✓), are tentative (~), declined (✗) or have not answered (?)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Show the reminders configured for each event")
+	fs.StringVar(&opts.configPath, "config", defaultConfigFile, "Path to the config file (for transforms)")
+	fs.Var(&opts.tags, "tag", "Show only events with this tag (from extended properties or #tag in the description; repeatable)")
+	fs.DurationVar(&opts.minDuration, "min-duration", 0, "Hide timed events shorter than this (e.g. 15m)")
+	fs.DurationVar(&opts.maxDuration, "max-duration", 0, "Hide timed events longer than this (e.g. 4h, 0 for no limit)")
+	fs.BoolVar(&opts.mergeAdjacent, "merge-adjacent", false, "Show consecutive events with the same title and color (e.g. 25-minute blocks) as one range")
+	fs.DurationVar(&opts.mergeGap, "merge-gap", 5*time.Minute, "With --merge-adjacent, merge events separated by at most this long")
+	fs.StringVar(&opts.maxWidthMode, "max-width", "", "Fit each event line into this many columns (a number, or auto for the terminal width)")
+	fs.BoolVar(&opts.wrap, "wrap", false, "With --max-width, wrap long lines instead of truncating the title")
+	fs.StringVar(&opts.sortKey, "sort", "start", "Sort events by start, duration, summary or calendar (ties are broken by start, duration, summary)")
+	fs.BoolVar(&opts.reverse, "reverse", false, "Reverse the sort order")
+	fs.IntVar(&opts.limit, "limit", 0, "Show at most this many events per day after filtering and sorting (0 for no limit)")
+	fs.IntVar(&opts.skip, "skip", 0, "Skip this many events per day after filtering and sorting")
+	fs.StringVar(&opts.lang, "lang", "ja", "Language of the weekday in the date header (en, ja)")
+	fs.BoolVar(&opts.era, "era", false, "Show the date header in the Japanese era calendar (令和6年6月14日)")
+	fs.StringVar(&opts.hyperlinkMode, "hyperlinks", "auto", "Link titles to Google Calendar and locations to Google Maps in the terminal (auto, always, never)")
+	fs.StringVar(&opts.themeName, "theme", "", "Output theme: default, high-contrast, colorblind or monochrome (default: theme in the config)")
+}
+
+// 設定ファイルから表示に使う設定を読み込む
+func (opts *agendaOptions) loadConfig() error {
+	if _, ok := weekdayNames[opts.lang]; !ok {
+		return fmt.Errorf("Unsupported language: %s", opts.lang)
+	}
+	if err := checkSortKey(opts.sortKey); err != nil {
+		return err
+	}
+	if opts.limit < 0 || opts.skip < 0 {
+		return fmt.Errorf("--limit and --skip must not be negative")
+	}
+	if opts.mergeGap < 0 {
+		return fmt.Errorf("--merge-gap must not be negative")
+	}
+	if opts.maxDuration > 0 && opts.maxDuration < opts.minDuration {
+		return fmt.Errorf("--max-duration must not be shorter than --min-duration")
+	}
+	cfg, err := loadConfig(opts.configPath)
+	if err != nil {
+		return err
+	}
+	if opts.maxWidth, err = resolveMaxWidth(opts.maxWidthMode); err != nil {
+		return err
+	}
+	opts.archiveDir = cfg.Archive.Dir
+	opts.links = cfg.Links
+	opts.maps = cfg.Maps
+	opts.focus = cfg.Focus
+	opts.cost = cfg.Cost
+	opts.holidays = cfg.Holidays
+	opts.maxRangeDays = cfg.maxRangeDays()
+	if opts.hyperlinks, err = useHyperlinks(opts.hyperlinkMode); err != nil {
+		return err
+	}
+	if opts.themeName == "" {
+		opts.themeName = cfg.Theme
+	}
+	if opts.theme, err = lookupTheme(opts.themeName); err != nil {
+		return err
+	}
+	opts.ansi = useANSIColors(opts.theme)
+	if opts.transformScript, err = loadTransformScript(cfg.TransformScript); err != nil {
+		return err
+	}
+	opts.transforms, err = compileTransforms(cfg.Transforms)
+	return err
+}
+
+// --format と block の設定を確かめる
+func (opts agendaOptions) checkFormat() error {
+	switch opts.format {
+	case "", "text":
+		return nil
+	case "block":
+		if opts.block.width < 20 {
+			return fmt.Errorf("--block-width must be at least 20")
+		}
+		if opts.block.lines < 0 {
+			return fmt.Errorf("--block-lines must not be negative")
+		}
+		if opts.journal {
+			return fmt.Errorf("--format block cannot be combined with --journal")
+		}
+		return nil
+	case "timeline":
+		if opts.journal {
+			return fmt.Errorf("--format timeline cannot be combined with --journal")
+		}
+		if opts.maxWidth > 0 && opts.maxWidth < 20 {
+			return fmt.Errorf("--format timeline needs --max-width of at least 20")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported --format %q (expected text, block or timeline)", opts.format)
+}
+
+// 予定を表示し、表示した件数を返す
+//
+// 複数の日（--with-tomorrow）を表示する場合も、予定は1回の呼び出しでまとめて取得し、
+// 日ごとに見出しを付けて表示する。警告は最初の日にだけ表示する。
+func runAgenda(ctx context.Context, client calendarClient, w io.Writer, days []time.Time, opts agendaOptions) (int, error) {
+	// 最初の日の前日の開始時刻から最後の日の終了時刻までを設定
+	startTime, _ := dayWindow(days[0])
+	_, endTime := dayWindow(days[len(days)-1])
+
+	res, err := client.ListEvents(ctx, "primary", startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	if opts.saveFixture != "" {
+		if err := saveFixture(opts.saveFixture, res); err != nil {
+			return 0, err
+		}
+	}
+
+	opts.archive(res, days)
+	if warning, err := focusWarning(ctx, client, opts.focus, days[0]); err != nil {
+		return 0, err
+	} else if warning != "" {
+		opts.warnings = append(opts.warnings, warning)
+	}
+	normalized := normalizeEvents("primary", res.Items)
+	opts.defaultReminders = res.DefaultReminders
+	var listed []agendaEvent
+	for i, day := range days {
+		if len(days) > 1 {
+			if i > 0 {
+				fmt.Fprintln(w)
+				opts.warnings = nil
+			}
+			if label := relativeDayLabel(day, time.Now().Add(-dayStart)); label != "" {
+				fmt.Fprintf(w, "■ %s\n", label)
+			}
+		}
+		// 変換スクリプトなどを2回実行しないよう、表示とフック・番号の保存に同じ結果を使う
+		events := opts.prepare(opts.filterTimeRange(selectDay(normalized, day), day))
+		opts.printPrepared(w, day, events)
+		runAgendaHooks(ctx, day, events)
+		// 勤務場所の予定は見出しに表示するため、番号を数えない
+		_, shown := splitWorkingLocations(events)
+		listed = append(listed, shown...)
+	}
+	// show 3 のように番号で予定を指定できるよう、表示した順に保存する
+	if err := saveLastListing(lastListingFile, listed); err != nil {
+		slog.Warn("Unable to save listing", "error", err)
+	}
+	return len(listed), nil
+}
+
+// 見出しに使う「今日」「明日」（それ以外の日は ""）
+func relativeDayLabel(day, today time.Time) string {
+	switch day.Format("2006-01-02") {
+	case today.Format("2006-01-02"):
+		return "今日"
+	case today.AddDate(0, 0, 1).Format("2006-01-02"):
+		return "明日"
+	}
+	return ""
+}
+
+// 表示する予定に変換、タグと長さによる絞り込み、並べ替え、件数の制限と伏せ字を適用する
+func (opts agendaOptions) prepare(events []agendaEvent) []agendaEvent {
+	events = applyTransforms(opts.transforms, events)
+	events = opts.transformScript.apply(events)
+	events = filterTags(events, opts.tags)
+	events = filterDuration(events, opts.minDuration, opts.maxDuration)
+	if opts.mergeAdjacent {
+		events = mergeAdjacent(events, opts.mergeGap)
+	}
+	events = sortEvents(events, opts.sortKey, opts.reverse)
+	events = limitEvents(events, opts.skip, opts.limit)
+	if opts.redactPrivate {
+		events = redactPrivate(events)
+	}
+	return events
+}
+
+// 先頭の skip 件を除いて最大 limit 件を返す（limit が 0 なら残りすべて）
+func limitEvents(events []agendaEvent, skip, limit int) []agendaEvent {
+	if skip >= len(events) {
+		return nil
+	}
+	events = events[skip:]
+	if limit > 0 && limit < len(events) {
+		events = events[:limit]
+	}
+	return events
+}
+
+// 時間指定の予定を長さで絞り込む（max が 0 なら上限なし、終日の予定は常に残す）
+func filterDuration(events []agendaEvent, min, max time.Duration) []agendaEvent {
+	if min <= 0 && max <= 0 {
+		return events
+	}
+	out := make([]agendaEvent, 0, len(events))
+	for _, e := range events {
+		if d := e.duration(); !e.allDay && (d < min || (max > 0 && d > max)) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// --from-time と --to-time を解釈し、見出しの下に表示している時間帯を添える
+//
+// --from-time の now は今の時刻（今日の残りの予定を表示する場合用）。
+func (opts *agendaOptions) parseTimeRange(from, to string) error {
+	var err error
+	if from == "now" {
+		now := time.Now()
+		opts.fromTime = time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	} else if from != "" {
+		if opts.fromTime, err = parseClock(from); err != nil {
+			return fmt.Errorf("Invalid --from-time: %w", err)
+		}
+	}
+	if to != "" {
+		if opts.toTime, err = parseClock(to); err != nil {
+			return fmt.Errorf("Invalid --to-time: %w", err)
+		}
+		if opts.toTime == dayStart {
+			return fmt.Errorf("--to-time must not be the start of the day")
+		}
+	}
+	if from == "" && to == "" {
+		return nil
+	}
+	if from != "" && to != "" && !clockBefore(opts.fromTime, opts.toTime) {
+		return fmt.Errorf("--from-time must be before --to-time")
+	}
+	label := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	var rng string
+	switch {
+	case to == "":
+		rng = label(opts.fromTime) + "以降"
+	case from == "":
+		rng = label(opts.toTime) + "まで"
+	default:
+		rng = label(opts.fromTime) + "〜" + label(opts.toTime)
+	}
+	opts.warnings = append(opts.warnings, fmt.Sprintf("（%sの予定を表示しています）", rng))
+	return nil
+}
+
+// 1日の始まり（--day-start）から数えて a が b より前か
+func clockBefore(a, b time.Duration) bool {
+	return (a-dayStart+24*time.Hour)%(24*time.Hour) < (b-dayStart+24*time.Hour)%(24*time.Hour)
+}
+
+// その日の時刻 clock の日時（--day-start より前の時刻は翌日の日時）
+func atDayClock(day time.Time, clock time.Duration) time.Time {
+	t := atClock(day, clock)
+	if clock < dayStart {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// --from-time から --to-time までに重なる時間指定の予定に絞り込む（終日の予定は常に残す）
+func (opts agendaOptions) filterTimeRange(events []agendaEvent, day time.Time) []agendaEvent {
+	if opts.fromTime == 0 && opts.toTime == 0 {
+		return events
+	}
+	_, end := dayWindow(day)
+	begin := atClock(day, dayStart)
+	if opts.fromTime != 0 {
+		begin = atDayClock(day, opts.fromTime)
+	}
+	if opts.toTime != 0 {
+		end = atDayClock(day, opts.toTime)
+	}
+	out := make([]agendaEvent, 0, len(events))
+	for _, e := range events {
+		if !e.allDay && (!e.end.After(begin) || !e.start.Before(end)) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// 同じカレンダーで件名と色が同じ時間指定の予定が、gap 以内の間隔で続いていれば1件にまとめる
+//
+// まとめた予定は最初の予定の開始から最後の予定の終了までとし、件名に件数を付ける（「集中（3件）」）。
+// 表示だけをまとめるため、stats などの集計には影響しない。
+func mergeAdjacent(events []agendaEvent, gap time.Duration) []agendaEvent {
+	events = sortEvents(events, "start", false)
+	out := make([]agendaEvent, 0, len(events))
+	counts := make([]int, 0, len(events))
+	for _, e := range events {
+		if n := len(out); n > 0 {
+			last := &out[n-1]
+			between := e.start.Sub(last.end)
+			if !e.allDay && !last.allDay && e.calendarID == last.calendarID &&
+				e.item.Summary == last.item.Summary && e.item.ColorId == last.item.ColorId &&
+				between >= 0 && between <= gap {
+				if e.end.After(last.end) {
+					last.end = e.end
+				}
+				counts[n-1]++
+				continue
+			}
+		}
+		out = append(out, e)
+		counts = append(counts, 1)
+	}
+	for i, n := range counts {
+		if n == 1 {
+			continue
+		}
+		// 元の予定は変更しない
+		item := *out[i].item
+		end := *item.End
+		end.DateTime = out[i].end.Format(time.RFC3339)
+		item.End = &end
+		item.Summary = fmt.Sprintf("%s（%d件）", item.Summary, n)
+		out[i].item = &item
+	}
+	return out
+}
+
+// オプションに応じた形式で1日分の予定を出力する
+func (opts agendaOptions) print(w io.Writer, targetDate time.Time, events []agendaEvent) {
+	opts.printPrepared(w, targetDate, opts.prepare(events))
+}
+
+// prepare を適用済みの1日分の予定を出力する（表示した予定をほかにも使う場合用）
+func (opts agendaOptions) printPrepared(w io.Writer, targetDate time.Time, events []agendaEvent) {
+	if opts.journal {
+		printJournal(w, formatDateHeader(targetDate, opts.lang, opts.era), events)
+		return
+	}
+	if opts.format == "block" {
+		printBlock(w, targetDate, events, opts)
+		return
+	}
+	if opts.format == "timeline" {
+		printTimeline(w, targetDate, events, opts)
+		return
+	}
+	printAgenda(w, targetDate, events, opts)
+}
+
+// 取得する期間（前日の00:00:00から当日の23:59:59まで）を返す
+func dayWindow(targetDate time.Time) (time.Time, time.Time) {
+	startTime := atClock(targetDate.AddDate(0, 0, -1), dayStart) // 前日の00:00:00（--day-start の分だけずらす）
+	endTime := atClock(targetDate.AddDate(0, 0, 1), dayStart).Add(-time.Second)
+	return startTime, endTime
+}
+
+// イベントの開始時刻と終了時刻を返す（1件だけ扱う場合用。一覧は normalizeEvents でまとめてパースする）
+func eventTimes(item *calendar.Event) (time.Time, time.Time) {
+	return parseDateTime(item.Start), parseDateTime(item.End)
+}
+
+// EventDateTime の日時（終日イベントの場合は日付）をパースする（パースできなければゼロ値）
+func parseDateTime(dt *calendar.EventDateTime) time.Time {
+	t, _, _ := parseEventTime(dt)
+	return t
+}
+
+// 指定された日に表示するイベントを返す
+func eventsForDay(items []*calendar.Event, targetDate time.Time) []*calendar.Event {
+	return eventItems(selectDay(normalizeEvents("primary", items), targetDate))
+}
+
+// 指定された日に表示する予定を返す
+//
+// 終日の予定は、その日が開始日から終了日の前日までに入っていれば表示する。
+func selectDay(events []agendaEvent, targetDate time.Time) []agendaEvent {
+	year, month, day := targetDate.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	if dayStart > 0 {
+		return eventsInLogicalDay(events, targetDate, date)
+	}
+	_, endTime := dayWindow(targetDate)
+	nextDay := atClock(targetDate, dayStart)
+
+	var out []agendaEvent
+	for _, e := range events {
+		if e.allDay {
+			if !date.Before(e.start) && date.Before(e.end) {
+				out = append(out, e)
+			}
+			continue
+		}
+		// イベントが指定された日に終了するか、指定された日をまたぐ場合に表示
+		if y, m, d := e.end.Date(); (y == year && m == month && d == day) ||
+			(e.start.Before(endTime) && e.end.After(nextDay)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// --day-start を指定した場合、その時刻から翌日の同じ時刻までに重なるイベントを返す
+//
+// 深夜0時をまたぐ予定は、始まった日の夜の予定として扱われる。
+func eventsInLogicalDay(events []agendaEvent, targetDate, date time.Time) []agendaEvent {
+	begin := atClock(targetDate, dayStart)
+	// begin に AddDate すると、翌日の境目が夏時間の始まりで飛ばされる時刻の場合にずれる
+	end := atClock(targetDate.AddDate(0, 0, 1), dayStart)
+
+	var out []agendaEvent
+	for _, e := range events {
+		if e.allDay {
+			if !date.Before(e.start) && date.Before(e.end) {
+				out = append(out, e)
+			}
+			continue
+		}
+		if e.start.Before(end) && e.end.After(begin) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// 1日分の予定を出力する（オプションに応じて出欠と主催者や通知の設定を併記する）
+func printAgenda(w io.Writer, targetDate time.Time, events []agendaEvent, opts agendaOptions) {
+	// 日付を表示用にフォーマット
+	displayDate := formatDateHeader(targetDate, opts.lang, opts.era)
+	fmt.Fprintln(w, opts.theme.emphasize(displayDate+"の予定:", opts.ansi))
+	// 勤務場所の予定は一覧に含めず、見出しの下に1行で表示する
+	locations, events := splitWorkingLocations(events)
+	if where := formatWorkingLocations(locations); where != "" {
+		fmt.Fprintln(w, where)
+	}
+	for _, warning := range opts.warnings {
+		fmt.Fprintln(w, warning)
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintf(w, "%sの予定はありません。\n", displayDate)
+		return
+	}
+	var conflicts []conflictKind
+	if opts.markers {
+		conflicts = eventConflicts(events)
+	}
+	for i, e := range events {
+		item := e.item
+		suffix := timezoneNote(e) + rescheduleNote(e)
+		if opts.responses {
+			suffix += responseCounts(item)
+		}
+		if opts.markers {
+			suffix += eventMarkers(item)
+			if m, ok := conflictMarkers[conflicts[i]]; ok {
+				suffix += " " + m
+			}
+		}
+		if opts.maxWidth > 0 && opts.wrap {
+			// 折り返す行は端末のリンクや色にしない
+			label := opts.theme.colorLabel(item.ColorId, false)
+			for _, l := range wrapWidth(formatEventLine(e, label, item.Summary)+suffix, opts.maxWidth, "    ") {
+				fmt.Fprintln(w, l)
+			}
+		} else {
+			summary := item.Summary
+			if opts.maxWidth > 0 {
+				// 時刻が見えるよう件名だけを切り詰める（幅は色を付ける前の文字列で数える）
+				plain := opts.theme.colorLabel(item.ColorId, false)
+				summary = truncateWidth(summary, opts.maxWidth-displayWidth(formatEventLine(e, plain, "")+suffix))
+			}
+			if opts.hyperlinks {
+				summary = hyperlink(item.HtmlLink, summary)
+			}
+			fmt.Fprintln(w, formatEventLine(e, opts.theme.colorLabel(item.ColorId, opts.ansi), summary)+suffix)
+		}
+		if opts.verbose {
+			if item.Location != "" {
+				fmt.Fprintf(w, "    場所: %s\n", opts.formatLocation(item.Location))
+			}
+			fmt.Fprintf(w, "    通知: %s\n", formatReminders(item, opts.defaultReminders))
+			if tags := eventTags(item); len(tags) > 0 {
+				fmt.Fprintf(w, "    タグ: %s\n", formatTags(tags))
+			}
+			if cost, n := opts.cost.eventCost(item); n > 0 {
+				fmt.Fprintf(w, "    費用: %s（%d人）\n", opts.cost.format(cost), n)
+			}
+			for _, l := range opts.links.eventLinks(item.Summary, item.Description) {
+				if opts.hyperlinks {
+					l.Label = hyperlink(l.URL, l.Label)
+				}
+				fmt.Fprintf(w, "    リンク: %s\n", l)
+			}
+		}
+	}
+}
+
+// 場所に Google マップの URL と出発地からの移動時間を併記する（住所でない場所はそのまま）
+func (opts agendaOptions) formatLocation(location string) string {
+	addr := physicalLocation(location)
+	if addr == "" {
+		return location
+	}
+	var s string
+	if opts.hyperlinks {
+		s = hyperlink(mapsURL(addr), addr)
+	} else {
+		s = addr + " " + mapsURL(addr)
+	}
+	if t := opts.maps.travelTime(addr); t != "" {
+		s += "（移動 " + t + "）"
+	}
+	return s
+}
+
+// イベント1件を「【色】タイトル (開始-終了)」の形式にする
+func formatEvent(item *calendar.Event) string {
+	start, end := eventTimes(item)
+	return formatEventSummary(agendaEvent{item: item, start: start, end: end, allDay: item.Start.DateTime == ""}, item.Summary)
+}
+
+// 件名を summary にして「【色】タイトル (開始-終了)」の形式にする
+func formatEventSummary(e agendaEvent, summary string) string {
+	return formatEventLine(e, "【"+colorName(e.item.ColorId)+"】", summary)
+}
+
+// 色の表示（テーマの記号や色を付けた「【色】」）を label にして「【色】タイトル (開始-終了)」の形式にする
+func formatEventLine(e agendaEvent, label, summary string) string {
+	// 終日イベントの場合は時刻を表示しない
+	if e.allDay {
+		return fmt.Sprintf("%s%v (終日) ", label, summary)
+	}
+	return fmt.Sprintf("%s%v (%v-%v)", label, summary, e.start.Format("15:04"), e.end.Format("15:04"))
+}
+
+// 色情報の取得と変換
+func colorName(colorID string) string {
+	if name, ok := colorNames[colorID]; ok {
+		return name
+	}
+	return "デフォルト"
+}
+
+// 繰り返し予定のこの回だけが移動されている場合の注記
+//
+// 繰り返し予定の各回には本来の開始時刻（originalStartTime）があり、開始時刻と異なればこの回だけ移動されている。
+func rescheduleNote(e agendaEvent) string {
+	original := parseDateTime(e.item.OriginalStartTime)
+	if original.IsZero() || original.Equal(e.start) {
+		return ""
+	}
+	original = original.In(e.start.Location())
+	layout := "15:04"
+	switch {
+	case e.allDay:
+		layout = "1/2"
+	case original.Format("2006-01-02") != e.start.Format("2006-01-02"):
+		layout = "1/2 15:04"
+	}
+	return fmt.Sprintf(" ↻ %s から移動（この回のみ）", original.Format(layout))
+}
+
+// 予定が作成されたタイムゾーンの時差が表示している時刻と異なる場合の注記
+//
+// 時差はその予定の開始時点で比べるため、夏時間の切り替わりの前後も正しく判定できる。
+func timezoneNote(e agendaEvent) string {
+	tz := e.item.Start.TimeZone
+	if e.allDay || tz == "" {
+		return ""
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return ""
+	}
+	start, end := e.start, e.end
+	_, shown := start.Zone()
+	_, original := start.In(loc).Zone()
+	if shown == original {
+		return ""
+	}
+	return fmt.Sprintf(" ⚠ %s では %s-%s", tz, start.In(loc).Format("15:04"), end.In(loc).Format("15:04 MST"))
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestParseDateFlag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-06-14", want: date(2024, 6, 14)},
+		{in: "2024-02-29", want: date(2024, 2, 29)},
+		{in: "2023-02-29", wantErr: true},
+		{in: "2024/06/14", wantErr: true},
+		{in: "2024-6-14", wantErr: true},
+		{in: "today", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDateFlag(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDateFlag(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 1日の区切りや終日の予定と比べるため、表示しているタイムゾーンの0時になる
+			if !got.Equal(tt.want) || got.Location() != time.Local {
+				t.Errorf("parseDateFlag(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// --date を省略すると今日（--day-start より前は前日）
+func TestParseDateFlagToday(t *testing.T) {
+	setDayStart(t, 0)
+	got, err := parseDateFlag("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Now(); got.Format("2006-01-02") != want.Format("2006-01-02") {
+		t.Errorf("parseDateFlag(\"\") = %v, want today (%v)", got, want)
+	}
+}
+
+func TestDayWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		dayStart  time.Duration
+		day       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "midnight",
+			day:       date(2024, 6, 14),
+			wantStart: time.Date(2024, 6, 13, 0, 0, 0, 0, time.Local),
+			wantEnd:   time.Date(2024, 6, 14, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:      "day start 04:00",
+			dayStart:  4 * time.Hour,
+			day:       date(2024, 6, 14),
+			wantStart: time.Date(2024, 6, 13, 4, 0, 0, 0, time.Local),
+			wantEnd:   time.Date(2024, 6, 15, 3, 59, 59, 0, time.Local),
+		},
+		{
+			name:      "month boundary",
+			day:       date(2024, 3, 1),
+			wantStart: time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local),
+			wantEnd:   time.Date(2024, 3, 1, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:      "year boundary",
+			dayStart:  30 * time.Minute,
+			day:       date(2024, 12, 31),
+			wantStart: time.Date(2024, 12, 30, 0, 30, 0, 0, time.Local),
+			wantEnd:   time.Date(2025, 1, 1, 0, 29, 59, 0, time.Local),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDayStart(t, tt.dayStart)
+			start, end := dayWindow(tt.day)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("dayWindow(%v) = %v, %v, want %v, %v", tt.day, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestEventsForDay(t *testing.T) {
+	events, err := loadFixture("testdata/edges.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		day  time.Time
+		want []string
+	}{
+		// 日をまたぐ予定は終わる日まで毎日表示し、0時ちょうどに始まる予定は前日に含めない
+		{day: date(2024, 6, 13), want: []string{"before", "conference"}},
+		{day: date(2024, 6, 14), want: []string{"conference", "early", "overnight"}},
+		{day: date(2024, 6, 15), want: []string{"conference", "overnight", "after"}},
+		{day: date(2024, 6, 16), want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day.Format("2006-01-02"), func(t *testing.T) {
+			got := []string{}
+			for _, item := range eventsForDay(events.Items, tt.day) {
+				got = append(got, item.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("eventsForDay(%s) = %v, want %v", tt.day.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		item *calendar.Event
+		want string
+	}{
+		{
+			name: "color",
+			item: &calendar.Event{Summary: "定例", ColorId: "2",
+				Start: &calendar.EventDateTime{DateTime: "2024-06-14T10:00:00+09:00"},
+				End:   &calendar.EventDateTime{DateTime: "2024-06-14T10:30:00+09:00"}},
+			want: "【緑】定例 (10:00-10:30)",
+		},
+		{
+			name: "default color",
+			item: &calendar.Event{Summary: "Lunch",
+				Start: &calendar.EventDateTime{DateTime: "2024-06-14T12:00:00+09:00"},
+				End:   &calendar.EventDateTime{DateTime: "2024-06-14T13:00:00+09:00"}},
+			want: "【デフォルト】Lunch (12:00-13:00)",
+		},
+		{
+			name: "unknown color",
+			item: &calendar.Event{Summary: "新色", ColorId: "99",
+				Start: &calendar.EventDateTime{DateTime: "2024-06-14T12:00:00+09:00"},
+				End:   &calendar.EventDateTime{DateTime: "2024-06-14T13:00:00+09:00"}},
+			want: "【デフォルト】新色 (12:00-13:00)",
+		},
+		{
+			name: "no title",
+			item: &calendar.Event{
+				Start: &calendar.EventDateTime{DateTime: "2024-06-14T23:00:00+09:00"},
+				End:   &calendar.EventDateTime{DateTime: "2024-06-15T01:00:00+09:00"}},
+			want: "【デフォルト】 (23:00-01:00)",
+		},
+		{
+			name: "all day",
+			item: &calendar.Event{Summary: "研修",
+				Start: &calendar.EventDateTime{Date: "2024-06-14"},
+				End:   &calendar.EventDateTime{Date: "2024-06-15"}},
+			want: "【デフォルト】研修 (終日) ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatEvent(tt.item); got != tt.want {
+				t.Errorf("formatEvent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDay(t *testing.T) {
+	events := loadTestEvents(t, "testdata/day.json")
+	tests := []struct {
+		name     string
+		dayStart time.Duration
+		day      time.Time
+		want     []string
+	}{
+		{
+			name: "the day",
+			day:  date(2024, 6, 14),
+			want: []string{"standup", "lunch", "focus1", "focus2", "focus3", "wip", "release", "training"},
+		},
+		{
+			// 日をまたぐ予定は翌日にも表示し、終日の予定は終了日には表示しない
+			name: "next day",
+			day:  date(2024, 6, 15),
+			want: []string{"release"},
+		},
+		{
+			name: "day before",
+			day:  date(2024, 6, 13),
+			want: []string{},
+		},
+		{
+			// 1日の始まりを 02:00 にすると、日付が変わってからの予定も前日に入る
+			name:     "day start 02:00",
+			dayStart: 2 * time.Hour,
+			day:      date(2024, 6, 15),
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDayStart(t, tt.dayStart)
+			if got := eventIDs(selectDay(events, tt.day)); !slices.Equal(got, tt.want) {
+				t.Errorf("selectDay(%v) = %v, want %v", tt.day.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+// 終日の予定は開始日から終了日の前日まで表示する（終了日は含まない）
+func TestSelectDayAllDay(t *testing.T) {
+	events := normalizeEvents("primary", []*calendar.Event{
+		{Id: "training", Summary: "研修", Start: &calendar.EventDateTime{Date: "2024-06-14"}, End: &calendar.EventDateTime{Date: "2024-06-15"}},
+		{Id: "trip", Summary: "出張", Start: &calendar.EventDateTime{Date: "2024-06-13"}, End: &calendar.EventDateTime{Date: "2024-06-16"}},
+	})
+	tests := []struct {
+		day  time.Time
+		want []string
+	}{
+		{day: date(2024, 6, 12), want: []string{}},
+		{day: date(2024, 6, 13), want: []string{"trip"}},
+		{day: date(2024, 6, 14), want: []string{"training", "trip"}},
+		{day: date(2024, 6, 15), want: []string{"trip"}},
+		{day: date(2024, 6, 16), want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day.Format("2006-01-02"), func(t *testing.T) {
+			got := []string{}
+			for _, e := range selectDay(events, tt.day) {
+				got = append(got, e.item.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectDay(%s) = %v, want %v", tt.day.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAdjacent(t *testing.T) {
+	events := loadTestEvents(t, "testdata/day.json")
+	tests := []struct {
+		name string
+		gap  time.Duration
+		// まとめた後の集中の予定（件名、開始、終了）
+		want [][3]string
+	}{
+		{
+			name: "no gap",
+			gap:  0,
+			want: [][3]string{{"集中（2件）", "13:00", "15:00"}, {"集中", "15:03", "16:00"}},
+		},
+		{
+			name: "default gap",
+			gap:  5 * time.Minute,
+			want: [][3]string{{"集中（3件）", "13:00", "16:00"}},
+		},
+		{
+			name: "short gap",
+			gap:  2 * time.Minute,
+			want: [][3]string{{"集中（2件）", "13:00", "15:00"}, {"集中", "15:03", "16:00"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][3]string
+			for _, e := range mergeAdjacent(events, tt.gap) {
+				if e.item.ColorId == "5" {
+					got = append(got, [3]string{e.item.Summary, e.start.Format("15:04"), e.end.Format("15:04")})
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mergeAdjacent(gap %v) = %v, want %v", tt.gap, got, tt.want)
+			}
+		})
+	}
+
+	// 元の予定は変更しない
+	for _, e := range events {
+		if e.item.Summary != "集中" && e.item.ColorId == "5" {
+			t.Errorf("mergeAdjacent changed the original event: %q", e.item.Summary)
+		}
+	}
+}
+
+// 件名が同じでも、色やカレンダーが違う予定と終日の予定はまとめない
+func TestMergeAdjacentKeepsDistinctEvents(t *testing.T) {
+	events := loadTestEvents(t, "testdata/day.json")
+	other := events[3]
+	other.calendarID = "team@example.com"
+	events[3] = other
+	got := mergeAdjacent(events, 5*time.Minute)
+	want := []string{"training", "standup", "lunch", "focus1", "focus2", "focus3", "wip", "release"}
+	if ids := eventIDs(got); !slices.Equal(ids, want) {
+		t.Errorf("mergeAdjacent = %v, want %v", ids, want)
+	}
+}
+
+func TestFilterTimeRange(t *testing.T) {
+	events := loadTestEvents(t, "testdata/day.json")
+	tests := []struct {
+		name     string
+		dayStart time.Duration
+		from, to string
+		want     []string
+	}{
+		{
+			name: "no range",
+			want: []string{"standup", "lunch", "focus1", "focus2", "focus3", "wip", "release", "training"},
+		},
+		{
+			// 範囲に一部でも重なる予定と終日の予定を残す
+			name: "afternoon",
+			from: "13:00", to: "15:00",
+			want: []string{"focus1", "focus2", "training"},
+		},
+		{
+			name: "from only",
+			from: "16:00",
+			want: []string{"wip", "release", "training"},
+		},
+		{
+			name: "to only",
+			to:   "12:30",
+			want: []string{"standup", "lunch", "training"},
+		},
+		{
+			// 終了時刻ちょうどに終わる予定と、開始時刻ちょうどに始まる予定は含めない
+			name: "boundaries",
+			from: "10:30", to: "12:00",
+			want: []string{"training"},
+		},
+		{
+			// --day-start より前の時刻は翌日の時刻として扱う
+			name:     "past midnight",
+			dayStart: 4 * time.Hour,
+			from:     "23:30", to: "02:00",
+			want: []string{"release", "training"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDayStart(t, tt.dayStart)
+			var opts agendaOptions
+			var err error
+			if tt.from != "" {
+				if opts.fromTime, err = parseClock(tt.from); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.to != "" {
+				if opts.toTime, err = parseClock(tt.to); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := eventIDs(opts.filterTimeRange(events, date(2024, 6, 14)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterTimeRange(%s-%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// 解釈できた日付は、表示しているタイムゾーンの0時で、同じ書き方に戻る
+func FuzzParseDateFlag(f *testing.F) {
+	for _, s := range []string{"2024-06-14", "2024-02-29", "2023-02-29", "2024-6-14", "0000-01-01", "9999-12-31", "2024-13-01", "2024-06-14T00:00:00Z"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if s == "" {
+			// 今日の日付になるため比べられない
+			return
+		}
+		got, err := parseDateFlag(s)
+		if err != nil {
+			return
+		}
+		if got.Location() != time.Local || got.Hour() != 0 || got.Minute() != 0 || got.Second() != 0 {
+			t.Errorf("parseDateFlag(%q) = %v, want midnight in the local time zone", s, got)
+		}
+		if back := got.Format("2006-01-02"); back != s {
+			t.Errorf("parseDateFlag(%q) = %v, formats back as %q", s, got, back)
+		}
+	})
+}
+
+// ベンチマーク用に、days 日分にわたる n 件の予定を作る（共有カレンダーのように同じ時間帯に多くの予定が重なる）
+//
+// 同じ件名と色の予定が続けて並ぶようにし、終日の予定と別のタイムゾーンで作成した予定も混ぜる。
+func benchmarkItems(n, days int) []*calendar.Event {
+	titles := []string{"定例", "1on1", "集中", "設計レビュー", "🚀 リリース判定", "顧客定例（オンライン）"}
+	tokyo := date(2024, 6, 1)
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	items := make([]*calendar.Event, n)
+	for i := range items {
+		item := &calendar.Event{
+			Id:          fmt.Sprintf("e%d", i),
+			Summary:     titles[i%len(titles)],
+			ColorId:     fmt.Sprint(i % 12),
+			Description: "#bench",
+		}
+		day := tokyo.AddDate(0, 0, i%days)
+		switch {
+		case i%50 == 0:
+			item.Start = &calendar.EventDateTime{Date: day.Format("2006-01-02")}
+			item.End = &calendar.EventDateTime{Date: day.AddDate(0, 0, 1+i%3).Format("2006-01-02")}
+		default:
+			start := day.Add(8*time.Hour + time.Duration(i*7%(12*60))*time.Minute)
+			end := start.Add(time.Duration(15+i%8*15) * time.Minute)
+			item.Start = &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)}
+			item.End = &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)}
+			if i%10 == 0 {
+				item.Start = &calendar.EventDateTime{DateTime: start.In(la).Format(time.RFC3339), TimeZone: "America/Los_Angeles"}
+				item.End = &calendar.EventDateTime{DateTime: end.In(la).Format(time.RFC3339), TimeZone: "America/Los_Angeles"}
+			}
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// 1日分の予定の日時のパースから表示までの各段階
+func BenchmarkRenderDay(b *testing.B) {
+	day := date(2024, 6, 1)
+	for _, n := range []int{500, 5000} {
+		items := benchmarkItems(n, 1)
+		events := selectDay(normalizeEvents("primary", items), day)
+		b.Run(fmt.Sprintf("normalize/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				normalizeEvents("primary", items)
+			}
+		})
+		b.Run(fmt.Sprintf("prepare/%d", n), func(b *testing.B) {
+			opts := goldenOptions(b, "--merge-adjacent", "--min-duration", "15m", "--redact-private")
+			b.ReportAllocs()
+			for range b.N {
+				opts.prepare(events)
+			}
+		})
+		formats := []struct {
+			name string
+			args []string
+		}{
+			{"text", nil},
+			{"markers", []string{"--markers", "--max-width", "60"}},
+			{"block", []string{"--format", "block"}},
+			{"timeline", []string{"--format", "timeline"}},
+		}
+		for _, f := range formats {
+			b.Run(fmt.Sprintf("print/%s/%d", f.name, n), func(b *testing.B) {
+				opts := goldenOptions(b, f.args...)
+				b.ReportAllocs()
+				for range b.N {
+					opts.print(io.Discard, day, events)
+				}
+			})
+		}
+		b.Run(fmt.Sprintf("json/%d", n), func(b *testing.B) {
+			opts := goldenOptions(b)
+			days := []renderDay{{date: day, events: events}}
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			for range b.N {
+				buf.Reset()
+				if err := renderAgendas(&buf, days, "json", opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
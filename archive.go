@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 日ごとの予定の記録の設定
+type archiveConfig struct {
+	// 取得した予定を YYYY-MM-DD.json として保存するディレクトリ（空なら保存しない）
+	Dir string `json:"dir,omitempty"`
+}
+
+// 記録のファイル
+func archivePath(dir string, day time.Time) string {
+	return filepath.Join(dir, day.Format("2006-01-02")+".json")
+}
+
+// 1日分の予定をフィクスチャと同じ形式で保存する
+//
+// 過去の日の記録は、あとでカレンダーから予定が削除されても残るよう上書きしない。
+// 今日以降の日はまだ変わるため、取得するたびに保存し直す。
+func archiveDay(dir string, day time.Time, events *calendar.Events, items []*calendar.Event, now time.Time) error {
+	path := archivePath(dir, day)
+	if day.Format("2006-01-02") < now.Format("2006-01-02") {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("Unable to create archive directory: %w", err)
+	}
+	out := *events
+	out.Items = items
+	out.NextPageToken, out.NextSyncToken = "", ""
+	b, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode archive: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("Unable to write archive: %w", err)
+	}
+	return nil
+}
+
+// 取得した予定を日ごとに記録する（保存に失敗しても表示は続ける）
+func (opts agendaOptions) archive(events *calendar.Events, days []time.Time) {
+	if opts.archiveDir == "" {
+		return
+	}
+	now := time.Now()
+	for _, day := range days {
+		if err := archiveDay(opts.archiveDir, day, events, eventsForDay(events.Items, day), now); err != nil {
+			slog.Warn("Unable to archive agenda", "date", day.Format("2006-01-02"), "error", err)
+		}
+	}
+}
+
+// daemon から今日の予定を記録する
+func archiveToday(ctx context.Context, client calendarClient, dir string, now time.Time) error {
+	startTime, endTime := dayWindow(now)
+	events, err := client.ListEvents(ctx, "primary", startTime, endTime)
+	if err != nil {
+		return err
+	}
+	return archiveDay(dir, now, events, eventsForDay(events.Items, now), now)
+}
+
+// 記録した日の予定（カレンダーから削除された予定も記録したときのまま）か、1件の予定の詳細を表示する
+func setupShow(fs *flag.FlagSet) func(args []string) error {
+	archived := fs.String("archived", "", "Show the agenda archived for this day (format: YYYY-MM-DD)")
+	raw := fs.Bool("raw", false, "Print the event resource as returned by the API (JSON)")
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event (for an event ID)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	opts := agendaOptions{}
+	addDisplayFlags(fs, &opts)
+	return func(args []string) error {
+		if *archived == "" && len(args) == 1 {
+			calID, eventID, err := resolveListedEvent(lastListingFile, args[0], *calendarID)
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			client, err := newClient(ctx, *fromFixture)
+			if err != nil {
+				return err
+			}
+			return showEvent(ctx, client, os.Stdout, calID, eventID, *raw)
+		}
+		if *archived == "" || len(args) != 0 {
+			return fmt.Errorf("Usage: %s show --archived YYYY-MM-DD | show <number|event-id> [--raw]", progName)
+		}
+		day, err := parseDateFlag(*archived)
+		if err != nil {
+			return err
+		}
+		if err := opts.loadConfig(); err != nil {
+			return err
+		}
+		if opts.archiveDir == "" {
+			return fmt.Errorf("No archive directory in %s (add \"archive\": {\"dir\": ...})", opts.configPath)
+		}
+		path := archivePath(opts.archiveDir, day)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("No archived agenda for %s", *archived)
+		}
+		events, err := loadFixture(path)
+		if err != nil {
+			return err
+		}
+		opts.defaultReminders = events.DefaultReminders
+		opts.print(os.Stdout, day, selectDay(normalizeEvents("primary", events.Items), day))
+		return nil
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 予定を変更する操作を記録するファイル（1行に1件の JSON、追記だけを行う）
+const auditLogFile = "audit.jsonl"
+
+// 実行しているサブコマンドの名前（監査ログに記録する）
+var auditCommand = progName
+
+// 監査ログの1件
+type auditEntry struct {
+	// 記録の ID（記録した時刻のナノ秒）
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Op       string    `json:"op"` // create、update、delete
+	Calendar string    `json:"calendar"`
+	EventID  string    `json:"event_id"`
+	Summary  string    `json:"summary,omitempty"`
+	// 変更前と変更後の予定（create に before はなく、delete に after はない）
+	Before *calendar.Event `json:"before,omitempty"`
+	After  *calendar.Event `json:"after,omitempty"`
+	// undo で取り消した記録の ID
+	UndoOf string `json:"undo_of,omitempty"`
+	// 1回のコマンドの実行で記録した操作に共通の ID（undo はこの単位でまとめて取り消す）
+	Operation string `json:"operation,omitempty"`
+}
+
+// 予定を変更する操作を監査ログに記録するクライアント
+//
+// 変更と削除の前に予定を取得し、変更前の予定として記録する（取得できなければ記録しない）。
+type auditClient struct {
+	calendarWriter
+	path string
+	// 記録に付ける操作の ID（newWriteClient で作成したときに決める）
+	operation string
+	// undo で実行する操作の場合、取り消す記録の ID
+	undoOf string
+}
+
+func newAuditClient(w calendarWriter, path string) *auditClient {
+	return &auditClient{calendarWriter: w, path: path, operation: newAuditOperation()}
+}
+
+// 操作の ID（作成した時刻のナノ秒）
+func newAuditOperation() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+// 以降の記録を新しい操作とする（daemon のように1回の実行で何度も変更する場合に、変更ごとに取り消せるようにする）
+func startAuditOperation(client calendarWriter) {
+	if ac, ok := client.(*auditClient); ok {
+		ac.operation = newAuditOperation()
+	}
+}
+
+func (c *auditClient) InsertEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	created, err := c.calendarWriter.InsertEvent(ctx, calendarID, event)
+	if err != nil {
+		return nil, err
+	}
+	c.record("create", calendarID, created.Id, nil, created)
+	return created, nil
+}
+
+func (c *auditClient) UpdateEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	before := c.snapshot(ctx, calendarID, event.Id)
+	updated, err := c.calendarWriter.UpdateEvent(ctx, calendarID, event)
+	if err != nil {
+		return nil, err
+	}
+	c.record("update", calendarID, event.Id, before, updated)
+	return updated, nil
+}
+
+func (c *auditClient) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	before := c.snapshot(ctx, calendarID, eventID)
+	if err := c.calendarWriter.DeleteEvent(ctx, calendarID, eventID); err != nil {
+		return err
+	}
+	c.record("delete", calendarID, eventID, before, nil)
+	return nil
+}
+
+func (c *auditClient) snapshot(ctx context.Context, calendarID, eventID string) *calendar.Event {
+	event, err := c.calendarWriter.GetEvent(ctx, calendarID, eventID)
+	if err != nil {
+		slog.Warn("Unable to record the event before changing it", "event", eventID, "error", err)
+		return nil
+	}
+	return event
+}
+
+// 監査ログに追記する（書き込めなくても操作は失敗させない）
+func (c *auditClient) record(op, calendarID, eventID string, before, after *calendar.Event) {
+	now := time.Now()
+	e := auditEntry{
+		ID:        strconv.FormatInt(now.UnixNano(), 10),
+		Time:      now,
+		Command:   auditCommand,
+		Op:        op,
+		Calendar:  calendarID,
+		EventID:   eventID,
+		Before:    before,
+		After:     after,
+		UndoOf:    c.undoOf,
+		Operation: c.operation,
+	}
+	for _, ev := range []*calendar.Event{after, before} {
+		if ev != nil {
+			e.Summary = ev.Summary
+			break
+		}
+	}
+	if err := appendAuditEntry(c.path, e); err != nil {
+		slog.Warn("Unable to write audit log", "error", err)
+	}
+}
+
+func appendAuditEntry(path string, e auditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// 監査ログを古い順に読み込む（ファイルがなければ空）
+func readAuditLog(path string) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read audit log: %w", err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e auditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("Unable to parse audit log: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// 「2025-01-15 10:00 move update 定例 (primary)」
+func formatAuditEntry(e auditEntry) string {
+	s := fmt.Sprintf("%s %s %s %s (%s)", e.Time.Local().Format("2006-01-02 15:04"), e.Command, e.Op, e.Summary, e.Calendar)
+	if e.UndoOf != "" {
+		s += " [undo]"
+	}
+	return s
+}
+
+// 監査ログの直近の記録を表示する
+func setupAudit(fs *flag.FlagSet) func(args []string) error {
+	limit := fs.Int("limit", 20, "Show at most this many recent entries (0 for all)")
+	return func(args []string) error {
+		entries, err := readAuditLog(auditLogFile)
+		if err != nil {
+			return err
+		}
+		if *limit > 0 && len(entries) > *limit {
+			entries = entries[len(entries)-*limit:]
+		}
+		for _, e := range entries {
+			fmt.Println(formatAuditEntry(e))
+		}
+		return nil
+	}
+}
+
+// 直近の（まだ取り消していない）操作を取り消す
+//
+// 1回のコマンドの実行（plan apply や copy-day など）で記録した変更は、新しいものから順にまとめて取り消す。
+// 作成した予定は削除し、変更（移動を含む）した予定は変更前の内容に戻し、
+// 削除した予定は記録した内容で作り直す。
+func setupUndo(fs *flag.FlagSet) func(args []string) error {
+	dryRun := fs.Bool("dry-run", false, "Show what would be undone without changing anything")
+	return func(args []string) error {
+		entries, err := readAuditLog(auditLogFile)
+		if err != nil {
+			return err
+		}
+		targets := lastUndoable(entries)
+		if len(targets) == 0 {
+			return fmt.Errorf("Nothing to undo in %s", auditLogFile)
+		}
+		if *dryRun {
+			for _, target := range targets {
+				fmt.Printf("取り消す操作: %s\n", formatAuditEntry(target))
+			}
+			return nil
+		}
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, "", calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		ac, _ := client.(*auditClient)
+		for _, target := range targets {
+			if ac != nil {
+				ac.undoOf = target.ID
+			}
+			// 途中で失敗しても、取り消した分は記録されているため、もう一度 undo すると残りを取り消す
+			if err := undoEntry(ctx, client, target); err != nil {
+				return fmt.Errorf("Unable to undo %s: %w", formatAuditEntry(target), err)
+			}
+			fmt.Printf("取り消しました: %s\n", formatAuditEntry(target))
+		}
+		return nil
+	}
+}
+
+// 取り消していない最新の操作の記録を、新しい順に返す（undo 自身の記録は除く）
+//
+// 操作の ID がない古い記録は、1件ずつ取り消す。
+func lastUndoable(entries []auditEntry) []auditEntry {
+	undone := map[string]bool{}
+	for _, e := range entries {
+		if e.UndoOf != "" {
+			undone[e.UndoOf] = true
+		}
+	}
+	var out []auditEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.UndoOf != "" || undone[e.ID] {
+			continue
+		}
+		if len(out) == 0 {
+			out = append(out, e)
+			if e.Operation == "" {
+				break
+			}
+		} else if e.Operation == out[0].Operation {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func undoEntry(ctx context.Context, client calendarWriter, e auditEntry) error {
+	switch e.Op {
+	case "create":
+		return client.DeleteEvent(ctx, e.Calendar, e.EventID)
+	case "update":
+		if e.Before == nil {
+			return fmt.Errorf("Cannot undo: the event was not recorded before %s changed it", e.Command)
+		}
+		current, err := client.GetEvent(ctx, e.Calendar, e.EventID)
+		if err != nil {
+			return err
+		}
+		restored := *e.Before
+		// 古い sequence のままでは更新できないため、今の値に合わせる
+		restored.Sequence = current.Sequence
+		restored.Etag = ""
+		_, err = client.UpdateEvent(ctx, e.Calendar, &restored)
+		return err
+	case "delete":
+		if e.Before == nil {
+			return fmt.Errorf("Cannot undo: the event was not recorded before %s deleted it", e.Command)
+		}
+		_, err := client.InsertEvent(ctx, e.Calendar, recreatedEvent(e.Before))
+		return err
+	}
+	return fmt.Errorf("Cannot undo %s of %q", e.Op, e.Summary)
+}
+
+// 削除した予定を作り直すための予定（ID やリンクなど Google が割り当てる項目を除き、ほかはそのまま使う）
+//
+// 削除した予定の ID は使い回せないため、作り直した予定の ID は元と異なる。
+// 繰り返し予定の1回分は、単独の予定として作り直す。
+func recreatedEvent(before *calendar.Event) *calendar.Event {
+	event := *before
+	event.Id = ""
+	event.Etag = ""
+	event.ICalUID = ""
+	event.HtmlLink = ""
+	event.HangoutLink = ""
+	event.Created = ""
+	event.Updated = ""
+	event.Sequence = 0
+	event.Status = ""
+	event.RecurringEventId = ""
+	event.OriginalStartTime = nil
+	event.Creator = nil
+	event.Organizer = nil
+	event.ConferenceData = nil
+	return &event
+}
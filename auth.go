@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// 認可が必要な（トークンがない、または無効になった）ことを表すエラー
+var errAuthRequired = errors.New("authorization required")
+
+// 認可したトークンを保存するファイル
+const tokenFile = "token.json"
+
+// Retrieve a token, saves the token, then returns the generated client.
+//
+// 保存したトークンに config.Scopes の権限がなければ、端末で確認してから
+// これまでの権限と合わせて認可し直す（403 で失敗させない）。
+func getClient(config *oauth2.Config) (*http.Client, error) {
+	// トークンの取得・更新も含めて共有のトランスポートを使う
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, baseHTTPClient())
+
+	// The file token.json stores the user's access and refresh tokens, and is
+	// created automatically when the authorization flow completes for the first
+	// time.
+	saved, err := tokenFromFile(tokenFile)
+	if err != nil {
+		// cron などから端末なしで実行された場合は認可コードを入力できない
+		if !isTerminal(os.Stdin) {
+			return nil, fmt.Errorf("%w: run interactively once to create %s", errAuthRequired, tokenFile)
+		}
+		if saved, err = authorize(ctx, config); err != nil {
+			return nil, err
+		}
+	} else if globalOpts.encryptToken && !tokenFileEncrypted(tokenFile) {
+		// --encrypt-token を付けて初めて実行したときに、平文のトークンを暗号化し直す
+		if err := saveToken(tokenFile, saved); err != nil {
+			return nil, err
+		}
+	}
+	if len(saved.Scopes) == 0 {
+		// 権限を記録する前に保存したトークンは、Google に問い合わせて記録する
+		if scopes, err := lookupTokenScopes(ctx, config, saved.Token); err == nil {
+			saved.Scopes = scopes
+			if err := saveToken(tokenFile, saved); err != nil {
+				return nil, err
+			}
+		} else {
+			slog.Debug("Unable to look up token scopes", "error", err)
+		}
+	}
+	if missing := missingScopes(saved.Scopes, config.Scopes); len(saved.Scopes) > 0 && len(missing) > 0 {
+		if saved, err = upgradeScopes(ctx, config, saved, missing); err != nil {
+			return nil, err
+		}
+	}
+	client := config.Client(ctx, saved.Token)
+	client.Timeout = globalOpts.httpTimeout
+	return client, nil
+}
+
+// ブラウザで認可してトークンを保存する（opts は認可の URL に付けるパラメーター）
+func authorize(ctx context.Context, config *oauth2.Config, opts ...oauth2.AuthCodeOption) (*savedToken, error) {
+	tok, err := getTokenFromWeb(ctx, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	saved := &savedToken{Token: tok, Scopes: grantedScopes(tok, config.Scopes)}
+	fmt.Printf("Saving credential file to: %s\n", tokenFile)
+	if err := saveToken(tokenFile, saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Request a token from the web, then returns the retrieved token.
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	authURL := config.AuthCodeURL("state-token", append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, opts...)...)
+	fmt.Printf("Go to the following link in your browser then type the "+
+		"authorization code: \n%v\n", authURL)
+
+	var authCode string
+	if _, err := fmt.Scan(&authCode); err != nil {
+		return nil, fmt.Errorf("Unable to read authorization code: %w", err)
+	}
+
+	tok, err := config.Exchange(ctx, authCode)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve token from web: %w", err)
+	}
+	return tok, nil
+}
+
+// token.json の内容（トークンと、認可した権限）
+type savedToken struct {
+	*oauth2.Token
+	// 認可した OAuth のスコープ（記録する前に保存したトークンでは空）
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Retrieves a token from a local file.
+func tokenFromFile(file string) (*savedToken, error) {
+	b, err := readTokenFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tok := &savedToken{Token: &oauth2.Token{}}
+	err = json.Unmarshal(b, tok)
+	return tok, err
+}
+
+// Saves a token to a file path.
+func saveToken(path string, token *savedToken) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("Unable to cache oauth token: %w", err)
+	}
+	return writeTokenFile(path, b)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// OAuth クライアントの設定のファイル
+const credentialsFile = "credentials.json"
+
+// ビルド時に埋め込む OAuth クライアントの設定（credentials.json の内容か、それを base64 にしたもの）
+//
+//	go build -ldflags "-X main.embeddedCredentials=$(base64 -w0 credentials.json)"
+//
+// または -tags embedcredentials でビルドすると、credentials.json をそのまま埋め込む（credentials_embed.go）。
+// credentials.json があればそちらを優先する。
+var embeddedCredentials string
+
+// OAuth クライアントの設定を読み込む（credentials.json がなければ埋め込まれた設定）
+func readClientCredentials() ([]byte, error) {
+	b, err := os.ReadFile(credentialsFile)
+	if err == nil {
+		return b, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("Unable to read client secret file: %w", err)
+	}
+	if b, ok := decodeEmbeddedCredentials(); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("Unable to read client secret file: %w (run %s init to set up)", err, progName)
+}
+
+func hasEmbeddedCredentials() bool {
+	_, ok := decodeEmbeddedCredentials()
+	return ok
+}
+
+// 埋め込まれた設定（JSON のままか base64）を返す
+func decodeEmbeddedCredentials() ([]byte, bool) {
+	s := strings.TrimSpace(embeddedCredentials)
+	if s == "" {
+		return nil, false
+	}
+	if strings.HasPrefix(s, "{") {
+		return []byte(s), true
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// credentials.json と token.json から Calendar API のサービスを作成する
+//
+// scope は token.json がなく新しく認可するときに求める権限。
+func newCalendarService(ctx context.Context, scope string) (*calendar.Service, error) {
+	b, err := readClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := google.ConfigFromJSON(b, scope)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse client secret file to config: %w", err)
+	}
+	client, err := getClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve Calendar client: %w", err)
+	}
+	return srv, nil
+}
+
+// API 呼び出しのエラーがトークンの失効など認可の問題によるものか判定する
+func isAuthError(err error) bool {
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) {
+		return true
+	}
+	var ge *googleapi.Error
+	return errors.As(err, &ge) && ge.Code == http.StatusUnauthorized || isInsufficientScope(err)
+}
+
+// 読み取り専用で認可したトークンで予定を変更しようとした場合など、トークンの権限が足りないか判定する
+func isInsufficientScope(err error) bool {
+	var ge *googleapi.Error
+	if !errors.As(err, &ge) || ge.Code != http.StatusForbidden {
+		return false
+	}
+	for _, e := range ge.Errors {
+		if e.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+	return strings.Contains(ge.Message, "insufficient authentication scopes")
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 時間帯 [start, end)
+type interval struct {
+	start, end time.Time
+}
+
+func (iv interval) duration() time.Duration {
+	return iv.end.Sub(iv.start)
+}
+
+// 曜日の表示名
+var weekdayNames = map[string][7]string{
+	"en": {"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	"ja": {"日", "月", "火", "水", "木", "金", "土"},
+}
+
+// 空き時間の抽出条件
+type availabilityOptions struct {
+	days      int
+	workStart time.Duration // 0時からの経過時間
+	workEnd   time.Duration
+	minSlot   time.Duration
+	lang      string
+	// 併記するタイムゾーン（--also-tz）
+	alsoTZ []*time.Location
+	// 「空き時間」に設定された予定も予定ありとして扱う
+	includeFree bool
+}
+
+// 今後の営業日の空き時間をメールに貼り付けられる形式で出力する
+func setupAvailability(fs *flag.FlagSet) func(args []string) error {
+	opts := availabilityOptions{}
+	fs.IntVar(&opts.days, "days", 5, "Number of business days to list")
+	workStart := fs.String("work-start", "09:00", "Start of working hours (HH:MM)")
+	workEnd := fs.String("work-end", "18:00", "End of working hours (HH:MM)")
+	fs.DurationVar(&opts.minSlot, "min-slot", 30*time.Minute, "Minimum length of a free slot")
+	fs.StringVar(&opts.lang, "lang", "ja", "Output language (en, ja)")
+	var alsoTZ stringList
+	fs.Var(&alsoTZ, "also-tz", "Also show slots in this timezone (e.g. America/New_York; repeatable)")
+	fs.BoolVar(&opts.includeFree, "include-free", false, "Treat events marked as free (transparent) as busy")
+	fromFixture := fs.String("from-fixture", "", "Compute availability from a saved API response instead of calling Google")
+	return func(args []string) error {
+		var err error
+		for _, name := range alsoTZ {
+			loc, err := time.LoadLocation(name)
+			if err != nil {
+				return fmt.Errorf("Unknown timezone %q: %w", name, err)
+			}
+			opts.alsoTZ = append(opts.alsoTZ, loc)
+		}
+		if opts.workStart, err = parseClock(*workStart); err != nil {
+			return err
+		}
+		if opts.workEnd, err = parseClock(*workEnd); err != nil {
+			return err
+		}
+		if opts.workEnd <= opts.workStart {
+			return fmt.Errorf("--work-end must be after --work-start")
+		}
+		if _, ok := weekdayNames[opts.lang]; !ok {
+			return fmt.Errorf("Unsupported language: %s", opts.lang)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		return runAvailability(ctx, client, os.Stdout, time.Now(), opts)
+	}
+}
+
+// "HH:MM" を0時からの経過時間に変換する
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time format. Please use HH:MM format: %w", err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// 土日を除いた日かどうか
+func isBusinessDay(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
+
+// now 以降の営業日を n 日分返す（今日の勤務時間がまだ残っていれば今日を含む）
+func businessDays(now time.Time, n int, workEnd time.Duration) []time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !now.Before(atClock(day, workEnd)) {
+		day = day.AddDate(0, 0, 1)
+	}
+	var days []time.Time
+	for ; len(days) < n; day = day.AddDate(0, 0, 1) {
+		if isBusinessDay(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func runAvailability(ctx context.Context, client calendarClient, w io.Writer, now time.Time, opts availabilityOptions) error {
+	days := businessDays(now, opts.days, opts.workEnd)
+	if len(days) == 0 {
+		return nil
+	}
+	timeMin := days[0]
+	timeMax := days[len(days)-1].AddDate(0, 0, 1)
+
+	busy, err := client.FreeBusy(ctx, []string{"primary"}, timeMin, timeMax)
+	if err != nil {
+		return err
+	}
+	if opts.includeFree {
+		free, err := freeEventIntervals(ctx, client, "primary", timeMin, timeMax)
+		if err != nil {
+			return err
+		}
+		busy = append(busy, free...)
+	}
+
+	for _, day := range days {
+		slots := freeSlots(day, now, busy, opts)
+		if len(opts.alsoTZ) == 0 {
+			fmt.Fprintln(w, formatAvailabilityLine(day, slots, opts.lang))
+			continue
+		}
+		// 他のタイムゾーンを併記する場合は日付がずれることがあるため1枠1行にする
+		if len(slots) == 0 {
+			fmt.Fprintln(w, formatAvailabilityLine(day, nil, opts.lang))
+		}
+		rows := make([][]string, len(slots))
+		for i, s := range slots {
+			rows[i] = []string{formatSlotInZone(s, day.Location(), opts.lang)}
+			for _, loc := range opts.alsoTZ {
+				rows[i] = append(rows[i], formatSlotInZone(s, loc, opts.lang))
+			}
+		}
+		// 曜日や全角の文字を含んでいても「|」の位置がそろうよう、列ごとに表示幅をそろえる
+		for c := 0; c < len(opts.alsoTZ); c++ {
+			var col []string
+			for _, row := range rows {
+				col = append(col, row[c])
+			}
+			n := maxWidth(col)
+			for _, row := range rows {
+				row[c] = padRight(row[c], n)
+			}
+		}
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, " | "))
+		}
+	}
+	return nil
+}
+
+// 1日の勤務時間から予定の入っている時間を除き、minSlot 以上の空き時間を返す
+func freeSlots(day, now time.Time, busy []interval, opts availabilityOptions) []interval {
+	work := interval{start: atClock(day, opts.workStart), end: atClock(day, opts.workEnd)}
+	// 今日の場合は現在時刻以降（15分単位に切り上げ）だけを対象にする
+	if cutoff := roundUp(now, 15*time.Minute); cutoff.After(work.start) {
+		work.start = cutoff
+	}
+	if !work.start.Before(work.end) {
+		return nil
+	}
+
+	var slots []interval
+	cursor := work.start
+	for _, b := range mergeIntervals(busy) {
+		if !b.end.After(cursor) || !b.start.Before(work.end) {
+			continue
+		}
+		if b.start.After(cursor) {
+			slots = append(slots, interval{start: cursor, end: b.start})
+		}
+		cursor = b.end
+	}
+	if cursor.Before(work.end) {
+		slots = append(slots, interval{start: cursor, end: work.end})
+	}
+
+	var out []interval
+	for _, s := range slots {
+		if s.duration() >= opts.minSlot {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// 重なっている時間帯をまとめ、開始時刻順に並べて返す
+func mergeIntervals(ivs []interval) []interval {
+	sorted := append([]interval{}, ivs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+
+	var out []interval
+	for _, iv := range sorted {
+		if n := len(out); n > 0 && !iv.start.After(out[n-1].end) {
+			if iv.end.After(out[n-1].end) {
+				out[n-1].end = iv.end
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+func roundUp(t time.Time, d time.Duration) time.Time {
+	r := t.Truncate(d)
+	if r.Before(t) {
+		r = r.Add(d)
+	}
+	return r
+}
+
+// "Mon 6/17 10:00–12:00, 14:00–16:00 (JST)" / "6/17(月) 10:00〜12:00、14:00〜16:00 (JST)"
+func formatAvailabilityLine(day time.Time, slots []interval, lang string) string {
+	wd := weekdayNames[lang][day.Weekday()]
+	zone := day.Format("MST")
+
+	var ranges []string
+	for _, s := range slots {
+		if lang == "ja" {
+			ranges = append(ranges, s.start.Format("15:04")+"〜"+s.end.Format("15:04"))
+		} else {
+			ranges = append(ranges, s.start.Format("15:04")+"–"+s.end.Format("15:04"))
+		}
+	}
+
+	if lang == "ja" {
+		if len(ranges) == 0 {
+			return fmt.Sprintf("%d/%d(%s) 空きなし", day.Month(), day.Day(), wd)
+		}
+		return fmt.Sprintf("%d/%d(%s) %s (%s)", day.Month(), day.Day(), wd, strings.Join(ranges, "、"), zone)
+	}
+	if len(ranges) == 0 {
+		return fmt.Sprintf("%s %d/%d no availability", wd, day.Month(), day.Day())
+	}
+	return fmt.Sprintf("%s %d/%d %s (%s)", wd, day.Month(), day.Day(), strings.Join(ranges, ", "), zone)
+}
+
+// 空き枠1つを指定したタイムゾーンの日付・時刻で表す
+//
+//	"Thu 10/15 09:30–12:00 JST" / "10/15(木) 09:30〜12:00 JST"
+//
+// 終了が翌日になる場合は終了時刻に "(+1)" を付ける。
+func formatSlotInZone(s interval, loc *time.Location, lang string) string {
+	start, end := s.start.In(loc), s.end.In(loc)
+	wd := weekdayNames[lang][start.Weekday()]
+
+	endStr := end.Format("15:04")
+	if end.YearDay() != start.YearDay() {
+		endStr += "(+1)"
+	}
+	if lang == "ja" {
+		return fmt.Sprintf("%d/%d(%s) %s〜%s %s", start.Month(), start.Day(), wd, start.Format("15:04"), endStr, start.Format("MST"))
+	}
+	return fmt.Sprintf("%s %d/%d %s–%s %s", wd, start.Month(), start.Day(), start.Format("15:04"), endStr, start.Format("MST"))
+}
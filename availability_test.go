@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "00:00", want: 0},
+		{in: "09:00", want: 9 * time.Hour},
+		{in: "9:05", want: 9*time.Hour + 5*time.Minute},
+		{in: "23:59", want: 23*time.Hour + 59*time.Minute},
+		{in: "24:00", wantErr: true},
+		{in: "12:60", wantErr: true},
+		{in: "0900", wantErr: true},
+		{in: " 09:00", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseClock(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseClock(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parseClock(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// 解釈できた時刻は1日のうちの分単位の時刻で、"HH:MM" に書き直しても同じ時刻になる
+func FuzzParseClock(f *testing.F) {
+	for _, s := range []string{"00:00", "09:00", "9:05", "23:59", "24:00", "12:60", "0900", " 09:00", "-1:00", "09:00:00"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := parseClock(s)
+		if err != nil {
+			return
+		}
+		if got < 0 || got >= 24*time.Hour || got%time.Minute != 0 {
+			t.Fatalf("parseClock(%q) = %v, want a minute of the day", s, got)
+		}
+		clock := fmt.Sprintf("%02d:%02d", got/time.Hour, got%time.Hour/time.Minute)
+		if again, err := parseClock(clock); err != nil || again != got {
+			t.Errorf("parseClock(%q) = %v, but parseClock(%q) = %v, %v", s, got, clock, again, err)
+		}
+	})
+}
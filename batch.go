@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gcal-daily-agenda/schema"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 複数日分の予定を1回の取得でまとめて出力する
+func setupBatch(fs *flag.FlagSet) func(args []string) error {
+	fromStr := fs.String("from", "", "First date (format: YYYY-MM-DD)")
+	toStr := fs.String("to", "", "Last date (format: YYYY-MM-DD)")
+	datesPath := fs.String("dates", "", "Read dates (YYYY-MM-DD, one per line) from this file or - for stdin instead of --from/--to")
+	outDir := fs.String("per-day-output", "", "Write each day to DIR/YYYY-MM-DD.txt instead of stdout")
+	format := fs.String("format", "text", "Output format: text, json (one object per day and line) or ndjson (one object per event and line, streamed)")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation when the range exceeds max_range_days")
+	fromFixture := fs.String("from-fixture", "", "Render events from a saved API response instead of calling Google")
+	opts := agendaOptions{}
+	fs.BoolVar(&opts.journal, "journal", false, "Print what actually happened (moved events and attendance) for past dates")
+	addDisplayFlags(fs, &opts)
+	return func(args []string) error {
+		var days []time.Time
+		switch {
+		case *datesPath != "":
+			var err error
+			if days, err = readDates(*datesPath); err != nil {
+				return err
+			}
+		case *fromStr == "" || *toStr == "":
+			return fmt.Errorf("Usage: %s batch (--from YYYY-MM-DD --to YYYY-MM-DD | --dates FILE) [--per-day-output DIR]", progName)
+		default:
+			from, err := parseDateFlag(*fromStr)
+			if err != nil {
+				return err
+			}
+			to, err := parseDateFlag(*toStr)
+			if err != nil {
+				return err
+			}
+			if to.Before(from) {
+				return fmt.Errorf("--to (%s) is before --from (%s)", *toStr, *fromStr)
+			}
+			days = daysBetween(from, to)
+		}
+		switch {
+		case *format != "text" && *format != "json" && *format != "ndjson":
+			return fmt.Errorf("Unsupported --format %q (expected text, json or ndjson)", *format)
+		case *format != "text" && *outDir != "":
+			return fmt.Errorf("--format %s cannot be used with --per-day-output", *format)
+		}
+		if err := opts.loadConfig(); err != nil {
+			return err
+		}
+		if err := confirmRange(days[0], days[len(days)-1], opts.maxRangeDays, *yes); err != nil {
+			return err
+		}
+		// ファイルに書き出す場合は端末向けのリンクと色を含めない
+		if *outDir != "" && opts.hyperlinkMode == "auto" {
+			opts.hyperlinks = false
+		}
+		if *outDir != "" {
+			opts.ansi = false
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return runBatchJSON(ctx, client, os.Stdout, days, opts)
+		case "ndjson":
+			return runBatchNDJSON(ctx, client, os.Stdout, days, opts)
+		}
+		return runBatch(ctx, client, days, *outDir, opts)
+	}
+}
+
+// path（"-" なら標準入力）から1行に1つずつ日付を読み込み、日付順に重複を除いて返す（空行は無視する）
+func readDates(path string) ([]time.Time, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read dates: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var days []time.Time
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || seen[line] {
+			continue
+		}
+		day, err := parseDateFlag(line)
+		if err != nil {
+			return nil, err
+		}
+		seen[line] = true
+		days = append(days, day)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read dates: %w", err)
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("No dates given in %s", path)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+func runBatch(ctx context.Context, client calendarClient, days []time.Time, outDir string, opts agendaOptions) error {
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return fmt.Errorf("Unable to create output directory: %w", err)
+		}
+	}
+
+	// rangeChunkDays 日ごとに1回で取得し、日ごとの振り分けはローカルで行う
+	first := true
+	for _, chunk := range chunkDays(days, rangeChunkDays) {
+		events, err := fetchRange(ctx, client, chunk[0], chunk[len(chunk)-1])
+		if err != nil {
+			return err
+		}
+		opts.defaultReminders = events.DefaultReminders
+
+		opts.archive(events, chunk)
+		for i, out := range renderDays(events.Items, chunk, opts.print) {
+			if outDir == "" {
+				if !first {
+					fmt.Println()
+				}
+				first = false
+				os.Stdout.Write(out)
+				continue
+			}
+			path := filepath.Join(outDir, chunk[i].Format("2006-01-02")+".txt")
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				return fmt.Errorf("Unable to write %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// 日ごとの予定を1行に1つの JSON（serve の /api/agenda と同じ形式）で出力する
+func runBatchJSON(ctx context.Context, client calendarClient, w io.Writer, days []time.Time, opts agendaOptions) error {
+	pal := colors(ctx, client)
+	enc := json.NewEncoder(w)
+	for _, chunk := range chunkDays(days, rangeChunkDays) {
+		events, err := fetchRange(ctx, client, chunk[0], chunk[len(chunk)-1])
+		if err != nil {
+			return err
+		}
+		opts.archive(events, chunk)
+		normalized := normalizeEvents("primary", events.Items)
+		for _, day := range chunk {
+			agenda := schema.NewAgenda(day.Format("2006-01-02"))
+			for _, ev := range opts.prepare(selectDay(normalized, day)) {
+				e := newJSONEvent(ev.calendarID, ev.item)
+				e.ColorHex = pal.eventColor(ev.item.ColorId)
+				agenda.Events = append(agenda.Events, e)
+			}
+			if err := enc.Encode(agenda); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ndjson で出力する予定（表示される日を付ける）
+type ndjsonEvent struct {
+	SchemaVersion int    `json:"schema_version"`
+	Date          string `json:"date"`
+	jsonEvent
+}
+
+// 予定を取得したページから順に、表示される日ごとに1行の JSON で出力する
+//
+// 日をまたぐ予定は表示される日ごとに出力する。期間全体の予定をメモリに載せないため、
+// 予定は開始時刻順（取得した順）に並び、日付順にはならない場合がある。
+func runBatchNDJSON(ctx context.Context, client calendarClient, w io.Writer, days []time.Time, opts agendaOptions) error {
+	wanted := map[string]bool{}
+	for _, day := range days {
+		wanted[day.Format("2006-01-02")] = true
+	}
+	first, last := days[0], days[len(days)-1]
+	pal := colors(ctx, client)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	emit := func(item *calendar.Event) error {
+		// 1件ずつ出力するため、件数の制限と並べ替えは行わない
+		one := opts
+		one.skip, one.limit = 0, 0
+		ev, err := newAgendaEvent("primary", item)
+		if err != nil {
+			slog.Warn("Skipping event", "calendar", "primary", "error", err)
+			return nil
+		}
+		events := one.prepare([]agendaEvent{ev})
+		if len(events) == 0 {
+			return nil
+		}
+		// 表示される可能性があるのは開始日の前日から終了日の翌日まで
+		from, to := ev.start.AddDate(0, 0, -1), ev.end.AddDate(0, 0, 1)
+		if from.Before(first) {
+			from = first
+		}
+		if to.After(last) {
+			to = last
+		}
+		for _, day := range daysBetween(dateOf(from, first.Location()), dateOf(to, first.Location())) {
+			if !wanted[day.Format("2006-01-02")] || len(selectDay(events, day)) == 0 {
+				continue
+			}
+			e := newJSONEvent("primary", events[0].item)
+			e.ColorHex = pal.eventColor(events[0].item.ColorId)
+			if err := enc.Encode(ndjsonEvent{SchemaVersion: schema.Version, Date: day.Format("2006-01-02"), jsonEvent: e}); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}
+
+	startTime, _ := dayWindow(first)
+	_, endTime := dayWindow(last)
+	if s, ok := client.(eventStreamer); ok {
+		return s.StreamEvents(ctx, "primary", startTime, endTime, emit)
+	}
+	events, err := client.ListEvents(ctx, "primary", startTime, endTime)
+	if err != nil {
+		return err
+	}
+	for _, item := range events.Items {
+		if err := emit(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// t の日付の0時（loc のタイムゾーン）
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
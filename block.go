@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// --format block の表示の設定
+type blockOptions struct {
+	// 枠線の内側の表示幅
+	width int
+	// 予定の行数（足りない分は空行で埋め、入りきらない分は「他N件」にまとめる。0 は予定の数だけ）
+	lines int
+	// 罫線の枠で囲む
+	border bool
+}
+
+// Conky や GeekTool のように毎分描き直すツール向けに、1日分の予定を幅の揃った文字のブロックにする
+//
+// どの行も表示幅が width になるよう切り詰めて空白で埋めるため、予定が変わっても枠や列がずれない。
+// 端末のリンクと色は付けない。
+func printBlock(w io.Writer, targetDate time.Time, events []agendaEvent, opts agendaOptions) {
+	b := opts.block
+	_, events = splitWorkingLocations(events)
+	header := formatDateHeader(targetDate, opts.lang, opts.era)
+	count := fmt.Sprintf("%d件", len(events))
+	lines := []string{padRight(truncateWidth(header, b.width-displayWidth(count)-1), b.width-displayWidth(count)) + count}
+
+	shown := events
+	if b.lines > 0 && len(events) > b.lines {
+		shown = events[:b.lines-1]
+	}
+	for _, e := range shown {
+		lines = append(lines, blockLine(e, b.width))
+	}
+	if len(shown) < len(events) {
+		lines = append(lines, fmt.Sprintf("他%d件", len(events)-len(shown)))
+	}
+	if len(events) == 0 {
+		lines = append(lines, "予定はありません")
+	}
+	for len(lines) < b.lines+1 {
+		lines = append(lines, "")
+	}
+
+	if b.border {
+		fmt.Fprintln(w, "┌"+strings.Repeat("─", b.width+2)+"┐")
+	}
+	for i, l := range lines {
+		l = padRight(truncateWidth(l, b.width), b.width)
+		if b.border {
+			fmt.Fprintln(w, "│ "+l+" │")
+			if i == 0 {
+				fmt.Fprintln(w, "├"+strings.Repeat("─", b.width+2)+"┤")
+			}
+			continue
+		}
+		fmt.Fprintln(w, l)
+	}
+	if b.border {
+		fmt.Fprintln(w, "└"+strings.Repeat("─", b.width+2)+"┘")
+	}
+}
+
+// 「10:00-10:30 定例」（終日の予定は時刻の列を「終日」にする）
+func blockLine(e agendaEvent, width int) string {
+	clock := "終日"
+	if !e.allDay {
+		clock = e.start.Format("15:04") + "-" + e.end.Format("15:04")
+	}
+	prefix := padRight(clock, len("15:04-15:04")) + " "
+	return prefix + truncateWidth(strings.TrimSpace(e.item.Summary), width-displayWidth(prefix))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// --calendar で指定したカレンダー（指定がなければ設定ファイルの calendars）
+func (c *config) selectCalendars(ctx context.Context, client calendarClient, selectors []string) ([]string, error) {
+	if len(selectors) == 0 {
+		return c.calendarIDs(), nil
+	}
+	return resolveCalendars(ctx, client, c.Groups, selectors)
+}
+
+// serve で指定されたカレンダー（指定がなければ設定ファイルの calendars）
+//
+// 共有トークンを持つ人が、設定していないカレンダーを読めないよう、設定ファイルの calendars にある ID と
+// groups の "@名前" だけを受け付ける（名前の正規表現やほかのカレンダーID は受け付けない）。
+func (c *config) servedCalendars(ctx context.Context, client calendarClient, selectors []string) ([]string, error) {
+	if len(selectors) == 0 {
+		return c.calendarIDs(), nil
+	}
+	served := c.servedCalendarSet()
+	for _, sel := range selectors {
+		if name, ok := strings.CutPrefix(sel, "@"); ok {
+			if _, ok := c.Groups[name]; ok {
+				continue
+			}
+		} else if served[sel] {
+			continue
+		}
+		return nil, fmt.Errorf("Calendar %q is not served; use a calendar ID from calendars or @group from groups in the config", sel)
+	}
+	return resolveCalendars(ctx, client, c.Groups, selectors)
+}
+
+// serve で予定を返すカレンダーID
+func (c *config) servedCalendarSet() map[string]bool {
+	served := map[string]bool{}
+	for _, id := range c.calendarIDs() {
+		served[id] = true
+	}
+	return served
+}
+
+// --calendar の指定をカレンダーIDにする
+//
+// "@work" は設定ファイルの groups に書いたカレンダー、"primary" と "@" を含むもの（カレンダーID）はそのまま、
+// それ以外はカレンダーの名前全体に一致する正規表現（"team-.*" など）として扱う。
+// 正規表現を指定したときだけカレンダーの一覧を取得する。
+func resolveCalendars(ctx context.Context, client calendarClient, groups map[string][]string, selectors []string) ([]string, error) {
+	var ids []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var patterns []string
+	for _, sel := range selectors {
+		members := []string{sel}
+		if name, ok := strings.CutPrefix(sel, "@"); ok {
+			if members, ok = groups[name]; !ok {
+				return nil, fmt.Errorf("Unknown calendar group %q", name)
+			}
+		}
+		for _, m := range members {
+			switch {
+			case strings.HasPrefix(m, "@"):
+				return nil, fmt.Errorf("Calendar group %q cannot contain another group (%s)", sel, m)
+			case m == "primary" || strings.Contains(m, "@"):
+				add(m)
+			default:
+				patterns = append(patterns, m)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return ids, nil
+	}
+
+	entries, err := client.ListCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("Invalid calendar pattern %q: %w", p, err)
+		}
+		matched := false
+		for _, e := range entries {
+			if !re.MatchString(e.Summary) && (e.SummaryOverride == "" || !re.MatchString(e.SummaryOverride)) {
+				continue
+			}
+			matched = true
+			if e.Primary {
+				add("primary")
+			} else {
+				add(e.Id)
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("No calendar matches %q", p)
+		}
+	}
+	return ids, nil
+}
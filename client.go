@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Calendar API のうちこのツールが使う操作
+//
+// 表示やフィルタのロジックは Google のサービスに直接依存せず、このインターフェイスを
+// 通して予定を取得する（テストやフィクスチャからの再生で差し替えられるようにするため）。
+type calendarClient interface {
+	// timeMin から timeMax までの予定を開始時刻順に取得する（繰り返し予定は展開済み）
+	ListEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*calendar.Events, error)
+	GetEvent(ctx context.Context, calendarID, eventID string) (*calendar.Event, error)
+	ListCalendars(ctx context.Context) ([]*calendar.CalendarListEntry, error)
+	Colors(ctx context.Context) (*calendar.Colors, error)
+	// 指定したカレンダー全体の予定が入っている時間帯を返す
+	FreeBusy(ctx context.Context, calendarIDs []string, timeMin, timeMax time.Time) ([]interval, error)
+}
+
+// 予定を変更する操作（カレンダーへの書き込みの権限が必要）
+type calendarWriter interface {
+	calendarClient
+	// 作成した予定（ID が割り当てられたもの）を返す
+	InsertEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error)
+	// event.Id の予定を event の内容で置き換える
+	UpdateEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error)
+	DeleteEvent(ctx context.Context, calendarID, eventID string) error
+}
+
+// 予定を取得したページから順に受け取る操作（長い期間を出力するときに、すべての予定をメモリに載せないため）
+//
+// fn にはすでに渡した予定があるため、途中で失敗しても取得し直さない。
+type eventStreamer interface {
+	StreamEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time, fn func(*calendar.Event) error) error
+}
+
+// Google Calendar API を呼び出す実装
+type googleClient struct {
+	srv *calendar.Service
+}
+
+func newGoogleClient(ctx context.Context, scope string) (*googleClient, error) {
+	srv, err := newCalendarService(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &googleClient{srv: srv}, nil
+}
+
+func (c *googleClient) ListEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*calendar.Events, error) {
+	var events *calendar.Events
+	err := withRetry(ctx, func() error {
+		events = nil
+		return c.srv.Events.List(calendarID).
+			ShowDeleted(false).
+			SingleEvents(true).
+			TimeMin(timeMin.Format(time.RFC3339)).
+			TimeMax(timeMax.Format(time.RFC3339)).
+			OrderBy("startTime").
+			Pages(ctx, func(page *calendar.Events) error {
+				if events == nil {
+					events = page
+					return nil
+				}
+				events.Items = append(events.Items, page.Items...)
+				return nil
+			})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve events: %w", err)
+	}
+	events.NextPageToken = ""
+	return events, nil
+}
+
+// ページごとに再試行するため、途中のページでレート制限やサーバーのエラーになっても最初から取得し直さない
+func (c *googleClient) StreamEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time, fn func(*calendar.Event) error) error {
+	call := c.srv.Events.List(calendarID).
+		ShowDeleted(false).
+		SingleEvents(true).
+		TimeMin(timeMin.Format(time.RFC3339)).
+		TimeMax(timeMax.Format(time.RFC3339)).
+		OrderBy("startTime").
+		Context(ctx)
+	err := func() error {
+		for pageToken := ""; ; {
+			var page *calendar.Events
+			if err := withReadRetry(ctx, func() error {
+				var err error
+				page, err = call.PageToken(pageToken).Do()
+				return err
+			}); err != nil {
+				return err
+			}
+			for _, item := range page.Items {
+				if err := fn(item); err != nil {
+					return err
+				}
+			}
+			if page.NextPageToken == "" {
+				return nil
+			}
+			pageToken = page.NextPageToken
+		}
+	}()
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve events: %w", err)
+	}
+	return nil
+}
+
+func (c *googleClient) GetEvent(ctx context.Context, calendarID, eventID string) (*calendar.Event, error) {
+	var event *calendar.Event
+	err := withRetry(ctx, func() error {
+		var err error
+		event, err = c.srv.Events.Get(calendarID, eventID).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve event: %w", err)
+	}
+	return event, nil
+}
+
+func (c *googleClient) ListCalendars(ctx context.Context) ([]*calendar.CalendarListEntry, error) {
+	var entries []*calendar.CalendarListEntry
+	err := c.srv.CalendarList.List().Pages(ctx, func(page *calendar.CalendarList) error {
+		entries = append(entries, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve calendar list: %w", err)
+	}
+	// シェル補完で --calendar の候補にする
+	if err := saveCalendarsCache(calendarsCacheFile, entries); err != nil {
+		slog.Warn("Unable to cache calendar list", "error", err)
+	}
+	return entries, nil
+}
+
+func (c *googleClient) Colors(ctx context.Context) (*calendar.Colors, error) {
+	colors, err := c.srv.Colors.Get().Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve colors: %w", err)
+	}
+	return colors, nil
+}
+
+func (c *googleClient) FreeBusy(ctx context.Context, calendarIDs []string, timeMin, timeMax time.Time) ([]interval, error) {
+	req := &calendar.FreeBusyRequest{
+		TimeMin: timeMin.Format(time.RFC3339),
+		TimeMax: timeMax.Format(time.RFC3339),
+	}
+	for _, id := range calendarIDs {
+		req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: id})
+	}
+
+	var resp *calendar.FreeBusyResponse
+	err := withRetry(ctx, func() error {
+		var err error
+		resp, err = c.srv.Freebusy.Query(req).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to query free/busy: %w", err)
+	}
+
+	var busy []interval
+	for id, cal := range resp.Calendars {
+		if len(cal.Errors) > 0 {
+			return nil, fmt.Errorf("Unable to query free/busy for %s: %s", id, cal.Errors[0].Reason)
+		}
+		for _, p := range cal.Busy {
+			start, err1 := time.Parse(time.RFC3339, p.Start)
+			end, err2 := time.Parse(time.RFC3339, p.End)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			busy = append(busy, interval{start: start.In(timeMin.Location()), end: end.In(timeMin.Location())})
+		}
+	}
+	return busy, nil
+}
+
+func (c *googleClient) InsertEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	var created *calendar.Event
+	err := withRetry(ctx, func() error {
+		var err error
+		created, err = c.srv.Events.Insert(calendarID, event).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, writeError("Unable to create event", err)
+	}
+	return created, nil
+}
+
+func (c *googleClient) UpdateEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	var updated *calendar.Event
+	err := withRetry(ctx, func() error {
+		var err error
+		updated, err = c.srv.Events.Update(calendarID, event.Id, event).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, writeError("Unable to update event", err)
+	}
+	return updated, nil
+}
+
+func (c *googleClient) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	err := withRetry(ctx, func() error {
+		return c.srv.Events.Delete(calendarID, eventID).Context(ctx).Do()
+	})
+	if err != nil {
+		return writeError("Unable to delete event", err)
+	}
+	return nil
+}
+
+// 書き込みのエラーを返す。読み取り専用のトークンの場合は認可し直す方法を示す
+func writeError(msg string, err error) error {
+	if isInsufficientScope(err) {
+		return fmt.Errorf("%s: %w: token.json does not allow changing events; remove it and run again to grant access", msg, errAuthRequired)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// レート制限に達した場合の最大再試行回数
+const maxRetries = 5
+
+// レート制限エラーの場合は待ち時間を倍にしながら call を再試行する
+func withRetry(ctx context.Context, call func() error) error {
+	return retry(ctx, call, isRateLimited)
+}
+
+// 読み取りの call を再試行する（何度呼んでも結果が変わらないため、サーバーの一時的なエラーでも再試行する）
+func withReadRetry(ctx context.Context, call func() error) error {
+	return retry(ctx, call, func(err error) bool { return isRateLimited(err) || isServerError(err) })
+}
+
+func retry(ctx context.Context, call func() error, retryable func(error) bool) error {
+	wait := time.Second
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || !retryable(err) || attempt >= maxRetries {
+			return err
+		}
+		apiUsage.retries.Add(1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// 500、502、503、504 のエラーか
+func isServerError(err error) bool {
+	var ge *googleapi.Error
+	if !errors.As(err, &ge) {
+		return false
+	}
+	switch ge.Code {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isRateLimited(err error) bool {
+	var ge *googleapi.Error
+	if !errors.As(err, &ge) {
+		return false
+	}
+	if ge.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if ge.Code == http.StatusForbidden {
+		for _, e := range ge.Errors {
+			if e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// --from-fixture で指定された保存済みのレスポンスを返す実装
+type fixtureClient struct {
+	events *calendar.Events
+}
+
+func newFixtureClient(path string) (*fixtureClient, error) {
+	events, err := loadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fixtureClient{events: events}, nil
+}
+
+// フィクスチャには1回分のレスポンスしかないため、期間とカレンダーIDは無視してそのまま返す
+func (c *fixtureClient) ListEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*calendar.Events, error) {
+	return c.events, nil
+}
+
+func (c *fixtureClient) StreamEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time, fn func(*calendar.Event) error) error {
+	for _, item := range c.events.Items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fixtureClient) GetEvent(ctx context.Context, calendarID, eventID string) (*calendar.Event, error) {
+	for _, item := range c.events.Items {
+		if item.Id == eventID {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("Unable to retrieve event: %s not found", eventID)
+}
+
+func (c *fixtureClient) ListCalendars(ctx context.Context) ([]*calendar.CalendarListEntry, error) {
+	return []*calendar.CalendarListEntry{{Id: "primary", Summary: c.events.Summary, Primary: true}}, nil
+}
+
+func (c *fixtureClient) Colors(ctx context.Context) (*calendar.Colors, error) {
+	return &calendar.Colors{}, nil
+}
+
+// フィクスチャへの書き込みはメモリ上の予定だけを変更する（ファイルには保存しない）
+func (c *fixtureClient) InsertEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	created := *event
+	if created.Id == "" {
+		created.Id = fmt.Sprintf("fixture%d", len(c.events.Items)+1)
+	}
+	c.events.Items = append(c.events.Items, &created)
+	return &created, nil
+}
+
+func (c *fixtureClient) UpdateEvent(ctx context.Context, calendarID string, event *calendar.Event) (*calendar.Event, error) {
+	for i, item := range c.events.Items {
+		if item.Id == event.Id {
+			updated := *event
+			c.events.Items[i] = &updated
+			return &updated, nil
+		}
+	}
+	return nil, fmt.Errorf("Unable to update event: %s not found", event.Id)
+}
+
+func (c *fixtureClient) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	for i, item := range c.events.Items {
+		if item.Id == eventID {
+			// 呼び出し側が持っている一覧を書き換えないよう、新しいスライスにする
+			c.events.Items = append(append([]*calendar.Event{}, c.events.Items[:i]...), c.events.Items[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unable to delete event: %s not found", eventID)
+}
+
+// フィクスチャの予定のうち「予定あり」として扱われるもの（時間指定の予定）から計算する
+func (c *fixtureClient) FreeBusy(ctx context.Context, calendarIDs []string, timeMin, timeMax time.Time) ([]interval, error) {
+	var busy []interval
+	for _, item := range c.events.Items {
+		if !isBusy(item, false) || item.Start.DateTime == "" {
+			continue
+		}
+		start, end := eventTimes(item)
+		if start.Before(timeMax) && end.After(timeMin) {
+			busy = append(busy, interval{start: start.In(timeMin.Location()), end: end.In(timeMin.Location())})
+		}
+	}
+	return busy, nil
+}
+
+// オプションに応じてクライアントを作成する
+func newClient(ctx context.Context, fromFixture string) (calendarClient, error) {
+	return newWriteClient(ctx, fromFixture, calendar.CalendarReadonlyScope)
+}
+
+// 予定を変更するコマンド用のクライアントを作成する
+//
+// scope は新しく認可するときに求める権限（通常は calendar.CalendarEventsScope）。
+func newWriteClient(ctx context.Context, fromFixture, scope string) (calendarWriter, error) {
+	if fromFixture != "" {
+		return newFixtureClient(fromFixture)
+	}
+	g, err := newGoogleClient(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if scope == calendar.CalendarReadonlyScope {
+		return g, nil
+	}
+	// 予定を変更できるクライアントは、変更を監査ログに記録する
+	return newAuditClient(g, auditLogFile), nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 呼び出しを記録し、決まった予定を返す calendarClient（テストで使わない操作は埋め込んだ nil の calendarClient のまま）
+type fakeClient struct {
+	calendarClient
+	events *calendar.Events
+	err    error
+	calls  []fakeCall
+}
+
+type fakeCall struct {
+	calendarID       string
+	timeMin, timeMax time.Time
+}
+
+func (c *fakeClient) ListEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*calendar.Events, error) {
+	c.calls = append(c.calls, fakeCall{calendarID, timeMin, timeMax})
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.events, nil
+}
+
+func (c *fakeClient) ListCalendars(ctx context.Context) ([]*calendar.CalendarListEntry, error) {
+	return []*calendar.CalendarListEntry{{Id: "primary", Primary: true}}, c.err
+}
+
+func (c *fakeClient) Colors(ctx context.Context) (*calendar.Colors, error) {
+	return &calendar.Colors{}, c.err
+}
+
+// testdata の予定を返す fakeClient
+func newTestClient(t *testing.T, path string) *fakeClient {
+	t.Helper()
+	events, err := loadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeClient{events: events}
+}
+
+func TestRunAgenda(t *testing.T) {
+	client := newTestClient(t, "testdata/edges.json")
+	chdirTemp(t)
+	day := date(2024, 6, 14)
+	var b strings.Builder
+	n, err := runAgenda(context.Background(), client, &b, []time.Time{day}, agendaOptions{lang: "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `2024-06-14 (金)の予定:
+【水色】カンファレンス (10:00-18:00)
+【トマト】早朝メンテナンス (00:00-01:00)
+【赤】リリース作業 (23:00-01:00)
+`
+	if got := b.String(); got != want {
+		t.Errorf("runAgenda output:\n%s\nwant:\n%s", got, want)
+	}
+	if n != 3 {
+		t.Errorf("runAgenda = %d events, want 3", n)
+	}
+	// 前日の0時から当日の終わりまでを primary から1回で取得する
+	begin, end := dayWindow(day)
+	if len(client.calls) != 1 || client.calls[0] != (fakeCall{"primary", begin, end}) {
+		t.Errorf("ListEvents calls = %v, want [{primary %v %v}]", client.calls, begin, end)
+	}
+}
+
+func TestRunAgendaNoEvents(t *testing.T) {
+	client := &fakeClient{events: &calendar.Events{}}
+	chdirTemp(t)
+	var b strings.Builder
+	n, err := runAgenda(context.Background(), client, &b, []time.Time{date(2024, 6, 14)}, agendaOptions{lang: "ja"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-06-14 (金)の予定:\n2024-06-14 (金)の予定はありません。\n"; n != 0 || b.String() != want {
+		t.Errorf("runAgenda = %d, %q, want 0, %q", n, b.String(), want)
+	}
+}
+
+func TestRunAgendaError(t *testing.T) {
+	want := errors.New("quota exceeded")
+	var b strings.Builder
+	if _, err := runAgenda(context.Background(), &fakeClient{err: want}, &b, []time.Time{date(2024, 6, 14)}, agendaOptions{lang: "ja"}); !errors.Is(err, want) {
+		t.Errorf("runAgenda error = %v, want %v", err, want)
+	}
+	if b.Len() != 0 {
+		t.Errorf("runAgenda printed %q on error", b.String())
+	}
+}
+
+// --save-fixture で保存したレスポンスを --from-fixture で再生すると同じ予定になり、メールアドレスは伏せてある
+func TestFixtureRoundTrip(t *testing.T) {
+	client := newTestClient(t, "testdata/edges.json")
+	client.events.Items[0].Attendees = []*calendar.EventAttendee{
+		{Email: "me@example.org", Self: true, ResponseStatus: "accepted"},
+		{Email: "alice@example.org"},
+	}
+	chdirTemp(t)
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	var saved strings.Builder
+	if _, err := runAgenda(context.Background(), client, &saved, []time.Time{date(2024, 6, 14)}, agendaOptions{lang: "ja", saveFixture: path}); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := newFixtureClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayed strings.Builder
+	if _, err := runAgenda(context.Background(), replay, &replayed, []time.Time{date(2024, 6, 14)}, agendaOptions{lang: "ja"}); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.String() != saved.String() {
+		t.Errorf("replayed output:\n%s\nwant:\n%s", replayed.String(), saved.String())
+	}
+	attendees := replay.events.Items[0].Attendees
+	if len(attendees) != 2 || attendees[0].Email != "me@example.com" || attendees[1].Email != "user1@example.com" {
+		t.Errorf("saved attendees were not sanitized: %+v %+v", attendees[0], attendees[1])
+	}
+}
+
+func TestFixtureClient(t *testing.T) {
+	client, err := newFixtureClient("testdata/edges.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// 期間によらず保存したレスポンスをそのまま返す
+	events, err := client.ListEvents(ctx, "other", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 5 {
+		t.Errorf("ListEvents returned %d events, want 5", len(events.Items))
+	}
+	calendars, err := client.ListCalendars(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendars) != 1 || calendars[0].Id != "primary" || !calendars[0].Primary || calendars[0].Summary != "test@example.com" {
+		t.Errorf("ListCalendars = %+v", calendars)
+	}
+	if _, err := newFixtureClient(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("newFixtureClient accepted a missing file")
+	}
+}
+
+// ほかのタイムゾーンで作成された予定は表示しているタイムゾーンの日付と時刻で表示し、元の時刻を併記する
+func TestRunAgendaOtherTimeZones(t *testing.T) {
+	client := &fakeClient{events: &calendar.Events{Items: []*calendar.Event{
+		{Id: "la", Summary: "US sync",
+			Start: &calendar.EventDateTime{DateTime: "2024-06-13T23:00:00-07:00", TimeZone: "America/Los_Angeles"},
+			End:   &calendar.EventDateTime{DateTime: "2024-06-14T00:00:00-07:00", TimeZone: "America/Los_Angeles"}},
+		{Id: "utc", Summary: "Global sync",
+			Start: &calendar.EventDateTime{DateTime: "2024-06-14T01:00:00Z"},
+			End:   &calendar.EventDateTime{DateTime: "2024-06-14T02:00:00Z"}},
+		{Id: "tokyo", Summary: "定例",
+			Start: &calendar.EventDateTime{DateTime: "2024-06-14T18:00:00+09:00", TimeZone: "Asia/Tokyo"},
+			End:   &calendar.EventDateTime{DateTime: "2024-06-14T18:30:00+09:00", TimeZone: "Asia/Tokyo"}},
+	}}}
+	chdirTemp(t)
+	tests := []struct {
+		day  time.Time
+		want string
+	}{
+		{
+			day:  date(2024, 6, 13),
+			want: "2024-06-13 (木)の予定:\n2024-06-13 (木)の予定はありません。\n",
+		},
+		{
+			day: date(2024, 6, 14),
+			want: `2024-06-14 (金)の予定:
+【デフォルト】Global sync (10:00-11:00)
+【デフォルト】US sync (15:00-16:00) ⚠ America/Los_Angeles では 23:00-00:00 PDT
+【デフォルト】定例 (18:00-18:30)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day.Format("2006-01-02"), func(t *testing.T) {
+			var b strings.Builder
+			if _, err := runAgenda(context.Background(), client, &b, []time.Time{tt.day}, agendaOptions{lang: "ja"}); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("runAgenda output:\n%s\nwant:\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 色のない予定に提案する色のルール（上から順に評価し、最初に一致したルールの色を使う）
+type colorRule struct {
+	// 件名に含まれる語（大文字と小文字は区別しない）
+	Keyword  string `json:"keyword,omitempty"`
+	Calendar string `json:"calendar,omitempty"`
+	// カラーID（"2"）か色名（"緑"）
+	Color string `json:"color"`
+}
+
+// 色名またはカラーIDをカラーIDにする
+func parseColor(s string) (string, error) {
+	if _, ok := colorNames[s]; ok {
+		return s, nil
+	}
+	if id := colorID(s); id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("Unknown color %q (use a color ID 1-11 or a name such as 緑)", s)
+}
+
+// 予定に提案する色と、一致したルール（一致しなければ nil）
+func suggestColor(rules []colorRule, calendarID string, item *calendar.Event) *colorRule {
+	for i, r := range rules {
+		if r.Calendar != "" && r.Calendar != calendarID {
+			continue
+		}
+		if r.Keyword != "" && !strings.Contains(strings.ToLower(item.Summary), strings.ToLower(r.Keyword)) {
+			continue
+		}
+		return &rules[i]
+	}
+	return nil
+}
+
+// 色のない予定と提案する色
+type colorSuggestion struct {
+	event agendaEvent
+	// 提案するカラーID（ルールに一致しなければ ""）
+	color   string
+	keyword string
+}
+
+// 期間内の色のない予定を一覧にし、設定ファイルの color_rules に基づく色を提案する（--apply で設定する）
+func setupAuditColors(fs *flag.FlagSet) func(args []string) error {
+	fromStr := fs.String("from", "", "First date (format: YYYY-MM-DD; default: today)")
+	toStr := fs.String("to", "", "Last date (format: YYYY-MM-DD; default: 6 days after --from)")
+	apply := fs.Bool("apply", false, "Set the suggested colors via the API")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation when the range exceeds max_range_days")
+	var calendars stringList
+	fs.Var(&calendars, "calendar", "Calendar ID, @group from the config, or a regular expression for calendar names (repeatable; default: calendars in the config)")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		from, err := parseDateFlag(*fromStr)
+		if err != nil {
+			return err
+		}
+		to := from.AddDate(0, 0, 6)
+		if *toStr != "" {
+			if to, err = parseDateFlag(*toStr); err != nil {
+				return err
+			}
+		}
+		if to.Before(from) {
+			return fmt.Errorf("--to (%s) is before --from (%s)", to.Format("2006-01-02"), from.Format("2006-01-02"))
+		}
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		rules := make([]colorRule, len(cfg.ColorRules))
+		for i, r := range cfg.ColorRules {
+			if r.Color, err = parseColor(r.Color); err != nil {
+				return fmt.Errorf("Invalid color_rules: %w", err)
+			}
+			rules[i] = r
+		}
+		if err := confirmRange(from, to, cfg.maxRangeDays(), *yes); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		scope := calendar.CalendarReadonlyScope
+		if *apply {
+			scope = calendar.CalendarEventsScope
+		}
+		client, err := newWriteClient(ctx, *fromFixture, scope)
+		if err != nil {
+			return err
+		}
+		ids, err := cfg.selectCalendars(ctx, client, calendars)
+		if err != nil {
+			return err
+		}
+		var suggestions []colorSuggestion
+		errs, err := eachCalendar(ids, func(id string) error {
+			events, err := client.ListEvents(ctx, id, from, to.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+			for _, e := range sortEvents(normalizeEvents(id, events.Items), "start", false) {
+				if e.item.ColorId != "" || e.item.Status == "cancelled" {
+					continue
+				}
+				s := colorSuggestion{event: e}
+				if r := suggestColor(rules, id, e.item); r != nil {
+					s.color, s.keyword = r.Color, r.Keyword
+				}
+				suggestions = append(suggestions, s)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		printCalendarErrors(os.Stdout, errs)
+		printColorSuggestions(os.Stdout, suggestions)
+		if !*apply {
+			return nil
+		}
+		for _, s := range suggestions {
+			if s.color == "" {
+				continue
+			}
+			updated := *s.event.item
+			updated.ColorId = s.color
+			if _, err := client.UpdateEvent(ctx, s.event.calendarID, &updated); err != nil {
+				return fmt.Errorf("Unable to set the color of %q: %w", updated.Summary, err)
+			}
+			fmt.Printf("色を設定しました: %s\n", formatEvent(&updated))
+		}
+		return nil
+	}
+}
+
+func printColorSuggestions(w io.Writer, suggestions []colorSuggestion) {
+	matched := 0
+	for _, s := range suggestions {
+		if s.color != "" {
+			matched++
+		}
+	}
+	fmt.Fprintf(w, "色のない予定: %d件（提案あり %d件）\n", len(suggestions), matched)
+	for _, s := range suggestions {
+		e := s.event
+		when := e.start.Format("2006-01-02 15:04")
+		if e.allDay {
+			when = e.start.Format("2006-01-02") + " 終日"
+		}
+		suggestion := "提案なし"
+		if s.color != "" {
+			suggestion = colorName(s.color)
+			if s.keyword != "" {
+				suggestion += fmt.Sprintf("（「%s」を含む）", s.keyword)
+			}
+		}
+		fmt.Fprintf(w, "%s %s → %s\n", when, e.item.Summary, suggestion)
+	}
+}
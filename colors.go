@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// Colors API から取得した色の一覧を保存するファイル
+const colorsCacheFile = "colors.json"
+
+// 色の一覧はほとんど変わらないため、取得し直すまでの期間を長めにとる
+const colorsCacheTTL = 7 * 24 * time.Hour
+
+// カラーIDごとの背景色（"#a4bdfc" の形式）
+type colorPalette struct {
+	Event     map[string]string `json:"event"`
+	Calendar  map[string]string `json:"calendar"`
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+// Colors API を使えないとき（フィクスチャや取得の失敗）に使う予定の色
+var defaultEventColors = map[string]string{
+	"1":  "#a4bdfc",
+	"2":  "#7ae7bf",
+	"3":  "#dbadff",
+	"4":  "#ff887c",
+	"5":  "#fbd75b",
+	"6":  "#ffb878",
+	"7":  "#46d6db",
+	"8":  "#e1e1e1",
+	"9":  "#5484ed",
+	"10": "#51b749",
+	"11": "#dc2127",
+}
+
+var (
+	paletteMu sync.Mutex
+	palette   *colorPalette
+)
+
+// 色の一覧を返す
+//
+// プロセス内とファイルにキャッシュし、古くなったときだけ Colors API から取得し直す。
+// 取得に失敗した場合は古いキャッシュか既定の色を使う（色は表示にしか使わないため、エラーにはしない）。
+func colors(ctx context.Context, client calendarClient) *colorPalette {
+	paletteMu.Lock()
+	defer paletteMu.Unlock()
+	if palette != nil && time.Since(palette.FetchedAt) < colorsCacheTTL {
+		return palette
+	}
+	if cached, err := loadPalette(colorsCacheFile); err == nil {
+		palette = cached
+		if time.Since(cached.FetchedAt) < colorsCacheTTL {
+			return palette
+		}
+	}
+
+	resp, err := client.Colors(ctx)
+	if err != nil || len(resp.Event) == 0 {
+		if err != nil {
+			slog.Warn("Using built-in colors", "error", err)
+		}
+		if palette == nil {
+			palette = &colorPalette{Event: defaultEventColors, FetchedAt: time.Now()}
+		}
+		return palette
+	}
+	palette = newPalette(resp)
+	if err := savePalette(colorsCacheFile, palette); err != nil {
+		slog.Warn("Unable to cache colors", "error", err)
+	}
+	return palette
+}
+
+func newPalette(resp *calendar.Colors) *colorPalette {
+	p := &colorPalette{Event: map[string]string{}, Calendar: map[string]string{}, FetchedAt: time.Now()}
+	for id, c := range resp.Event {
+		p.Event[id] = c.Background
+	}
+	for id, c := range resp.Calendar {
+		p.Calendar[id] = c.Background
+	}
+	return p
+}
+
+func loadPalette(path string) (*colorPalette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &colorPalette{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func savePalette(path string, p *colorPalette) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// カレンダーの背景色（個別に色を設定していなければカレンダーのカラーIDの色）
+func calendarColor(p *colorPalette, e *calendar.CalendarListEntry) string {
+	if e.BackgroundColor != "" {
+		return e.BackgroundColor
+	}
+	return p.Calendar[e.ColorId]
+}
+
+// 予定の背景色（カラーIDのない予定は空）
+func (p *colorPalette) eventColor(colorID string) string {
+	if colorID == "" {
+		return ""
+	}
+	if c, ok := p.Event[colorID]; ok {
+		return c
+	}
+	return defaultEventColors[colorID]
+}
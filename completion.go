@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 補完に使うカレンダーの一覧を保存するファイル（カレンダーの一覧を取得するたびに更新する）
+const calendarsCacheFile = "calendars.json"
+
+// 保存するカレンダー（主カレンダーの ID は "primary"）
+type cachedCalendar struct {
+	ID      string `json:"id"`
+	Summary string `json:"summary,omitempty"`
+}
+
+func saveCalendarsCache(path string, entries []*calendar.CalendarListEntry) error {
+	cached := make([]cachedCalendar, 0, len(entries))
+	for _, e := range entries {
+		id := e.Id
+		if e.Primary {
+			id = "primary"
+		}
+		cached = append(cached, cachedCalendar{ID: id, Summary: e.Summary})
+	}
+	b, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// --calendar の補完候補（保存したカレンダーID と、設定ファイルの groups の "@名前"）
+//
+// 補完はキーを押すたびに実行されるため、API は呼ばず保存した一覧だけを使う。
+func calendarCandidates(cachePath, configPath string) []string {
+	var words []string
+	if b, err := os.ReadFile(cachePath); err == nil {
+		var cached []cachedCalendar
+		if json.Unmarshal(b, &cached) == nil {
+			for _, c := range cached {
+				words = append(words, c.ID)
+			}
+		}
+	}
+	if cfg, err := loadConfig(configPath); err == nil {
+		for _, id := range cfg.Calendars {
+			words = append(words, id)
+		}
+		for name := range cfg.Groups {
+			words = append(words, "@"+name)
+		}
+	}
+	seen := map[string]bool{}
+	out := words[:0]
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// シェル補完スクリプトを生成する（calendars は補完スクリプトから呼ぶ、--calendar の候補の一覧）
+func setupCompletion(fs *flag.FlagSet) func(args []string) error {
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file (for calendar groups)")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: %s completion bash|zsh|fish|calendars", progName)
+		}
+		var err error
+		switch args[0] {
+		case "calendars":
+			for _, w := range calendarCandidates(calendarsCacheFile, *configPath) {
+				fmt.Println(w)
+			}
+			return nil
+		case "bash":
+			err = writeBashCompletion(os.Stdout)
+		case "zsh":
+			err = writeZshCompletion(os.Stdout)
+		case "fish":
+			err = writeFishCompletion(os.Stdout)
+		default:
+			return fmt.Errorf("Unsupported shell: %s", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to write completion script: %w", err)
+		}
+		return nil
+	}
+}
+
+// コマンドに登録されているフラグを取得する
+func commandFlags(c command) []*flag.Flag {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	c.setup(fs)
+	addGlobalFlags(fs)
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// 補完候補（位置引数と "--" 付きのフラグ名）をスペース区切りで返す
+func completionWords(c command) string {
+	words := append([]string{}, c.args...)
+	for _, f := range commandFlags(c) {
+		words = append(words, "--"+f.Name)
+	}
+	return strings.Join(words, " ")
+}
+
+// シェルの関数名として使える形にする
+func completionFuncName() string {
+	return "_" + strings.ReplaceAll(progName, "-", "_")
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := completionFuncName()
+	var names []string
+	for _, c := range commands() {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("\tlocal words cmd\n")
+	b.WriteString("\t(( COMP_CWORD > 1 )) && cmd=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("\tif [[ \"${COMP_WORDS[COMP_CWORD-1]}\" == --calendar ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W \"$(%s completion calendars 2>/dev/null)\" -- \"${cur}\"))\n", progName)
+	b.WriteString("\t\treturn\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase \"${cmd}\" in\n")
+	for _, c := range commands() {
+		fmt.Fprintf(&b, "\t%s) words=%q ;;\n", c.name, completionWords(c))
+	}
+	fmt.Fprintf(&b, "\t*) words=%q ;;\n", strings.TrimSpace(strings.Join(names, " ")+" "+completionWords(rootCommand)))
+	b.WriteString("\tesac\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"${words}\" -- \"${cur}\"))\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, progName)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := completionFuncName()
+
+	fmt.Fprintf(&b, "#compdef %s\n\n", progName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tif [[ ${words[CURRENT-1]} == --calendar ]]; then\n")
+	fmt.Fprintf(&b, "\t\tcompadd -- ${(f)\"$(%s completion calendars 2>/dev/null)\"}\n", progName)
+	b.WriteString("\t\treturn\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tlocal -a subcommands\n")
+	b.WriteString("\tsubcommands=(\n")
+	for _, c := range commands() {
+		fmt.Fprintf(&b, "\t\t%q\n", c.name+":"+c.summary)
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\tcase ${words[2]} in\n")
+	for _, c := range commands() {
+		fmt.Fprintf(&b, "\t%s) compadd -- %s ;;\n", c.name, completionWords(c))
+	}
+	b.WriteString("\t*)\n")
+	b.WriteString("\t\tif (( CURRENT == 2 )); then\n")
+	b.WriteString("\t\t\t_describe 'command' subcommands\n")
+	b.WriteString("\t\tfi\n")
+	if words := completionWords(rootCommand); words != "" {
+		fmt.Fprintf(&b, "\t\tcompadd -- %s\n", words)
+	}
+	b.WriteString("\t\t;;\n")
+	b.WriteString("\tesac\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, progName)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "complete -c %s -f\n", progName)
+	for _, c := range commands() {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -a %s -d %s\n",
+			progName, c.name, fishQuote(c.summary))
+	}
+	for _, f := range commandFlags(rootCommand) {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -l %s -d %s\n",
+			progName, f.Name, fishQuote(f.Usage))
+	}
+	for _, c := range commands() {
+		cond := fmt.Sprintf("'__fish_seen_subcommand_from %s'", c.name)
+		if len(c.args) > 0 {
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s\n",
+				progName, cond, fishQuote(strings.Join(c.args, " ")))
+		}
+		for _, f := range commandFlags(c) {
+			if f.Name == "calendar" {
+				fmt.Fprintf(&b, "complete -c %s -n %s -l %s -r -a '(%s completion calendars 2>/dev/null)' -d %s\n",
+					progName, cond, f.Name, progName, fishQuote(f.Usage))
+				continue
+			}
+			fmt.Fprintf(&b, "complete -c %s -n %s -l %s -d %s\n",
+				progName, cond, f.Name, fishQuote(f.Usage))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 設定ファイルの既定のパス
+const defaultConfigFile = "config.json"
+
+// config.json の内容
+type config struct {
+	// 予定を取得するカレンダーID（省略時は primary のみ）
+	Calendars []string `json:"calendars,omitempty"`
+	// --calendar @名前 で指定できるカレンダーの組（カレンダーIDか名前の正規表現）
+	Groups    map[string][]string `json:"groups,omitempty"`
+	Reminders reminderConfig      `json:"reminders,omitempty"`
+	Serve     serveConfig         `json:"serve,omitempty"`
+	// daemon で今日の予定が変わったときに通知する
+	Webhook webhookConfig `json:"webhook,omitempty"`
+	// daemon と remind で通知を送らない時間帯（夜間、土日、祝日）
+	Quiet quietConfig `json:"quiet,omitempty"`
+	// daemon で Home Assistant に今日の予定の状態を送る
+	MQTT mqttConfig `json:"mqtt,omitempty"`
+	// mirror コマンドと daemon で予定を「Busy」として複製するカレンダーの組
+	Mirror []mirrorConfig `json:"mirror,omitempty"`
+	// template apply で作成する1日分の予定（名前ごと）
+	Templates map[string][]eventSpec `json:"templates,omitempty"`
+	// ooo と team コマンドで予定を確認するメンバー
+	Team []namedCalendar `json:"team,omitempty"`
+	// rooms コマンドで空きを確認する会議室（リソースカレンダー）
+	Rooms []namedCalendar `json:"rooms,omitempty"`
+	// 予定を表示する前に適用する変換（上から順に、一致したものすべて）
+	Transforms []transformRule `json:"transforms,omitempty"`
+	// transforms の後に予定を変換する Starlark のスクリプト（transform(event) を定義する）
+	TransformScript string `json:"transform_script,omitempty"`
+	// 取得した日ごとの予定の記録
+	Archive archiveConfig `json:"archive,omitempty"`
+	// track-diff で予定と突き合わせるタイムトラッキングのサービス
+	Tracking trackingConfig `json:"tracking,omitempty"`
+	// 件名や説明欄の課題番号から作るリンク
+	Links linksConfig `json:"links,omitempty"`
+	// 場所への移動時間の見積もり
+	Maps mapsConfig `json:"maps,omitempty"`
+	// stats で集計する集中時間の目標
+	Focus focusConfig `json:"focus,omitempty"`
+	// 会議の費用の見積もり
+	Cost costConfig `json:"cost,omitempty"`
+	// --next-business-day で飛ばす祝日のカレンダー
+	Holidays holidaysConfig `json:"holidays,omitempty"`
+	// 予定の一覧の見た目（default、high-contrast、colorblind、monochrome、--theme で上書きできる）
+	Theme string `json:"theme,omitempty"`
+	// speak で予定を読み上げる方法
+	Speak speakConfig `json:"speak,omitempty"`
+	// audit-colors で色のない予定に提案する色
+	ColorRules []colorRule `json:"color_rules,omitempty"`
+	// batch と heatmap で確認なしで取得する期間の日数（省略時は 90、負の値は制限なし）
+	MaxRangeDays int `json:"max_range_days,omitempty"`
+}
+
+// 表示名を付けたカレンダー（チームのメンバーや会議室）
+type namedCalendar struct {
+	Name     string `json:"name"`
+	Calendar string `json:"calendar"`
+}
+
+// serve の設定
+type serveConfig struct {
+	// 読み取り専用の共有トークン
+	Tokens []string `json:"tokens,omitempty"`
+	// 接続を許可するアドレス（"192.168.1.0/24" や "10.0.0.5"）
+	Allow []string   `json:"allow,omitempty"`
+	Feed  feedConfig `json:"feed,omitempty"`
+	// 非公開の予定の件名や場所を伏せる（API、gRPC、フィードのすべて）
+	RedactPrivate bool `json:"redact_private,omitempty"`
+	// Slack のスラッシュコマンド（Signing Secret があるときだけ /slack/command を受け付ける）
+	Slack slackConfig `json:"slack,omitempty"`
+}
+
+// /feed.ics の設定
+type feedConfig struct {
+	// 今日から何日前・何日後までを含めるか（省略時は 7 日前から 60 日後まで）
+	PastDays   int `json:"past_days,omitempty"`
+	FutureDays int `json:"future_days,omitempty"`
+	// 予定の中身を伏せて「Busy」として出すカレンダー
+	Redact []string `json:"redact,omitempty"`
+}
+
+// リマインダーの設定
+type reminderConfig struct {
+	// どのルールにも一致しない予定の通知タイミング
+	Default []duration `json:"default,omitempty"`
+	// 色・カレンダー・キーワードごとの通知タイミング（先に一致したものを使う）
+	Rules []reminderRule `json:"rules,omitempty"`
+}
+
+// 指定した条件すべてに一致する予定には Leads の各時間前に通知する
+type reminderRule struct {
+	Color    string     `json:"color,omitempty"`
+	Calendar string     `json:"calendar,omitempty"`
+	Keyword  string     `json:"keyword,omitempty"`
+	Leads    []duration `json:"leads"`
+}
+
+// JSON では "10m" のような文字列で書く time.Duration
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// 設定ファイルを読み込む（ファイルがなければ空の設定を返す）
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config file: %w", err)
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("Unable to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// 予定を取得するカレンダーID
+func (c *config) calendarIDs() []string {
+	if len(c.Calendars) == 0 {
+		return []string{"primary"}
+	}
+	return c.Calendars
+}
+
+func durations(ds []duration) []time.Duration {
+	out := make([]time.Duration, len(ds))
+	for i, d := range ds {
+		out[i] = time.Duration(d)
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package main
+
+// 予定の重なりの種類
+type conflictKind int
+
+const (
+	noConflict conflictKind = iota
+	// 重なる予定のどちらかが「空き時間」（仮押さえなど）で、実際にはぶつからない
+	softConflict
+	// どちらも「予定あり」の予定が重なっている（ダブルブッキング）
+	hardConflict
+)
+
+// --markers で予定の後ろに付ける重なりの記号
+var conflictMarkers = map[conflictKind]string{
+	softConflict: "△重複（空き時間）",
+	hardConflict: "‼重複",
+}
+
+// 各予定がほかの予定と重なっているか（events と同じ順）
+//
+// 終日の予定、長さのない予定、キャンセルされた予定と欠席した予定は重なりとして数えない。
+// 一方が「空き時間」の重なりは、両方が「予定あり」の重なりと区別して softConflict にする。
+func eventConflicts(events []agendaEvent) []conflictKind {
+	kinds := make([]conflictKind, len(events))
+	counts := func(e agendaEvent) bool {
+		return !e.allDay && e.end.After(e.start) && e.item.Status != "cancelled" && attendanceStatus(e.item) != "declined"
+	}
+	for i, a := range events {
+		if !counts(a) {
+			continue
+		}
+		for j := i + 1; j < len(events); j++ {
+			b := events[j]
+			if !counts(b) || !a.start.Before(b.end) || !b.start.Before(a.end) {
+				continue
+			}
+			kind := softConflict
+			if isBusy(a.item, false) && isBusy(b.item, false) {
+				kind = hardConflict
+			}
+			kinds[i] = max(kinds[i], kind)
+			kinds[j] = max(kinds[j], kind)
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 重なり方のパターン（重なりの検出と、時間割の列の割り当てで共通に使う）
+//
+// 予定は "10:00-11:00" のように書き、2024-06-14 の予定とする。"終日" は終日の予定、
+// 後ろに " free" を付けると空き時間、" declined" を付けると欠席、" cancelled" を付けるとキャンセルした予定にする。
+// 時間割は欠席やキャンセルした予定も表示するため、列はそれらの予定にも割り当てる。
+var overlapPatterns = []struct {
+	name          string
+	events        []string
+	wantConflicts []conflictKind
+	// 時間割の列と、その予定を含むまとまりの列の数（終日の予定は時間割に並べないため -1）
+	wantColumns []int
+	wantWidths  []int
+}{
+	{
+		name:          "back to back",
+		events:        []string{"10:00-11:00", "11:00-12:00"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{0, 0},
+		wantWidths:    []int{1, 1},
+	},
+	{
+		name:          "partial overlap",
+		events:        []string{"10:00-11:00", "10:30-11:30"},
+		wantConflicts: []conflictKind{hardConflict, hardConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		name:          "full containment",
+		events:        []string{"10:00-12:00", "10:30-11:00"},
+		wantConflicts: []conflictKind{hardConflict, hardConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		// A と B、B と C は重なるが、A と C は重ならない
+		name:          "chain",
+		events:        []string{"10:00-11:00", "10:30-11:30", "11:00-12:00"},
+		wantConflicts: []conflictKind{hardConflict, hardConflict, hardConflict},
+		wantColumns:   []int{0, 1, 0},
+		wantWidths:    []int{2, 2, 2},
+	},
+	{
+		name:          "identical start and end",
+		events:        []string{"10:00-11:00", "10:00-11:00"},
+		wantConflicts: []conflictKind{hardConflict, hardConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		// 長さのない予定は、同じ時刻に始まる予定とも重ならない
+		name:          "zero length",
+		events:        []string{"10:00-10:00", "10:00-11:00"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{1, 0},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		name:          "zero length inside",
+		events:        []string{"10:00-11:00", "10:30-10:30"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		name:          "all day and timed",
+		events:        []string{"終日", "10:00-11:00"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{-1, 0},
+		wantWidths:    []int{-1, 1},
+	},
+	{
+		name:          "declined",
+		events:        []string{"10:00-11:00 declined", "10:30-11:30"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		name:          "cancelled",
+		events:        []string{"10:00-11:00", "10:30-11:30 cancelled"},
+		wantConflicts: []conflictKind{noConflict, noConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		// 仮押さえ（空き時間）との重なりはダブルブッキングと区別する
+		name:          "free hold",
+		events:        []string{"10:00-11:00 free", "10:30-11:30"},
+		wantConflicts: []conflictKind{softConflict, softConflict},
+		wantColumns:   []int{0, 1},
+		wantWidths:    []int{2, 2},
+	},
+	{
+		// 予定ありの予定同士の重なりがあれば、空き時間との重なりより優先する
+		name:          "hard wins over soft",
+		events:        []string{"10:00-12:00", "10:30-11:00", "11:00-11:30 free"},
+		wantConflicts: []conflictKind{hardConflict, hardConflict, softConflict},
+		wantColumns:   []int{0, 1, 1},
+		wantWidths:    []int{2, 2, 2},
+	},
+	{
+		name:          "declined inside a chain",
+		events:        []string{"10:00-11:00", "10:30-11:30 declined", "11:00-12:00"},
+		wantConflicts: []conflictKind{noConflict, noConflict, noConflict},
+		wantColumns:   []int{0, 1, 0},
+		wantWidths:    []int{2, 2, 2},
+	},
+}
+
+// overlapPatterns の書き方から予定を作る
+func patternEvents(t testing.TB, specs []string) []agendaEvent {
+	t.Helper()
+	events := make([]agendaEvent, len(specs))
+	for i, spec := range specs {
+		fields := strings.Fields(spec)
+		item := &calendar.Event{Id: fmt.Sprintf("e%d", i), Summary: spec}
+		if fields[0] == "終日" {
+			item.Start = &calendar.EventDateTime{Date: "2024-06-14"}
+			item.End = &calendar.EventDateTime{Date: "2024-06-15"}
+		} else {
+			start, end, _ := strings.Cut(fields[0], "-")
+			item.Start = &calendar.EventDateTime{DateTime: "2024-06-14T" + start + ":00+09:00"}
+			item.End = &calendar.EventDateTime{DateTime: "2024-06-14T" + end + ":00+09:00"}
+		}
+		for _, flag := range fields[1:] {
+			switch flag {
+			case "free":
+				item.Transparency = "transparent"
+			case "declined":
+				item.Attendees = []*calendar.EventAttendee{{Email: "me@example.com", Self: true, ResponseStatus: "declined"}}
+			case "cancelled":
+				item.Status = "cancelled"
+			default:
+				t.Fatalf("unknown flag %q in %q", flag, spec)
+			}
+		}
+		e, err := newAgendaEvent("primary", item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events[i] = e
+	}
+	return events
+}
+
+func TestEventConflicts(t *testing.T) {
+	for _, tt := range overlapPatterns {
+		t.Run(tt.name, func(t *testing.T) {
+			events := patternEvents(t, tt.events)
+			if got := eventConflicts(events); !slices.Equal(got, tt.wantConflicts) {
+				t.Errorf("eventConflicts(%v) = %v, want %v", tt.events, got, tt.wantConflicts)
+			}
+			// 並び順によらない
+			slices.Reverse(events)
+			got := eventConflicts(events)
+			slices.Reverse(got)
+			if !slices.Equal(got, tt.wantConflicts) {
+				t.Errorf("eventConflicts(reversed %v) = %v, want %v", tt.events, got, tt.wantConflicts)
+			}
+		})
+	}
+}
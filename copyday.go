@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// ある日の予定を別の日に作成し直す（決まった曜日の予定や授業の予定などの使い回し）
+func setupCopyDay(fs *flag.FlagSet) func(args []string) error {
+	fromStr := fs.String("from", "", "Day to copy events from (format: YYYY-MM-DD)")
+	toStr := fs.String("to", "", "Day to create the events on (format: YYYY-MM-DD)")
+	var onlyColors stringList
+	fs.Var(&onlyColors, "only-color", "Only copy events with this color ID (repeatable)")
+	calendarID := fs.String("calendar", "primary", "Calendar to copy events in")
+	dryRun := fs.Bool("dry-run", false, "Print the changes without applying them")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if *fromStr == "" || *toStr == "" {
+			return fmt.Errorf("Usage: %s copy-day --from YYYY-MM-DD --to YYYY-MM-DD [--only-color ID]", progName)
+		}
+		from, err := parseDateFlag(*fromStr)
+		if err != nil {
+			return err
+		}
+		to, err := parseDateFlag(*toStr)
+		if err != nil {
+			return err
+		}
+		if from.Equal(to) {
+			return fmt.Errorf("--from and --to must be different days")
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		res, err := copyDay(ctx, client, os.Stdout, *calendarID, from, to, onlyColors, *dryRun)
+		if err != nil {
+			return err
+		}
+		res.print(os.Stdout)
+		return nil
+	}
+}
+
+// from の日の予定を to の日に作成する
+//
+// 参加者は招待を送らないよう複製しない。同じ日からすでに複製した予定と、件名と開始時刻が同じ予定は作成しない。
+func copyDay(ctx context.Context, client calendarWriter, w io.Writer, calendarID string, from, to time.Time, onlyColors []string, dryRun bool) (applyResult, error) {
+	var res applyResult
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	shift := int(time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+
+	source, err := client.ListEvents(ctx, calendarID, fromDay, fromDay.AddDate(0, 0, 1))
+	if err != nil {
+		return res, err
+	}
+	target, err := client.ListEvents(ctx, calendarID, toDay, toDay.AddDate(0, 0, 1))
+	if err != nil {
+		return res, err
+	}
+
+	sourceKey := "copy/" + fromDay.Format("2006-01-02")
+	existing := map[string]bool{}
+	for _, item := range target.Items {
+		if item.Status == "cancelled" {
+			continue
+		}
+		if key := createdKey(item); key != "" {
+			existing[key] = true
+		}
+		existing[startKey(item)+" "+item.Summary] = true
+	}
+
+	colors := map[string]bool{}
+	for _, c := range onlyColors {
+		colors[c] = true
+	}
+	for _, item := range source.Items {
+		if !startsOn(item, fromDay) || item.Status == "cancelled" || attendanceStatus(item) == "declined" || mirrorKey(item) != "" {
+			continue
+		}
+		if len(colors) > 0 && !colors[item.ColorId] {
+			continue
+		}
+		copied := copiedEvent(item, shift, sourceKey+"/"+item.Id)
+		label := formatEvent(copied)
+		if existing[createdKey(copied)] || existing[startKey(copied)+" "+copied.Summary] {
+			fmt.Fprintf(w, "作成済み: %s\n", label)
+			res.existing++
+			continue
+		}
+		fmt.Fprintf(w, "作成: %s\n", label)
+		res.created++
+		if !dryRun {
+			if _, err := client.InsertEvent(ctx, calendarID, copied); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// day（0時の時刻）に始まる予定か
+func startsOn(item *calendar.Event, day time.Time) bool {
+	if item.Start.DateTime == "" {
+		return item.Start.Date == day.Format("2006-01-02")
+	}
+	start, _ := eventTimes(item)
+	start = start.In(day.Location())
+	return !start.Before(day) && start.Before(day.AddDate(0, 0, 1))
+}
+
+// 同じ予定か比べるための開始時刻（終日の予定は日付）
+func startKey(item *calendar.Event) string {
+	if item.Start.DateTime == "" {
+		return item.Start.Date
+	}
+	start, _ := eventTimes(item)
+	return start.In(time.Local).Format(time.RFC3339)
+}
+
+// shift 日後にずらした予定（参加者や会議の情報は含めない）
+func copiedEvent(item *calendar.Event, shift int, key string) *calendar.Event {
+	return &calendar.Event{
+		Summary:      item.Summary,
+		Description:  item.Description,
+		Location:     item.Location,
+		ColorId:      item.ColorId,
+		Transparency: item.Transparency,
+		Visibility:   item.Visibility,
+		Reminders:    item.Reminders,
+		Start:        shiftDateTime(item.Start, shift),
+		End:          shiftDateTime(item.End, shift),
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{createdProperty: key},
+		},
+	}
+}
+
+// 日時を shift 日後にする（時刻はその日のローカル時刻のまま）
+func shiftDateTime(dt *calendar.EventDateTime, shift int) *calendar.EventDateTime {
+	out := *dt
+	if dt.DateTime == "" {
+		d, err := time.Parse("2006-01-02", dt.Date)
+		if err == nil {
+			out.Date = d.AddDate(0, 0, shift).Format("2006-01-02")
+		}
+		return &out
+	}
+	t := parseDateTime(dt)
+	if loc, err := loadLocation(dt.TimeZone); dt.TimeZone != "" && err == nil {
+		t = t.In(loc)
+	} else {
+		t = t.In(time.Local)
+	}
+	out.DateTime = t.AddDate(0, 0, shift).Format(time.RFC3339)
+	return &out
+}
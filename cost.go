@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 会議の費用を見積もる設定
+type costConfig struct {
+	// 参加者1人あたりの時給
+	HourlyRate float64 `json:"hourly_rate,omitempty"`
+	// 参加者ごとの時給（メールアドレスごと、HourlyRate より優先する）
+	Rates map[string]float64 `json:"rates,omitempty"`
+	// 金額の単位（省略時は "円"）
+	Currency string `json:"currency,omitempty"`
+}
+
+func (c costConfig) enabled() bool {
+	return c.HourlyRate > 0 || len(c.Rates) > 0
+}
+
+// 予定の長さ × 参加者の時給の合計（2人以上の時間指定の予定だけ、欠席者と会議室は除く）
+func (c costConfig) eventCost(item *calendar.Event) (cost float64, attendees int) {
+	if !c.enabled() || item.Start.DateTime == "" {
+		return 0, 0
+	}
+	start, end := eventTimes(item)
+	hours := end.Sub(start).Hours()
+	var rate float64
+	for _, a := range item.Attendees {
+		if a.Resource || a.ResponseStatus == "declined" {
+			continue
+		}
+		attendees++
+		rate += c.rate(a.Email)
+	}
+	if attendees < 2 {
+		return 0, 0
+	}
+	return hours * rate, attendees
+}
+
+func (c costConfig) rate(email string) float64 {
+	for k, r := range c.Rates {
+		if strings.EqualFold(k, email) {
+			return r
+		}
+	}
+	return c.HourlyRate
+}
+
+// "約15,000円"
+func (c costConfig) format(cost float64) string {
+	currency := c.Currency
+	if currency == "" {
+		currency = "円"
+	}
+	s := strconv.FormatInt(int64(cost+0.5), 10)
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return "約" + b.String() + currency
+}
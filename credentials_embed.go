@@ -0,0 +1,14 @@
+//go:build embedcredentials
+
+package main
+
+import _ "embed"
+
+// -tags embedcredentials でビルドすると、ビルドしたディレクトリの credentials.json を埋め込む
+//
+//go:embed credentials.json
+var embeddedCredentialsFile string
+
+func init() {
+	embeddedCredentials = embeddedCredentialsFile
+}
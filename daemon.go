@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+type daemonOptions struct {
+	interval  time.Duration
+	calendars []string
+	// どのルールにも一致しない予定の通知タイミング
+	defaultLeads []time.Duration
+	rules        []reminderRule
+	// 1回だけ確認して終了する（cron から実行する場合）
+	once bool
+	// 今日の予定の変更を通知する Webhook（設定されていなければ nil）
+	webhook *webhook
+	// Home Assistant に状態を送る（設定されていなければ nil）
+	homeAssistant *homeAssistant
+	// 予定を複製するカレンダーの組
+	mirrors []mirrorConfig
+	// 今日の予定を記録するディレクトリ
+	archiveDir string
+	// 通知を送らない時間帯（設定されていなければ nil）
+	quiet *quietGuard
+}
+
+// 常駐して予定の開始前に通知する
+func setupDaemon(fs *flag.FlagSet) func(args []string) error {
+	return setupReminders(fs, false)
+}
+
+// 通知の時刻になった予定を通知する（--once で1回だけ確認して終了する）
+func setupRemind(fs *flag.FlagSet) func(args []string) error {
+	return setupReminders(fs, true)
+}
+
+func setupReminders(fs *flag.FlagSet, onceFlag bool) func(args []string) error {
+	lead := fs.Duration("lead", 10*time.Minute, "Notify this long before each event starts (overrides reminders.default in the config)")
+	interval := fs.Duration("interval", time.Minute, "Polling interval")
+	once := false
+	healthAddr := ""
+	if onceFlag {
+		fs.BoolVar(&once, "once", false, "Check once and exit (for running from cron)")
+	} else {
+		fs.StringVar(&healthAddr, "health-addr", "", "Serve /healthz on this address (e.g. :8081)")
+	}
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	statePath := fs.String("state", defaultStateFile, "File or redis:// URL to persist delivered reminders (share it between machines to avoid double notifications)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		// 起動時と SIGHUP を受けたときに設定ファイルから組み立てる
+		load := func() (daemonOptions, error) {
+			cfg, err := loadConfig(*configPath)
+			if err != nil {
+				return daemonOptions{}, err
+			}
+			opts := daemonOptions{
+				interval:     *interval,
+				calendars:    cfg.calendarIDs(),
+				defaultLeads: durations(cfg.Reminders.Default),
+				rules:        cfg.Reminders.Rules,
+				once:         once,
+			}
+			if len(opts.defaultLeads) == 0 || flagWasSet(fs, "lead") {
+				opts.defaultLeads = []time.Duration{*lead}
+			}
+			if opts.webhook, err = newWebhook(cfg.Webhook); err != nil {
+				return daemonOptions{}, err
+			}
+			if opts.quiet, err = newQuietGuard(cfg.Quiet, cfg.Holidays); err != nil {
+				return daemonOptions{}, err
+			}
+			opts.homeAssistant = newHomeAssistant(cfg.MQTT)
+			opts.mirrors = cfg.Mirror
+			opts.archiveDir = cfg.Archive.Dir
+			return opts, nil
+		}
+		opts, err := load()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// 複製する場合は新しく認可するときに書き込みの権限も求める
+		scope := calendar.CalendarReadonlyScope
+		if len(opts.mirrors) > 0 {
+			scope = calendar.CalendarEventsScope
+		}
+		client, err := newDaemonClient(ctx, *fromFixture, scope)
+		if err != nil {
+			return err
+		}
+		store, err := openStateStore(*statePath)
+		if err != nil {
+			return err
+		}
+		if opts.once {
+			return checkReminders(ctx, client, store, time.Now(), opts)
+		}
+
+		d := &daemon{client: client, store: store, load: load, opts: opts}
+		return d.run(ctx, healthAddr)
+	}
+}
+
+// 常駐モードの状態
+type daemon struct {
+	client calendarWriter
+	store  *stateStore
+	load   func() (daemonOptions, error)
+
+	// run のループからだけ使う
+	watcher    agendaWatcher
+	lastMirror time.Time
+
+	mu          sync.Mutex
+	opts        daemonOptions
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastErr     error
+}
+
+func (d *daemon) options() daemonOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts
+}
+
+func (d *daemon) run(ctx context.Context, healthAddr string) error {
+	opts := d.options()
+	slog.Info("Daemon started", "calendars", opts.calendars, "interval", opts.interval)
+	defer slog.Info("Daemon stopped")
+
+	if healthAddr != "" {
+		srv := &http.Server{Addr: healthAddr, Handler: d.healthHandler()}
+		ln, err := net.Listen("tcp", healthAddr)
+		if err != nil {
+			return err
+		}
+		go srv.Serve(ln)
+		defer srv.Close()
+		slog.Info("Health check listening", "addr", ln.Addr().String())
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// 最初の確認が終わってから systemd に起動完了を伝える
+	d.tick(ctx)
+	if err := sdNotify("READY=1"); err != nil {
+		slog.Warn("Unable to notify systemd", "error", err)
+	}
+	var watchdog <-chan time.Time
+	if iv := watchdogInterval(); iv > 0 {
+		t := time.NewTicker(iv / 2)
+		defer t.Stop()
+		watchdog = t.C
+	}
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			sdNotify("STOPPING=1")
+			return nil
+		case <-ticker.C:
+			d.tick(ctx)
+		case <-watchdog:
+			if d.healthy() {
+				sdNotify("WATCHDOG=1")
+			}
+		case <-hup:
+			d.reload(ticker)
+		}
+	}
+}
+
+// 1周期分の処理
+func (d *daemon) tick(ctx context.Context) {
+	now := time.Now()
+	opts := d.options()
+	err := checkReminders(ctx, d.client, d.store, now, opts)
+	if err != nil {
+		// 一時的なネットワークエラーなどで止まらないよう、ログだけ出して次の周期で再試行する
+		slog.Warn("Unable to check reminders", "error", err)
+	}
+	// 通知しない時間帯は確認しない（その間の変更は、通知できるようになってからまとめて送る）
+	if opts.webhook != nil && opts.quiet.allows(ctx, d.client, "webhook", now) {
+		if err := d.watcher.check(ctx, d.client, opts.webhook, opts.calendars, now); err != nil {
+			slog.Warn("Unable to check agenda changes", "error", err)
+		}
+	}
+	if opts.homeAssistant != nil {
+		if err := opts.homeAssistant.publish(ctx, d.client, opts.calendars, now); err != nil {
+			slog.Warn("Unable to update Home Assistant", "error", err)
+		}
+	}
+	if opts.archiveDir != "" {
+		if err := archiveToday(ctx, d.client, opts.archiveDir, now); err != nil {
+			slog.Warn("Unable to archive agenda", "error", err)
+		}
+	}
+	if len(opts.mirrors) > 0 && now.Sub(d.lastMirror) >= mirrorInterval {
+		// 複製のたびに別の操作として記録し、undo で直近の複製だけを取り消せるようにする
+		startAuditOperation(d.client)
+		if err := syncMirrors(ctx, d.client, opts.mirrors, now); err != nil {
+			slog.Warn("Unable to mirror events", "error", err)
+		} else {
+			d.lastMirror = now
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastRun = now
+	d.lastErr = err
+	if err == nil {
+		d.lastSuccess = now
+	}
+}
+
+// 設定ファイルを読み直す。通知済みの状態やキャッシュはそのまま引き継ぐ
+func (d *daemon) reload(ticker *time.Ticker) {
+	sdNotify("RELOADING=1")
+	defer sdNotify("READY=1")
+
+	opts, err := d.load()
+	if err != nil {
+		slog.Error("Unable to reload config; keeping the previous one", "error", err)
+		return
+	}
+	d.mu.Lock()
+	d.opts = opts
+	d.mu.Unlock()
+	ticker.Reset(opts.interval)
+	slog.Info("Config reloaded", "calendars", opts.calendars)
+}
+
+// 直近の周期が動いていて、成功してから時間が経ちすぎていないか
+func (d *daemon) healthy() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	limit := 3*d.opts.interval + globalOpts.httpTimeout
+	return time.Since(d.lastRun) < limit && time.Since(d.lastSuccess) < limit
+}
+
+func (d *daemon) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		body := map[string]any{
+			"last_run":     d.lastRun,
+			"last_success": d.lastSuccess,
+		}
+		if d.lastErr != nil {
+			body["last_error"] = d.lastErr.Error()
+		}
+		d.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if d.healthy() {
+			body["status"] = "ok"
+		} else {
+			body["status"] = "unhealthy"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+	return mux
+}
+
+// systemd（Type=notify）に状態を通知する。systemd 以外から起動された場合は何もしない
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		// 抽象名前空間のソケット
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// systemd の WatchdogSec に対応する間隔（設定されていなければ 0）
+func watchdogInterval() time.Duration {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	d, err := time.ParseDuration(usec + "us")
+	if err != nil {
+		slog.Warn("Invalid WATCHDOG_USEC", "value", usec, "error", err)
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 和暦の元号（新しい順、開始日は各元号の初日）
+var japaneseEras = []struct {
+	name  string
+	start time.Time
+}{
+	{"令和", time.Date(2019, 5, 1, 0, 0, 0, 0, time.Local)},
+	{"平成", time.Date(1989, 1, 8, 0, 0, 0, 0, time.Local)},
+	{"昭和", time.Date(1926, 12, 25, 0, 0, 0, 0, time.Local)},
+}
+
+// 日付の見出し（"2024-06-14 (金)"、era の場合は "令和6年6月14日 (金)"）
+func formatDateHeader(day time.Time, lang string, era bool) string {
+	wd := weekdayNames[lang][day.Weekday()]
+	if era {
+		if s, ok := formatJapaneseEra(day); ok {
+			return s + " (" + wd + ")"
+		}
+	}
+	return day.Format("2006-01-02") + " (" + wd + ")"
+}
+
+// 和暦の日付（"令和元年5月1日"）。昭和より前の日付は false
+func formatJapaneseEra(day time.Time) (string, bool) {
+	d := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	for _, e := range japaneseEras {
+		if d.Before(e.start) {
+			continue
+		}
+		year := d.Year() - e.start.Year() + 1
+		y := fmt.Sprintf("%d", year)
+		if year == 1 {
+			y = "元"
+		}
+		return fmt.Sprintf("%s%s年%d月%d日", e.name, y, d.Month(), d.Day()), true
+	}
+	return "", false
+}
+
+// ISO 8601 の週（"2024-W25"）の月曜日
+func parseISOWeek(s string) (time.Time, error) {
+	var year, week int
+	// Sscanf は符号や空白も受け付けるため、書き直して同じになるものだけを受け付ける
+	if _, err := fmt.Sscanf(s, "%4d-W%2d", &year, &week); err != nil || fmt.Sprintf("%04d-W%02d", year, week) != s {
+		return time.Time{}, fmt.Errorf("Invalid week %q. Please use YYYY-Www format (e.g. 2024-W25)", s)
+	}
+	// 1月4日を含む週がその年の第1週
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(week-1)*7)
+	if y, w := monday.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, fmt.Errorf("Invalid week %q: %d has no week %d", s, year, week)
+	}
+	return monday, nil
+}
+
+// 週の見出しに付ける ISO 8601 の週番号（ja は "2024年第25週"、en は "2024-W25"）
+func formatWeekNumber(day time.Time, lang string) string {
+	year, week := day.ISOWeek()
+	if lang == "ja" {
+		return fmt.Sprintf("%d年第%d週", year, week)
+	}
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// day の日付の0時から clock だけ経った時刻の、壁時計の上での時刻（"09:00" は夏時間の切り替わる日も 9:00）
+//
+// 0時に Add すると、切り替わる日は1時間ずれてしまう。夏時間が始まって飛ばされる時刻は、
+// 時計がその時刻を過ぎる瞬間（切り替わった時刻）にし、2回ある時刻は1回目にする。
+func atClock(day time.Time, clock time.Duration) time.Time {
+	h := int(clock / time.Hour)
+	m := int(clock % time.Hour / time.Minute)
+	t := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location())
+	// time.Date は飛ばされる時刻を切り替わる前の時刻にするため、切り替わった時刻に進める
+	if t.Hour() != h || t.Minute() != m {
+		if _, end := t.ZoneBounds(); end.After(t) {
+			return end
+		}
+	}
+	return t
+}
+
+// from から to までの期間に夏時間の切り替わりがあれば、その日と時差の変化を返す
+//
+// 表示しているタイムゾーンと、予定が作成されたタイムゾーンのすべてについて調べる。
+func dstTransitions(from, to time.Time, zones []*time.Location) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, loc := range zones {
+		if seen[loc.String()] {
+			continue
+		}
+		seen[loc.String()] = true
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			begin := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+			_, before := begin.Zone()
+			_, after := begin.AddDate(0, 0, 1).Zone()
+			if before == after {
+				continue
+			}
+			kind := "夏時間が始まります"
+			if after < before {
+				kind = "夏時間が終わります"
+			}
+			out = append(out, fmt.Sprintf("⚠ %s に %s の%s（時差 %s → %s）", begin.Format("2006-01-02"), loc,
+				kind, begin.Format("-07:00"), begin.AddDate(0, 0, 1).Format("-07:00")))
+		}
+	}
+	return out
+}
+
+// 読み込んだタイムゾーン（time.LoadLocation は呼ぶたびに tzdata を読み直すため、予定ごとに呼ばない）
+var locationCache sync.Map
+
+// 名前のタイムゾーンを返す（読み込めなかった結果も覚えておく）
+func loadLocation(name string) (*time.Location, error) {
+	type result struct {
+		loc *time.Location
+		err error
+	}
+	if r, ok := locationCache.Load(name); ok {
+		return r.(result).loc, r.(result).err
+	}
+	loc, err := time.LoadLocation(name)
+	locationCache.Store(name, result{loc, err})
+	return loc, err
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseISOWeek(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-W25", want: date(2024, 6, 17)},
+		{in: "2024-W01", want: date(2024, 1, 1)},
+		// 第1週が前の年の12月から始まる年
+		{in: "2025-W01", want: date(2024, 12, 30)},
+		{in: "2020-W53", want: date(2020, 12, 28)},
+		{in: "2021-W53", wantErr: true},
+		{in: "2024-W00", wantErr: true},
+		{in: "2024-W1", wantErr: true},
+		{in: "2024W25", wantErr: true},
+		{in: "2024-25", wantErr: true},
+		{in: "+024-W25", wantErr: true},
+		{in: " 024-W25", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseISOWeek(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseISOWeek(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseISOWeek(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			if got.Weekday() != time.Monday {
+				t.Errorf("parseISOWeek(%q) = %v, want a Monday", tt.in, got.Weekday())
+			}
+		})
+	}
+}
+
+func TestFormatWeekNumber(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		lang string
+		want string
+	}{
+		{date(2024, 6, 14), "ja", "2024年第24週"},
+		{date(2024, 6, 14), "en", "2024-W24"},
+		{date(2024, 12, 30), "en", "2025-W01"},
+		{date(2021, 1, 3), "ja", "2020年第53週"},
+	}
+	for _, tt := range tests {
+		if got := formatWeekNumber(tt.day, tt.lang); got != tt.want {
+			t.Errorf("formatWeekNumber(%v, %q) = %q, want %q", tt.day.Format("2006-01-02"), tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestAtClock(t *testing.T) {
+	tests := []struct {
+		day   time.Time
+		clock time.Duration
+		want  time.Time
+	}{
+		{date(2024, 6, 14), 0, time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local)},
+		{date(2024, 6, 14), 9*time.Hour + 30*time.Minute, time.Date(2024, 6, 14, 9, 30, 0, 0, time.Local)},
+		// 日付の途中の時刻でも、その日の0時から数える
+		{time.Date(2024, 6, 14, 18, 45, 0, 0, time.Local), 4 * time.Hour, time.Date(2024, 6, 14, 4, 0, 0, 0, time.Local)},
+		{date(2024, 6, 14), 23*time.Hour + 59*time.Minute, time.Date(2024, 6, 14, 23, 59, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		if got := atClock(tt.day, tt.clock); !got.Equal(tt.want) {
+			t.Errorf("atClock(%v, %v) = %v, want %v", tt.day, tt.clock, got, tt.want)
+		}
+	}
+}
+
+// テストの間だけ表示するタイムゾーンを変更する
+func setLocal(t testing.TB, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = saved })
+	return loc
+}
+
+// 夏時間が始まる日は23時間、終わる日は25時間になる
+func TestDayWindowDST(t *testing.T) {
+	setLocal(t, "America/New_York")
+	tests := []struct {
+		day  string
+		want time.Duration
+	}{
+		{"2024-03-10", 23 * time.Hour},
+		{"2024-11-03", 25 * time.Hour},
+		{"2024-06-14", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day, func(t *testing.T) {
+			setDayStart(t, 0)
+			day, err := parseDateFlag(tt.day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := atClock(day.AddDate(0, 0, 1), 0).Sub(atClock(day, 0)); got != tt.want {
+				t.Errorf("%s is %v long, want %v", tt.day, got, tt.want)
+			}
+			// 取得する期間は前日と当日の2日分
+			start, end := dayWindow(day)
+			if got, want := end.Add(time.Second).Sub(start), atClock(day, 0).Sub(atClock(day.AddDate(0, 0, -1), 0))+tt.want; got != want {
+				t.Errorf("dayWindow(%s) is %v long, want %v", tt.day, got, want)
+			}
+			if start.Hour() != 0 || end.Hour() != 23 || end.Minute() != 59 {
+				t.Errorf("dayWindow(%s) = %v, %v, want from 00:00 to 23:59:59", tt.day, start, end)
+			}
+		})
+	}
+}
+
+func TestAtClockDST(t *testing.T) {
+	loc := setLocal(t, "America/New_York")
+	tests := []struct {
+		name  string
+		day   time.Time
+		clock string
+		want  time.Time
+	}{
+		{"before the gap", date(2024, 3, 10), "01:30", time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC)},
+		// 飛ばされる 02:30 は、時計が 02:30 を過ぎる瞬間（03:00 EDT）にする
+		{"inside the gap", date(2024, 3, 10), "02:30", time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC)},
+		{"gap start", date(2024, 3, 10), "02:00", time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC)},
+		{"after the gap", date(2024, 3, 10), "09:00", time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC)},
+		// 2回ある 01:30 は1回目（EDT）にする
+		{"repeated hour", date(2024, 11, 3), "01:30", time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC)},
+		{"after fall back", date(2024, 11, 3), "09:00", time.Date(2024, 11, 3, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, err := parseClock(tt.clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := atClock(tt.day, clock)
+			if !got.Equal(tt.want) {
+				t.Errorf("atClock(%s, %s) = %v, want %v", tt.day.Format("2006-01-02"), tt.clock, got, tt.want.In(loc))
+			}
+		})
+	}
+}
+
+// --day-start が夏時間の始まりで飛ばされる時刻の場合も、1日の境目は1つに決まる
+func TestDayStartInSkippedHour(t *testing.T) {
+	setLocal(t, "America/New_York")
+	setDayStart(t, 2*time.Hour+30*time.Minute)
+	events := loadTestEvents(t, "testdata/dst.json")
+	tests := []struct {
+		day  time.Time
+		want []string
+		// その日の長さ
+		length time.Duration
+	}{
+		// 03-09 は 03-09 02:30 EST から 03-10 03:00 EDT（時計が 02:30 を過ぎる瞬間）まで
+		{date(2024, 3, 9), []string{"spring-gap"}, 23*time.Hour + 30*time.Minute},
+		{date(2024, 3, 10), []string{"spring-gap", "spring-morning"}, 23*time.Hour + 30*time.Minute},
+		{date(2024, 3, 11), []string{}, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day.Format("2006-01-02"), func(t *testing.T) {
+			if got := eventIDs(selectDay(events, tt.day)); !slices.Equal(got, tt.want) {
+				t.Errorf("selectDay = %v, want %v", got, tt.want)
+			}
+			if got := atClock(tt.day.AddDate(0, 0, 1), dayStart).Sub(atClock(tt.day, dayStart)); got != tt.length {
+				t.Errorf("the day is %v long, want %v", got, tt.length)
+			}
+		})
+	}
+}
+
+// 夏時間が終わる日は 01:00 の行が2回ある
+func TestTimelineFallBack(t *testing.T) {
+	setLocal(t, "America/New_York")
+	setDayStart(t, 0)
+	events := loadTestEvents(t, "testdata/dst.json")
+	day := date(2024, 11, 3)
+	var lines []string
+	for _, l := range timelineLines(day, selectDay(events, day), 40) {
+		lines = append(lines, strings.TrimRight(l, " "))
+	}
+	want := []string{
+		"01:00 │",
+		"      │ ┃1回目の1時半",
+		"01:00 │",
+		"      │ ┃2回目の1時半",
+		"02:00 │",
+	}
+	if !slices.Equal(lines[:len(want)], want) {
+		t.Errorf("timelineLines =\n%s\nwant prefix\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	// 25時間の日は 01:00 からの24時間分（48行）で、最後の行は 23:30
+	if last := lines[len(lines)-1]; !strings.HasPrefix(last, "      │ ┃夜の作業") || len(lines) != 48 {
+		t.Errorf("timelineLines has %d lines ending with %q", len(lines), last)
+	}
+}
+
+func TestDSTTransitions(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo := time.Local
+	tests := []struct {
+		name     string
+		from, to time.Time
+		zones    []*time.Location
+		want     []string
+	}{
+		{
+			name:  "spring forward",
+			from:  date(2024, 3, 4),
+			to:    date(2024, 3, 10),
+			zones: []*time.Location{tokyo, ny},
+			want:  []string{"⚠ 2024-03-10 に America/New_York の夏時間が始まります（時差 -05:00 → -04:00）"},
+		},
+		{
+			name:  "fall back",
+			from:  date(2024, 11, 1),
+			to:    date(2024, 11, 7),
+			zones: []*time.Location{ny, ny},
+			want:  []string{"⚠ 2024-11-03 に America/New_York の夏時間が終わります（時差 -04:00 → -05:00）"},
+		},
+		{
+			name:  "the day before",
+			from:  date(2024, 3, 3),
+			to:    date(2024, 3, 9),
+			zones: []*time.Location{ny},
+		},
+		{
+			name:  "several zones",
+			from:  date(2024, 3, 1),
+			to:    date(2024, 3, 31),
+			zones: []*time.Location{ny, london, tokyo},
+			want: []string{
+				"⚠ 2024-03-10 に America/New_York の夏時間が始まります（時差 -05:00 → -04:00）",
+				"⚠ 2024-03-31 に Europe/London の夏時間が始まります（時差 +00:00 → +01:00）",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dstTransitions(tt.from, tt.to, tt.zones); !slices.Equal(got, tt.want) {
+				t.Errorf("dstTransitions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 解釈できた週は、その週の月曜日の0時で、同じ書き方に戻る
+func FuzzParseISOWeek(f *testing.F) {
+	for _, s := range []string{"2024-W25", "2024-W01", "2025-W01", "2020-W53", "2021-W53", "2024-W00", "2024-W1", "2024W25", "0001-W01", "+024-W25", " 024-W25"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := parseISOWeek(s)
+		if err != nil {
+			return
+		}
+		if got.Weekday() != time.Monday || got.Hour() != 0 {
+			t.Errorf("parseISOWeek(%q) = %v, want a Monday at midnight", s, got)
+		}
+		if back := formatWeekNumber(got, "en"); back != s {
+			t.Errorf("parseISOWeek(%q) = %v, formats back as %q", s, got, back)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 日時をパースした予定（表示・絞り込み・並べ替えはこの値を使い、日時を何度もパースしない）
+type agendaEvent struct {
+	calendarID string
+	item       *calendar.Event
+	// 終日の予定は、開始日の0時から終了日（翌日）の0時まで（表示しているタイムゾーン）
+	start, end time.Time
+	allDay     bool
+}
+
+// API の予定の日時をパースする
+func newAgendaEvent(calendarID string, item *calendar.Event) (agendaEvent, error) {
+	start, allDay, err := parseEventTime(item.Start)
+	if err != nil {
+		return agendaEvent{}, fmt.Errorf("Unable to parse start of event %s: %w", item.Id, err)
+	}
+	end, _, err := parseEventTime(item.End)
+	if err != nil {
+		return agendaEvent{}, fmt.Errorf("Unable to parse end of event %s: %w", item.Id, err)
+	}
+	return agendaEvent{calendarID: calendarID, item: item, start: start, end: end, allDay: allDay}, nil
+}
+
+// 予定の日時をパースする（パースできない予定は警告を出して除く）
+func normalizeEvents(calendarID string, items []*calendar.Event) []agendaEvent {
+	out := make([]agendaEvent, 0, len(items))
+	for _, item := range items {
+		e, err := newAgendaEvent(calendarID, item)
+		if err != nil {
+			slog.Warn("Skipping event", "calendar", calendarID, "error", err)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// EventDateTime の日時をパースする（終日の予定の日付は表示しているタイムゾーンの0時）
+//
+// 別のタイムゾーンで作成された予定はその時差で返ってくるため、表示しているタイムゾーンの時刻にそろえる。
+func parseEventTime(dt *calendar.EventDateTime) (t time.Time, allDay bool, err error) {
+	switch {
+	case dt == nil:
+		return time.Time{}, false, fmt.Errorf("missing date")
+	case dt.DateTime != "":
+		t, err = time.Parse(time.RFC3339, dt.DateTime)
+		return t.In(time.Local), false, err
+	case dt.Date != "":
+		t, err = time.ParseInLocation("2006-01-02", dt.Date, time.Local)
+		return t, true, err
+	}
+	return time.Time{}, false, fmt.Errorf("missing date")
+}
+
+// item を置き換えた予定（変換や伏せ字の後も日時はそのまま使う）
+func (e agendaEvent) withItem(item *calendar.Event) agendaEvent {
+	e.item = item
+	return e
+}
+
+func (e agendaEvent) duration() time.Duration {
+	return e.end.Sub(e.start)
+}
+
+// 予定の一覧から API の予定を取り出す
+func eventItems(events []agendaEvent) []*calendar.Event {
+	out := make([]*calendar.Event, len(events))
+	for i, e := range events {
+		out[i] = e.item
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 最後に表示した予定の一覧を保存するファイル（show 3 のように番号で予定を指定するため）
+const lastListingFile = "last-listing.json"
+
+// 一覧に表示した予定
+type listedEvent struct {
+	Calendar string `json:"calendar"`
+	ID       string `json:"id"`
+	Summary  string `json:"summary"`
+}
+
+// 表示した順に予定を保存する
+func saveLastListing(path string, events []agendaEvent) error {
+	listed := make([]listedEvent, len(events))
+	for i, e := range events {
+		listed[i] = listedEvent{Calendar: e.calendarID, ID: e.item.Id, Summary: e.item.Summary}
+	}
+	b, err := json.MarshalIndent(listed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode listing: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("Unable to write listing: %w", err)
+	}
+	return nil
+}
+
+// 予定の指定（一覧の番号（1から）またはイベントID）をカレンダーとイベントIDにする
+func resolveListedEvent(path, ref, calendarID string) (string, string, error) {
+	n, err := strconv.Atoi(ref)
+	if err != nil {
+		return calendarID, ref, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("No listing to pick event %d from (run the agenda first)", n)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to read listing: %w", err)
+	}
+	var listed []listedEvent
+	if err := json.Unmarshal(b, &listed); err != nil {
+		return "", "", fmt.Errorf("Unable to parse listing: %w", err)
+	}
+	if n < 1 || n > len(listed) {
+		return "", "", fmt.Errorf("No event numbered %d in the last listing (%d events)", n, len(listed))
+	}
+	return listed[n-1].Calendar, listed[n-1].ID, nil
+}
+
+// 1件の予定の詳細（raw なら API の予定をそのまま JSON で）を出力する
+func showEvent(ctx context.Context, client calendarClient, w io.Writer, calendarID, eventID string, raw bool) error {
+	item, err := client.GetEvent(ctx, calendarID, eventID)
+	if err != nil {
+		return err
+	}
+	if raw {
+		b, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to encode event: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	printEventDetail(w, item)
+	return nil
+}
+
+// 予定の出席者・通知・繰り返し・会議の入口・添付ファイルを出力する
+func printEventDetail(w io.Writer, item *calendar.Event) {
+	fmt.Fprintln(w, formatEvent(item))
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "  %s: %s\n", label, value)
+		}
+	}
+	if e, err := newAgendaEvent("", item); err == nil && !e.allDay {
+		field("日時", e.start.Format("2006-01-02 15:04")+" - "+e.end.Format("2006-01-02 15:04 MST"))
+	} else if err == nil {
+		field("日付", item.Start.Date+" - "+e.end.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	field("ID", item.Id)
+	field("状態", item.Status)
+	field("場所", item.Location)
+	if item.Organizer != nil {
+		field("主催者", personLabel(item.Organizer.DisplayName, item.Organizer.Email))
+	}
+	if len(item.Attendees) > 0 {
+		fmt.Fprintf(w, "  出席者（%d人）:\n", len(item.Attendees))
+		for _, a := range item.Attendees {
+			line := personLabel(a.DisplayName, a.Email)
+			if label, ok := attendanceLabels[a.ResponseStatus]; ok {
+				line = label + " " + line
+			}
+			if a.Optional {
+				line += "（任意）"
+			}
+			if a.Resource {
+				line += "（設備）"
+			}
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	field("通知", formatReminders(item, nil))
+	if len(item.Recurrence) > 0 {
+		field("繰り返し", strings.Join(item.Recurrence, " "))
+	} else if item.RecurringEventId != "" {
+		field("繰り返し", "繰り返しの予定の1回（"+item.RecurringEventId+"）")
+	}
+	if item.ConferenceData != nil && len(item.ConferenceData.EntryPoints) > 0 {
+		fmt.Fprintln(w, "  会議:")
+		for _, ep := range item.ConferenceData.EntryPoints {
+			line := ep.EntryPointType + " " + ep.Uri
+			if ep.Label != "" && !strings.Contains(ep.Uri, ep.Label) {
+				line += "（" + ep.Label + "）"
+			}
+			for _, code := range []string{ep.Pin, ep.Passcode, ep.Password, ep.AccessCode, ep.MeetingCode} {
+				if code != "" {
+					line += " コード " + code
+					break
+				}
+			}
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	} else if item.HangoutLink != "" {
+		field("会議", item.HangoutLink)
+	}
+	if len(item.Attachments) > 0 {
+		fmt.Fprintln(w, "  添付ファイル:")
+		for _, a := range item.Attachments {
+			fmt.Fprintf(w, "    %s %s\n", a.Title, a.FileUrl)
+		}
+	}
+	field("リンク", item.HtmlLink)
+	if item.Description != "" {
+		fmt.Fprintln(w, "  説明:")
+		for _, l := range strings.Split(strings.TrimRight(item.Description, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", l)
+		}
+	}
+}
+
+// 「表示名 <メールアドレス>」（表示名がなければメールアドレスだけ）
+func personLabel(name, email string) string {
+	if name == "" {
+		return email
+	}
+	return name + " <" + email + ">"
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 通知の方法の表示名
+var reminderMethodNames = map[string]string{
+	"popup": "ポップアップ",
+	"email": "メール",
+}
+
+// 予定に設定されている通知を「ポップアップ 10分前、メール 1日前」の形式にする
+func formatReminders(item *calendar.Event, defaults []*calendar.EventReminder) string {
+	r := item.Reminders
+	if r == nil || r.UseDefault {
+		if len(defaults) == 0 {
+			return "カレンダーの既定"
+		}
+		return "カレンダーの既定（" + joinReminders(defaults) + "）"
+	}
+	if len(r.Overrides) == 0 {
+		return "なし"
+	}
+	return joinReminders(r.Overrides)
+}
+
+func joinReminders(reminders []*calendar.EventReminder) string {
+	parts := make([]string, len(reminders))
+	for i, r := range reminders {
+		method, ok := reminderMethodNames[r.Method]
+		if !ok {
+			method = r.Method
+		}
+		parts[i] = method + " " + formatLead(time.Duration(r.Minutes)*time.Minute)
+	}
+	return strings.Join(parts, "、")
+}
+
+// 通知のタイミングを「1時間30分前」の形式にする
+func formatLead(d time.Duration) string {
+	if d == 0 {
+		return "開始時"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	var b strings.Builder
+	if days > 0 {
+		fmt.Fprintf(&b, "%d日", days)
+	}
+	if hours > 0 {
+		fmt.Fprintf(&b, "%d時間", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%d分", minutes)
+	}
+	return b.String() + "前"
+}
+
+// 予定の通知を設定し直す
+func setupSetReminder(fs *flag.FlagSet) func(args []string) error {
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event")
+	var popups, emails stringList
+	fs.Var(&popups, "popup", "Show a popup this long before the event (e.g. 10m; repeatable)")
+	fs.Var(&emails, "email", "Send an email this long before the event (e.g. 24h; repeatable)")
+	useDefault := fs.Bool("default", false, "Use the calendar's default reminders")
+	none := fs.Bool("none", false, "Remove all reminders from the event")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: %s set-reminder <event-id> [--popup 10m] [--email 24h] [--default] [--none]", progName)
+		}
+		reminders, err := buildReminders(popups, emails, *useDefault, *none)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		event, err := client.GetEvent(ctx, *calendarID, args[0])
+		if err != nil {
+			return err
+		}
+		updated := *event
+		updated.Reminders = reminders
+		if _, err := client.UpdateEvent(ctx, *calendarID, &updated); err != nil {
+			return err
+		}
+		fmt.Printf("「%s」の通知: %s\n", event.Summary, formatReminders(&updated, nil))
+		return nil
+	}
+}
+
+// フラグから予定の通知の設定を組み立てる
+func buildReminders(popups, emails []string, useDefault, none bool) (*calendar.EventReminders, error) {
+	given := 0
+	if len(popups) > 0 || len(emails) > 0 {
+		given++
+	}
+	if useDefault {
+		given++
+	}
+	if none {
+		given++
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("Specify reminders with --popup/--email, or one of --default and --none")
+	}
+	if useDefault {
+		return &calendar.EventReminders{UseDefault: true}, nil
+	}
+
+	// 既定の通知を使わないことを明示して送る
+	r := &calendar.EventReminders{ForceSendFields: []string{"UseDefault", "Overrides"}, Overrides: []*calendar.EventReminder{}}
+	for _, m := range []struct {
+		method string
+		leads  []string
+	}{{"popup", popups}, {"email", emails}} {
+		method := m.method
+		for _, s := range m.leads {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("Invalid reminder %q: use a duration like 10m or 24h", s)
+			}
+			r.Overrides = append(r.Overrides, &calendar.EventReminder{Method: method, Minutes: int64(d / time.Minute), ForceSendFields: []string{"Minutes"}})
+		}
+	}
+	// Google カレンダーで設定できるのは5件まで
+	if len(r.Overrides) > 5 {
+		return nil, fmt.Errorf("At most 5 reminders can be set on an event")
+	}
+	return r, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 1件の予定を外部に転送できる形式で書き出す
+func setupExport(fs *flag.FlagSet) func(args []string) error {
+	ics := fs.Bool("ics", false, "Write the event as an iCalendar (.ics) file")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event (for an event ID)")
+	mailto := fs.String("mailto", "", "Also print a mailto: link to this address with the event details")
+	eml := fs.String("eml", "", "Also write an unsent email draft (.eml) with the .ics attached, to open in a mail client")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 2 || args[0] != "event" || !*ics {
+			return fmt.Errorf("Usage: %s export event <number|event-id> --ics [--output FILE] [--mailto ADDRESS] [--eml FILE]", progName)
+		}
+		calID, eventID, err := resolveListedEvent(lastListingFile, args[1], *calendarID)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		item, err := client.GetEvent(ctx, calID, eventID)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := writeICS(&buf, "", []icsEvent{{calendarID: calID, item: item}}); err != nil {
+			return err
+		}
+		if *output == "" {
+			os.Stdout.Write(buf.Bytes())
+		} else if err := os.WriteFile(*output, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("Unable to write %s: %w", *output, err)
+		}
+		if *eml != "" {
+			f, err := os.Create(*eml)
+			if err != nil {
+				return fmt.Errorf("Unable to write %s: %w", *eml, err)
+			}
+			err = writeEventDraft(f, *mailto, item, buf.Bytes(), time.Now())
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("Unable to write %s: %w", *eml, err)
+			}
+		}
+		if *mailto != "" {
+			fmt.Fprintln(os.Stderr, eventMailtoURL(*mailto, item))
+		}
+		return nil
+	}
+}
+
+// 転送するメールの件名と本文（件名・日時・場所・会議リンク）
+func eventMailText(item *calendar.Event) (string, string) {
+	lines := []string{item.Summary}
+	if e, err := newAgendaEvent("", item); err == nil && !e.allDay {
+		lines = append(lines, "日時: "+e.start.Format("2006-01-02 15:04")+" - "+e.end.Format("15:04 MST"))
+	} else if err == nil {
+		lines = append(lines, "日付: "+item.Start.Date)
+	}
+	if item.Location != "" {
+		lines = append(lines, "場所: "+item.Location)
+	}
+	if link := conferenceURL(item); link != "" {
+		lines = append(lines, "会議: "+link)
+	}
+	return item.Summary, strings.Join(lines, "\n") + "\n"
+}
+
+// 件名と本文を入れた mailto: リンク（mailto: では添付できないため、.ics は --output か --eml で添付する）
+func eventMailtoURL(to string, item *calendar.Event) string {
+	subject, body := eventMailText(item)
+	q := url.Values{"subject": {subject}, "body": {body}}
+	// mailto: の空白は + ではなく %20 にする
+	return "mailto:" + url.PathEscape(to) + "?" + strings.ReplaceAll(q.Encode(), "+", "%20")
+}
+
+// .ics を添付した未送信のメール（X-Unsent: 1 を付けると、多くのメールソフトが下書きとして開く）
+func writeEventDraft(w io.Writer, to string, item *calendar.Event, ics []byte, now time.Time) error {
+	subject, body := eventMailText(item)
+	boundary := fmt.Sprintf("%s-%d", progName, now.UnixNano())
+	var b strings.Builder
+	if to != "" {
+		b.WriteString("To: " + to + "\r\n")
+	}
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("X-Unsent: 1\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n\r\n")
+
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	b.WriteString(base64Lines([]byte(body)))
+
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/calendar; charset=utf-8; name=\"invite.ics\"\r\n")
+	b.WriteString("Content-Disposition: attachment; filename=\"invite.ics\"\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	b.WriteString(base64Lines(ics))
+	b.WriteString("--" + boundary + "--\r\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// 76 文字ごとに改行した base64
+func base64Lines(data []byte) string {
+	s := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(s) > 76 {
+		b.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	b.WriteString(s + "\r\n")
+	return b.String()
+}
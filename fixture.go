@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 保存済みの API レスポンス（Events.List の結果）を読み込む
+func loadFixture(path string) (*calendar.Events, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read fixture file: %w", err)
+	}
+	events := &calendar.Events{}
+	if err := json.Unmarshal(b, events); err != nil {
+		return nil, fmt.Errorf("Unable to parse fixture file: %w", err)
+	}
+	return events, nil
+}
+
+// API レスポンスから個人情報やリンクを取り除いてファイルに保存する
+func saveFixture(path string, events *calendar.Events) error {
+	b, err := json.MarshalIndent(sanitizeEvents(events), "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("Unable to write fixture file: %w", err)
+	}
+	return nil
+}
+
+// 表示に必要な項目（時刻、タイトル、色、ステータスなど）だけを残したコピーを返す
+func sanitizeEvents(events *calendar.Events) *calendar.Events {
+	out := &calendar.Events{
+		Kind:     events.Kind,
+		TimeZone: events.TimeZone,
+	}
+	// メールアドレスは連番のダミーに置き換える（同じ人は同じダミーになる）
+	emails := map[string]string{}
+	dummy := func(email string) string {
+		if email == "" {
+			return ""
+		}
+		if d, ok := emails[email]; ok {
+			return d
+		}
+		d := fmt.Sprintf("user%d@example.com", len(emails)+1)
+		emails[email] = d
+		return d
+	}
+	person := func(email string, self bool) (string, string) {
+		if self {
+			return "me@example.com", "Me"
+		}
+		d := dummy(email)
+		return d, ""
+	}
+
+	for _, item := range events.Items {
+		e := &calendar.Event{
+			Kind:                    item.Kind,
+			Id:                      item.Id,
+			Status:                  item.Status,
+			Summary:                 item.Summary,
+			Location:                item.Location,
+			ColorId:                 item.ColorId,
+			Start:                   item.Start,
+			End:                     item.End,
+			EndTimeUnspecified:      item.EndTimeUnspecified,
+			OriginalStartTime:       item.OriginalStartTime,
+			RecurringEventId:        item.RecurringEventId,
+			Transparency:            item.Transparency,
+			Visibility:              item.Visibility,
+			EventType:               item.EventType,
+			GuestsCanModify:         item.GuestsCanModify,
+			GuestsCanSeeOtherGuests: item.GuestsCanSeeOtherGuests,
+			Created:                 item.Created,
+			Updated:                 item.Updated,
+			Reminders:               item.Reminders,
+			// 勤務場所（自宅・オフィスの名前）
+			WorkingLocationProperties: item.WorkingLocationProperties,
+		}
+		if item.Organizer != nil {
+			email, name := person(item.Organizer.Email, item.Organizer.Self)
+			e.Organizer = &calendar.EventOrganizer{Email: email, DisplayName: name, Self: item.Organizer.Self}
+		}
+		for _, a := range item.Attendees {
+			email, name := person(a.Email, a.Self)
+			e.Attendees = append(e.Attendees, &calendar.EventAttendee{
+				Email:          email,
+				DisplayName:    name,
+				Self:           a.Self,
+				Organizer:      a.Organizer,
+				Resource:       a.Resource,
+				Optional:       a.Optional,
+				ResponseStatus: a.ResponseStatus,
+			})
+		}
+		out.Items = append(out.Items, e)
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// 複数回指定できる文字列フラグ
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// フラグがコマンドラインで明示的に指定されたかどうか
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
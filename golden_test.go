@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata/golden with the current output")
+
+// 出力の形式ごとのスナップショット（testdata/formats.json の 2024-06-14 の予定）
+//
+// 表示を変えたときは go test -run TestGolden -update で書き直し、差分を確かめてからコミットする。
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	day := date(2024, 6, 14)
+	text := func(args ...string) func(t *testing.T, w io.Writer) {
+		return func(t *testing.T, w io.Writer) {
+			opts := goldenOptions(t, args...)
+			opts.print(w, day, selectDay(loadTestEvents(t, "testdata/formats.json"), day))
+		}
+	}
+	tests := []struct {
+		name string
+		run  func(t *testing.T, w io.Writer)
+	}{
+		{"text", text()},
+		{"text-markers", text("--markers", "--responses", "--verbose")},
+		{"text-redact", text("--redact-private")},
+		{"text-en-era", text("--lang", "en", "--era")},
+		{"text-max-width", text("--max-width", "36")},
+		{"text-wrap", text("--max-width", "36", "--wrap")},
+		{"block", text("--format", "block")},
+		{"block-border", text("--format", "block", "--block-lines", "5", "--border", "--block-width", "30")},
+		{"timeline", text("--format", "timeline")},
+		{"timeline-narrow", text("--format", "timeline", "--max-width", "30")},
+		{"journal", text("--journal")},
+		{"week", func(t *testing.T, w io.Writer) {
+			opts := goldenOptions(t)
+			from, to := weekRange(day)
+			heading := from.Format("2006-01-02") + "〜" + to.Format("2006-01-02") + " (" + formatWeekNumber(from, opts.lang) + ")"
+			if err := runRangeView(ctx, goldenClient(t), w, heading, from, to, opts); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"json", func(t *testing.T, w io.Writer) {
+			if err := runBatchJSON(ctx, goldenClient(t), w, []time.Time{day}, goldenOptions(t)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"ndjson", func(t *testing.T, w io.Writer) {
+			days := daysBetween(day.AddDate(0, 0, -1), day.AddDate(0, 0, 1))
+			if err := runBatchNDJSON(ctx, goldenClient(t), w, days, goldenOptions(t)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"ics", func(t *testing.T, w io.Writer) {
+			days := []renderDay{{date: day, events: selectDay(loadTestEvents(t, "testdata/formats.json"), day)}}
+			if err := renderAgendas(w, days, "ics", goldenOptions(t)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"widget", func(t *testing.T, w io.Writer) {
+			agenda, err := fetchJSONAgenda(ctx, goldenClient(t), []string{"primary"}, day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(newWidget(agenda, time.Date(2024, 6, 14, 10, 45, 0, 0, time.Local))); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"standup-markdown", func(t *testing.T, w io.Writer) {
+			events := loadTestEvents(t, "testdata/formats.json")
+			past := standupEvents(selectDay(events, day.AddDate(0, 0, -1)), time.Time{})
+			printStandup(w, "markdown", standupLanguages["ja"], past, standupEvents(selectDay(events, day), time.Time{}))
+		}},
+		{"standup-slack", func(t *testing.T, w io.Writer) {
+			events := loadTestEvents(t, "testdata/formats.json")
+			past := standupEvents(selectDay(events, day.AddDate(0, 0, -1)), time.Time{})
+			printStandup(w, "slack", standupLanguages["en"], past, standupEvents(selectDay(events, day), time.Time{}))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.run(t, &buf)
+			checkGolden(t, filepath.Join("testdata", "golden", tt.name+".golden"), scrubGolden(buf.Bytes()))
+		})
+	}
+}
+
+// 表示に使うオプション（コマンドと同じフラグの既定値に args を適用する。色と端末のリンクは使わない）
+func goldenOptions(t testing.TB, args ...string) agendaOptions {
+	t.Helper()
+	fs := flag.NewFlagSet("golden", flag.ContinueOnError)
+	var opts agendaOptions
+	fs.StringVar(&opts.format, "format", "text", "")
+	fs.IntVar(&opts.block.width, "block-width", 40, "")
+	fs.IntVar(&opts.block.lines, "block-lines", 0, "")
+	fs.BoolVar(&opts.block.border, "border", false, "")
+	fs.BoolVar(&opts.journal, "journal", false, "")
+	addDisplayFlags(fs, &opts)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if opts.maxWidth, err = resolveMaxWidth(opts.maxWidthMode); err != nil {
+		t.Fatal(err)
+	}
+	if opts.theme, err = lookupTheme(opts.themeName); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.checkFormat(); err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+func goldenClient(t *testing.T) *fixtureClient {
+	t.Helper()
+	client, err := newFixtureClient("testdata/formats.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// 実行するたびに変わる値（ICS の DTSTAMP）
+var dtstampPattern = regexp.MustCompile(`(?m)^DTSTAMP:\d{8}T\d{6}Z\r$`)
+
+func scrubGolden(b []byte) []byte {
+	return dtstampPattern.ReplaceAll(b, []byte("DTSTAMP:20240101T000000Z\r"))
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -run TestGolden -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -run TestGolden -update and review the diff):\n--- got\n%s\n--- want\n%s",
+			path, strings.TrimRight(string(got), "\n"), strings.TrimRight(string(want), "\n"))
+	}
+}
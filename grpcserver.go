@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// proto/agenda/v1/agenda.proto と同じ内容の記述子
+//
+// protoc によるコード生成を使わず、この記述子と dynamicpb でメッセージを読み書きする。
+// .proto を変更した場合はここも合わせて変更すること。
+var agendaFile = mustAgendaFile()
+
+func mustAgendaFile() protoreflect.FileDescriptor {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	boolean := descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	int32t := descriptorpb.FieldDescriptorProto_TYPE_INT32
+	msg := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("agenda/v1/agenda.proto"),
+		Package: proto.String("gcaldailyagenda.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("GetAgendaRequest",
+				field("date", 1, str, ""),
+				repeated(field("calendar_ids", 2, str, ""))),
+			message("Event",
+				field("id", 1, str, ""),
+				field("calendar_id", 2, str, ""),
+				field("summary", 3, str, ""),
+				field("start", 4, str, ""),
+				field("end", 5, str, ""),
+				field("all_day", 6, boolean, ""),
+				field("color_id", 7, str, ""),
+				field("color_name", 8, str, ""),
+				field("location", 9, str, "")),
+			message("CalendarError",
+				field("calendar_id", 1, str, ""),
+				field("error", 2, str, "")),
+			message("Agenda",
+				field("date", 1, str, ""),
+				repeated(field("events", 2, msg, ".gcaldailyagenda.v1.Event")),
+				repeated(field("errors", 3, msg, ".gcaldailyagenda.v1.CalendarError"))),
+			message("ListCalendarsRequest"),
+			message("Calendar",
+				field("id", 1, str, ""),
+				field("summary", 2, str, ""),
+				field("color", 3, str, ""),
+				field("primary", 4, boolean, "")),
+			message("ListCalendarsResponse",
+				repeated(field("calendars", 1, msg, ".gcaldailyagenda.v1.Calendar"))),
+			message("FindFreeSlotsRequest",
+				field("days", 1, int32t, ""),
+				field("work_start", 2, str, ""),
+				field("work_end", 3, str, ""),
+				field("min_slot_minutes", 4, int32t, "")),
+			message("TimeSlot",
+				field("start", 1, str, ""),
+				field("end", 2, str, "")),
+			message("FindFreeSlotsResponse",
+				repeated(field("slots", 1, msg, ".gcaldailyagenda.v1.TimeSlot"))),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("AgendaService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetAgenda", "GetAgendaRequest", "Agenda"),
+				method("ListCalendars", "ListCalendarsRequest", "ListCalendarsResponse"),
+				method("FindFreeSlots", "FindFreeSlotsRequest", "FindFreeSlotsResponse"),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		panic(err)
+	}
+	return fd
+}
+
+func message(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonName(name)),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+func method(name, input, output string) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(".gcaldailyagenda.v1." + input),
+		OutputType: proto.String(".gcaldailyagenda.v1." + output),
+	}
+}
+
+// snake_case を lowerCamelCase にする（protoc と同じ JSON 名）
+func jsonName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func newMessage(name protoreflect.Name) *dynamicpb.Message {
+	return dynamicpb.NewMessage(agendaFile.Messages().ByName(name))
+}
+
+// メッセージの読み書きを短く書くためのラッパー
+type pbMessage struct {
+	*dynamicpb.Message
+}
+
+func (m pbMessage) fd(name string) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+}
+
+func (m pbMessage) str(name string) string {
+	return m.Get(m.fd(name)).String()
+}
+
+func (m pbMessage) int(name string) int {
+	return int(m.Get(m.fd(name)).Int())
+}
+
+func (m pbMessage) strs(name string) []string {
+	list := m.Get(m.fd(name)).List()
+	out := make([]string, list.Len())
+	for i := range out {
+		out[i] = list.Get(i).String()
+	}
+	return out
+}
+
+func (m pbMessage) set(name string, v any) pbMessage {
+	m.Set(m.fd(name), protoreflect.ValueOf(v))
+	return m
+}
+
+func (m pbMessage) add(name string, child *dynamicpb.Message) {
+	m.Mutable(m.fd(name)).List().Append(protoreflect.ValueOfMessage(child))
+}
+
+// AgendaService の実装
+type agendaGRPCServer struct {
+	client calendarClient
+	cfg    *config
+}
+
+// grpc.ServiceDesc の HandlerType に使うインターフェイス
+type agendaServiceServer interface {
+	getAgenda(ctx context.Context, req pbMessage) (*dynamicpb.Message, error)
+	listCalendars(ctx context.Context, req pbMessage) (*dynamicpb.Message, error)
+	findFreeSlots(ctx context.Context, req pbMessage) (*dynamicpb.Message, error)
+}
+
+func unaryMethod(name string, input protoreflect.Name, call func(s agendaServiceServer, ctx context.Context, req pbMessage) (*dynamicpb.Message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := newMessage(input)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(agendaServiceServer), ctx, pbMessage{req.(*dynamicpb.Message)})
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/gcaldailyagenda.v1.AgendaService/" + name}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+var agendaServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gcaldailyagenda.v1.AgendaService",
+	HandlerType: (*agendaServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("GetAgenda", "GetAgendaRequest", agendaServiceServer.getAgenda),
+		unaryMethod("ListCalendars", "ListCalendarsRequest", agendaServiceServer.listCalendars),
+		unaryMethod("FindFreeSlots", "FindFreeSlotsRequest", agendaServiceServer.findFreeSlots),
+	},
+	Metadata: "agenda/v1/agenda.proto",
+}
+
+func (s *agendaGRPCServer) getAgenda(ctx context.Context, req pbMessage) (*dynamicpb.Message, error) {
+	date, err := parseDateFlag(req.str("date"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ids, err := s.cfg.servedCalendars(ctx, s.client, req.strs("calendar_ids"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	agenda, err := fetchJSONAgenda(ctx, s.client, ids, date)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if s.cfg.Serve.RedactPrivate {
+		redactPrivateJSON(agenda.Events)
+	}
+
+	out := pbMessage{newMessage("Agenda")}.set("date", agenda.Date)
+	for _, e := range agenda.Events {
+		ev := pbMessage{newMessage("Event")}.
+			set("id", e.ID).
+			set("calendar_id", e.Calendar).
+			set("summary", e.Summary).
+			set("start", e.Start).
+			set("end", e.End).
+			set("all_day", e.AllDay).
+			set("color_id", e.ColorID).
+			set("color_name", e.ColorName).
+			set("location", e.Location)
+		out.add("events", ev.Message)
+	}
+	for _, e := range agenda.Errors {
+		ce := pbMessage{newMessage("CalendarError")}.
+			set("calendar_id", e.Calendar).
+			set("error", e.Error)
+		out.add("errors", ce.Message)
+	}
+	return out.Message, nil
+}
+
+func (s *agendaGRPCServer) listCalendars(ctx context.Context, req pbMessage) (*dynamicpb.Message, error) {
+	entries, err := s.client.ListCalendars(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	out := pbMessage{newMessage("ListCalendarsResponse")}
+	pal := colors(ctx, s.client)
+	served := s.cfg.servedCalendarSet()
+	for _, e := range entries {
+		// GetAgenda の calendar_ids にそのまま渡せるよう、serve http と同じく自分のカレンダーは "primary" で返す
+		id := e.Id
+		if e.Primary {
+			id = "primary"
+		}
+		if !served[id] && !served[e.Id] {
+			continue
+		}
+		c := pbMessage{newMessage("Calendar")}.
+			set("id", id).
+			set("summary", e.Summary).
+			set("color", calendarColor(pal, e)).
+			set("primary", e.Primary)
+		out.add("calendars", c.Message)
+	}
+	return out.Message, nil
+}
+
+// FindFreeSlots で指定できる営業日数の上限（1回のリクエストで FreeBusy の期間や応答が大きくなりすぎないように）
+const maxFreeSlotDays = 31
+
+func (s *agendaGRPCServer) findFreeSlots(ctx context.Context, req pbMessage) (*dynamicpb.Message, error) {
+	opts := availabilityOptions{days: 5, workStart: 9 * time.Hour, workEnd: 18 * time.Hour, minSlot: 30 * time.Minute}
+	if n := req.int("days"); n > maxFreeSlotDays {
+		return nil, status.Errorf(codes.InvalidArgument, "days must be at most %d", maxFreeSlotDays)
+	} else if n > 0 {
+		opts.days = n
+	}
+	if n := req.int("min_slot_minutes"); n > 0 {
+		opts.minSlot = time.Duration(n) * time.Minute
+	}
+	var err error
+	if v := req.str("work_start"); v != "" {
+		if opts.workStart, err = parseClock(v); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if v := req.str("work_end"); v != "" {
+		if opts.workEnd, err = parseClock(v); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if opts.workEnd <= opts.workStart {
+		return nil, status.Error(codes.InvalidArgument, "work_end must be after work_start")
+	}
+
+	now := time.Now()
+	days := businessDays(now, opts.days, opts.workEnd)
+	busy, err := s.client.FreeBusy(ctx, s.cfg.calendarIDs(), days[0], days[len(days)-1].AddDate(0, 0, 1))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	out := pbMessage{newMessage("FindFreeSlotsResponse")}
+	for _, day := range days {
+		for _, slot := range freeSlots(day, now, busy, opts) {
+			ts := pbMessage{newMessage("TimeSlot")}.
+				set("start", slot.start.Format(time.RFC3339)).
+				set("end", slot.end.Format(time.RFC3339))
+			out.add("slots", ts.Message)
+		}
+	}
+	return out.Message, nil
+}
+
+func grpcError(err error) error {
+	slog.Error("Request failed", "error", err)
+	return status.Error(codes.Unavailable, "Unable to retrieve events")
+}
+
+// serve http と同じトークン・許可リストで認可するインターセプター
+func (a *serveAuth) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorizeGRPC(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// ストリームの呼び出し（サーバーリフレクション）も同じように認可する
+func (a *serveAuth) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorizeGRPC(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (a *serveAuth) authorizeGRPC(ctx context.Context) error {
+	if p, ok := peer.FromContext(ctx); ok && !a.allowedAddr(p.Addr.String()) {
+		return status.Error(codes.PermissionDenied, "Forbidden")
+	}
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(v, "Bearer "); ok {
+				token = strings.TrimSpace(t)
+			}
+		}
+	}
+	if !a.validToken(token) {
+		return status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return nil
+}
+
+func runGRPCServer(ctx context.Context, addr string, client calendarClient, cfg *config, auth *serveAuth) error {
+	// サーバーリフレクションで .proto の内容を返せるように登録する
+	if _, err := protoregistry.GlobalFiles.FindFileByPath(agendaFile.Path()); err != nil {
+		if err := protoregistry.GlobalFiles.RegisterFile(agendaFile); err != nil {
+			return fmt.Errorf("Unable to register descriptor: %w", err)
+		}
+	}
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(auth.unaryInterceptor), grpc.StreamInterceptor(auth.streamInterceptor))
+	srv.RegisterService(&agendaServiceDesc, &agendaGRPCServer{client: client, cfg: cfg})
+	reflection.Register(srv)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	slog.Info("Serving gRPC", "addr", ln.Addr().String())
+	return srv.Serve(ln)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// 実際の API と同じく、自分のカレンダーをメールアドレスの ID で返す fakeClient
+type accountClient struct {
+	*fakeClient
+}
+
+func (c accountClient) ListCalendars(ctx context.Context) ([]*calendar.CalendarListEntry, error) {
+	return []*calendar.CalendarListEntry{
+		{Id: "me@example.com", Summary: "me@example.com", Primary: true},
+		{Id: "team@group.calendar.google.com", Summary: "チーム"},
+	}, nil
+}
+
+// ListCalendars で返した ID をそのまま GetAgenda に渡せる
+func TestGRPCListCalendarsIDsAreServed(t *testing.T) {
+	s := &agendaGRPCServer{client: accountClient{newTestClient(t, "testdata/edges.json")}, cfg: &config{}}
+	ctx := context.Background()
+
+	res, err := s.listCalendars(ctx, pbMessage{newMessage("ListCalendarsRequest")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := pbMessage{res}
+	list := out.Get(out.fd("calendars")).List()
+	if list.Len() != 1 {
+		t.Fatalf("ListCalendars returned %d calendars, want 1 (only the served primary calendar)", list.Len())
+	}
+	cal := pbMessage{list.Get(0).Message().(*dynamicpb.Message)}
+	if id := cal.str("id"); id != "primary" {
+		t.Errorf("ListCalendars id = %q, want %q", id, "primary")
+	}
+
+	req := pbMessage{newMessage("GetAgendaRequest")}.set("date", "2024-06-14")
+	req.Mutable(req.fd("calendar_ids")).List().Append(protoreflect.ValueOfString(cal.str("id")))
+	res, err = s.getAgenda(ctx, req)
+	if err != nil {
+		t.Fatalf("GetAgenda with calendar_ids = [%q]: %v", cal.str("id"), err)
+	}
+	out = pbMessage{res}
+	if n := out.Get(out.fd("events")).List().Len(); n != 3 {
+		t.Errorf("GetAgenda returned %d events, want 3", n)
+	}
+}
+
+func TestGRPCFindFreeSlotsDaysLimit(t *testing.T) {
+	s := &agendaGRPCServer{client: newTestClient(t, "testdata/edges.json"), cfg: &config{}}
+	req := pbMessage{newMessage("FindFreeSlotsRequest")}.set("days", int32(maxFreeSlotDays+1))
+	_, err := s.findFreeSlots(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("FindFreeSlots(days = %d) error = %v, want InvalidArgument", maxFreeSlotDays+1, err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 会議時間の段階（上限の時間と表示する記号）
+var heatmapLevels = []struct {
+	below time.Duration
+	cell  string
+}{
+	{time.Nanosecond, "·"},
+	{2 * time.Hour, "░"},
+	{4 * time.Hour, "▒"},
+	{6 * time.Hour, "▓"},
+}
+
+const heatmapMax = "█"
+
+// 期間中の日ごとの会議時間を、GitHub の草のような週ごとの表で表示する
+func setupHeatmap(fs *flag.FlagSet) func(args []string) error {
+	fromStr := fs.String("from", "", "First day (format: YYYY-MM-DD, default 12 weeks before --to)")
+	toStr := fs.String("to", "", "Last day (format: YYYY-MM-DD, default today)")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation when the range exceeds max_range_days")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file (for max_range_days)")
+	includeFree := fs.Bool("include-free", false, "Count events marked as free (transparent) as meetings")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		to, err := parseDateFlag(*toStr)
+		if err != nil {
+			return err
+		}
+		to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location())
+		from := to.AddDate(0, 0, -7*12+1)
+		if *fromStr != "" {
+			if from, err = parseDateFlag(*fromStr); err != nil {
+				return err
+			}
+			from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, to.Location())
+		}
+		if from.After(to) {
+			return fmt.Errorf("--from must not be after --to")
+		}
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if err := confirmRange(from, to, cfg.maxRangeDays(), *yes); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		// 期間全体の予定をメモリに載せないよう、rangeChunkDays 日ごとに取得して集計する
+		hours := map[string]time.Duration{}
+		for _, chunk := range chunkDays(daysBetween(from, to), rangeChunkDays) {
+			events, err := fetchRange(ctx, client, chunk[0], chunk[len(chunk)-1])
+			if err != nil {
+				return err
+			}
+			normalized := normalizeEvents("primary", events.Items)
+			for _, day := range chunk {
+				hours[day.Format("2006-01-02")] = busyTime(eventItems(selectDay(normalized, day)), day, *includeFree)
+			}
+		}
+		printHeatmap(os.Stdout, from, to, hours)
+		return nil
+	}
+}
+
+// その日（--day-start からの24時間）のうち予定が入っている時間
+//
+// 「空き時間」の予定（includeFree の場合は数える）、欠席の予定と終日の予定は数えず、重なっている予定は1回だけ数える。
+func busyTime(items []*calendar.Event, day time.Time, includeFree bool) time.Duration {
+	begin := atClock(day, dayStart)
+	end := atClock(day.AddDate(0, 0, 1), dayStart)
+	var ivs []interval
+	for _, item := range items {
+		if item.Start.DateTime == "" || !isBusy(item, includeFree) || attendanceStatus(item) == "declined" {
+			continue
+		}
+		start, stop := eventTimes(item)
+		if start.Before(begin) {
+			start = begin
+		}
+		if stop.After(end) {
+			stop = end
+		}
+		if stop.After(start) {
+			ivs = append(ivs, interval{start: start, end: stop})
+		}
+	}
+	var total time.Duration
+	for _, iv := range mergeIntervals(ivs) {
+		total += iv.duration()
+	}
+	return total
+}
+
+func heatmapCell(d time.Duration) string {
+	for _, l := range heatmapLevels {
+		if d < l.below {
+			return l.cell
+		}
+	}
+	return heatmapMax
+}
+
+// 行が曜日（月曜始まり）、列が週の表を出力する。期間外の日は空白にする
+func printHeatmap(w io.Writer, from, to time.Time, hours map[string]time.Duration) {
+	first, _ := weekRange(from)
+	weeks := int(to.Sub(first).Hours()/24)/7 + 1
+
+	fmt.Fprintf(w, "%s〜%sの会議時間:\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	// 月が変わる週の列に月を表示する（各列は2文字分）
+	var header strings.Builder
+	header.WriteString("   ")
+	width, month := 0, time.Month(0)
+	for c := 0; c < weeks; c++ {
+		m := first.AddDate(0, 0, 7*c+6).Month()
+		if m == month {
+			continue
+		}
+		month = m
+		if width > 2*c {
+			continue
+		}
+		header.WriteString(strings.Repeat(" ", 2*c-width))
+		label := fmt.Sprintf("%d月", m)
+		header.WriteString(label)
+		width = 2*c + displayWidth(label)
+	}
+	fmt.Fprintln(w, strings.TrimRight(header.String(), " "))
+
+	var total, businessTotal time.Duration
+	var businessDays int
+	for row := 0; row < 7; row++ {
+		wd := time.Weekday((row + 1) % 7)
+		line := weekdayNames["ja"][wd] + " "
+		for c := 0; c < weeks; c++ {
+			day := first.AddDate(0, 0, 7*c+row)
+			if day.Before(from) || day.After(to) {
+				line += "  "
+				continue
+			}
+			d := hours[day.Format("2006-01-02")]
+			total += d
+			if isBusinessDay(day) {
+				businessTotal += d
+				businessDays++
+			}
+			line += " " + heatmapCell(d)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintln(w, "凡例: · 0時間  ░ 2時間未満  ▒ 4時間未満  ▓ 6時間未満  █ 6時間以上")
+	avg := 0.0
+	if businessDays > 0 {
+		avg = businessTotal.Hours() / float64(businessDays)
+	}
+	fmt.Fprintf(w, "合計 %.1f時間（平日1日あたり %.1f時間）\n", total.Hours(), avg)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Google カレンダーの日本の祝日のカレンダー
+const defaultHolidayCalendar = "ja.japanese#holiday@group.v.calendar.google.com"
+
+// 祝日を取得するカレンダーの設定
+type holidaysConfig struct {
+	// 祝日のカレンダーID（省略時は日本の祝日）
+	Calendar string `json:"calendar,omitempty"`
+}
+
+func (h holidaysConfig) calendarID() string {
+	if h.Calendar == "" {
+		return defaultHolidayCalendar
+	}
+	return h.Calendar
+}
+
+// from から to までの祝日（YYYY-MM-DD から祝日の名前）
+//
+// 祝日のカレンダーの終日の予定を祝日として扱う。
+func fetchHolidays(ctx context.Context, client calendarClient, h holidaysConfig, from, to time.Time) (map[string]string, error) {
+	events, err := client.ListEvents(ctx, h.calendarID(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve holidays: %w", err)
+	}
+	holidays := map[string]string{}
+	for _, e := range normalizeEvents(h.calendarID(), events.Items) {
+		if !e.allDay || e.item.Status == "cancelled" {
+			continue
+		}
+		for day := e.start; day.Before(e.end); day = day.AddDate(0, 0, 1) {
+			holidays[day.Format("2006-01-02")] = e.item.Summary
+		}
+	}
+	return holidays, nil
+}
+
+// 祝日を探す期間（連休が続いてもこの日数のうちに営業日がある）
+const holidayLookahead = 31
+
+// date の翌日以降で最初の営業日（土日と祝日を除いた日）と、飛ばした休日を返す
+func nextBusinessDay(ctx context.Context, client calendarClient, h holidaysConfig, date time.Time) (time.Time, []string, error) {
+	first := time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, time.Local)
+	holidays, err := fetchHolidays(ctx, client, h, first, first.AddDate(0, 0, holidayLookahead))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	var skipped []string
+	for day := first; day.Before(first.AddDate(0, 0, holidayLookahead)); day = day.AddDate(0, 0, 1) {
+		name, holiday := holidays[day.Format("2006-01-02")]
+		if isBusinessDay(day) && !holiday {
+			return day, skipped, nil
+		}
+		label := fmt.Sprintf("%s(%s)", day.Format("01-02"), weekdayNames["ja"][day.Weekday()])
+		if holiday {
+			label += " " + name
+		}
+		skipped = append(skipped, label)
+	}
+	return time.Time{}, nil, fmt.Errorf("No business day within %d days after %s", holidayLookahead, date.Format("2006-01-02"))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"gcal-daily-agenda/mqtt"
+)
+
+// Home Assistant に MQTT で状態を送る設定
+type mqttConfig struct {
+	// "tcp://homeassistant.local:1883" など
+	Broker   string `json:"broker"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// 省略時は gcal-daily-agenda
+	ClientID string `json:"client_id,omitempty"`
+	// 状態を送るトピックの接頭辞（省略時は gcal-daily-agenda）
+	TopicPrefix string `json:"topic_prefix,omitempty"`
+	// Home Assistant の MQTT Discovery の接頭辞（省略時は homeassistant）
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+}
+
+// 予定がないときのセンサーの状態
+const haNoEvent = "なし"
+
+// Home Assistant のセンサー
+type haSensor struct {
+	object string
+	name   string
+	icon   string
+	// タイムスタンプのセンサー
+	timestamp bool
+	// 予定の詳細を属性として送る
+	attributes bool
+}
+
+var haSensors = []haSensor{
+	{object: "current_event", name: "現在の予定", icon: "mdi:calendar-clock", attributes: true},
+	{object: "next_event", name: "次の予定", icon: "mdi:calendar-arrow-right", attributes: true},
+	{object: "next_event_start", name: "次の予定の開始", timestamp: true},
+	{object: "today_count", name: "今日の予定の数", icon: "mdi:calendar-today"},
+}
+
+type homeAssistant struct {
+	cfg mqttConfig
+	// 最後に送った内容（変わったものだけを送る）
+	published map[string]string
+	// Discovery のメッセージを送ったか
+	discovered bool
+}
+
+// 設定から作成する（ブローカーが空なら nil）
+func newHomeAssistant(cfg mqttConfig) *homeAssistant {
+	if cfg.Broker == "" {
+		return nil
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = progName
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = progName
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = "homeassistant"
+	}
+	return &homeAssistant{cfg: cfg, published: map[string]string{}}
+}
+
+func (h *homeAssistant) topic(object string) string {
+	return h.cfg.TopicPrefix + "/" + object
+}
+
+// 今日の予定から現在の予定・次の予定・予定の数を求めて送る
+func (h *homeAssistant) publish(ctx context.Context, client calendarClient, calendarIDs []string, now time.Time) error {
+	agenda, err := fetchJSONAgenda(ctx, client, calendarIDs, now)
+	if err != nil {
+		return err
+	}
+	messages := h.messages(agenda, now)
+	if h.discovered && !h.changed(messages) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := mqtt.Dial(ctx, mqtt.Options{
+		Broker:   h.cfg.Broker,
+		ClientID: h.cfg.ClientID,
+		Username: h.cfg.Username,
+		Password: h.cfg.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to connect to MQTT broker: %w", err)
+	}
+	defer conn.Close()
+
+	if !h.discovered {
+		for _, s := range haSensors {
+			if err := conn.Publish(h.discoveryTopic(s), h.discoveryPayload(s), true); err != nil {
+				return fmt.Errorf("Unable to publish to MQTT broker: %w", err)
+			}
+		}
+		h.discovered = true
+	}
+	for topic, payload := range messages {
+		if old, ok := h.published[topic]; ok && old == payload {
+			continue
+		}
+		if err := conn.Publish(topic, []byte(payload), true); err != nil {
+			return fmt.Errorf("Unable to publish to MQTT broker: %w", err)
+		}
+		h.published[topic] = payload
+	}
+	slog.Debug("Published to MQTT", "broker", h.cfg.Broker, "events", len(agenda.Events))
+	return nil
+}
+
+func (h *homeAssistant) changed(messages map[string]string) bool {
+	for topic, payload := range messages {
+		if old, ok := h.published[topic]; !ok || old != payload {
+			return true
+		}
+	}
+	return false
+}
+
+// トピックごとの内容
+func (h *homeAssistant) messages(agenda *jsonAgenda, now time.Time) map[string]string {
+	var current, next *jsonEvent
+	for i, e := range agenda.Events {
+		if e.AllDay {
+			continue
+		}
+		start, _ := time.Parse(time.RFC3339, e.Start)
+		end, _ := time.Parse(time.RFC3339, e.End)
+		if current == nil && !now.Before(start) && now.Before(end) {
+			current = &agenda.Events[i]
+		}
+		if next == nil && start.After(now) {
+			next = &agenda.Events[i]
+		}
+	}
+
+	m := map[string]string{
+		h.topic("today_count"): strconv.Itoa(len(agenda.Events)),
+	}
+	for object, e := range map[string]*jsonEvent{"current_event": current, "next_event": next} {
+		state, attrs := haNoEvent, []byte("{}")
+		if e != nil {
+			state = e.Summary
+			attrs, _ = json.Marshal(e)
+		}
+		m[h.topic(object)] = state
+		m[h.topic(object)+"/attributes"] = string(attrs)
+	}
+	// タイムスタンプのセンサーは空にすると「不明」になる
+	m[h.topic("next_event_start")] = ""
+	if next != nil {
+		m[h.topic("next_event_start")] = next.Start
+	}
+	return m
+}
+
+func (h *homeAssistant) discoveryTopic(s haSensor) string {
+	return fmt.Sprintf("%s/sensor/%s/%s/config", h.cfg.DiscoveryPrefix, h.nodeID(), s.object)
+}
+
+// Discovery のノード ID（英数字・_・- のみ）
+func (h *homeAssistant) nodeID() string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, h.cfg.ClientID)
+}
+
+func (h *homeAssistant) discoveryPayload(s haSensor) []byte {
+	cfg := map[string]any{
+		"name":        s.name,
+		"unique_id":   h.nodeID() + "_" + s.object,
+		"state_topic": h.topic(s.object),
+		"device": map[string]any{
+			"identifiers": []string{h.nodeID()},
+			"name":        progName,
+		},
+	}
+	if s.icon != "" {
+		cfg["icon"] = s.icon
+	}
+	if s.timestamp {
+		cfg["device_class"] = "timestamp"
+	}
+	if s.attributes {
+		cfg["json_attributes_topic"] = h.topic(s.object) + "/attributes"
+	}
+	b, _ := json.Marshal(cfg)
+	return b
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"gcal-daily-agenda/schema"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// フックの実行を待つ最長時間
+const hookTimeout = 30 * time.Second
+
+// --exec-hook のプログラムに標準入力で渡す JSON
+//
+// 独自の出力形式や通知先はこの JSON を読むプログラムとして追加できる。
+type hookPayload struct {
+	SchemaVersion int `json:"schema_version"`
+	// "agenda"（予定を表示したとき）または "reminder"（通知したとき）
+	Type string `json:"type"`
+	Date string `json:"date,omitempty"`
+	// agenda: 表示した予定
+	Events []jsonEvent `json:"events,omitempty"`
+	// reminder: 通知した予定と開始までの分数
+	Event        *jsonEvent `json:"event,omitempty"`
+	MinutesUntil int        `json:"minutes_until,omitempty"`
+}
+
+// 表示した1日分の予定をフックに渡す
+func runAgendaHooks(ctx context.Context, targetDate time.Time, events []agendaEvent) {
+	if len(globalOpts.execHooks) == 0 {
+		return
+	}
+	p := hookPayload{SchemaVersion: schema.Version, Type: "agenda", Date: targetDate.Format("2006-01-02"), Events: []jsonEvent{}}
+	for _, e := range events {
+		p.Events = append(p.Events, newJSONEvent(e.calendarID, e.item))
+	}
+	runHooks(ctx, p)
+}
+
+// 通知した予定をフックに渡す
+func runReminderHooks(ctx context.Context, calendarID string, item *calendar.Event, until time.Duration) {
+	if len(globalOpts.execHooks) == 0 {
+		return
+	}
+	e := newJSONEvent(calendarID, item)
+	start, _ := eventTimes(item)
+	runHooks(ctx, hookPayload{
+		SchemaVersion: schema.Version,
+		Type:          "reminder",
+		Date:          start.Format("2006-01-02"),
+		Event:         &e,
+		MinutesUntil:  int(until.Round(time.Minute).Minutes()),
+	})
+}
+
+// 各フックを順に実行する
+//
+// フックの出力はそのまま標準出力・標準エラー出力に書き出す。フックが失敗しても予定の表示や通知は
+// 済んでいるため、警告だけにする。
+func runHooks(ctx context.Context, p hookPayload) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		slog.Warn("Unable to encode hook payload", "error", err)
+		return
+	}
+	for _, path := range globalOpts.execHooks {
+		ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Stdin = bytes.NewReader(b)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "GCAL_HOOK_TYPE="+p.Type)
+		if err := cmd.Run(); err != nil {
+			slog.Warn("Hook failed", "hook", path, "type", p.Type, "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// すべてのコマンドで使えるフラグの値
+var globalOpts struct {
+	httpTimeout time.Duration
+	logFormat   string
+	logLevel    string
+	dayStart    string
+	debugQuota  bool
+	execHooks   stringList
+	// token.json を暗号化して保存する
+	encryptToken bool
+}
+
+// 各コマンドのフラグに共通のフラグを追加する
+func addGlobalFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&globalOpts.httpTimeout, "http-timeout", 30*time.Second, "Timeout for each HTTP request to Google APIs (0 for no timeout)")
+	fs.StringVar(&globalOpts.logFormat, "log-format", "text", "Log format (text, json)")
+	fs.StringVar(&globalOpts.logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	fs.Var(&globalOpts.execHooks, "exec-hook", "Run this program with the agenda or reminder as JSON on stdin (repeatable)")
+	fs.StringVar(&globalOpts.dayStart, "day-start", "00:00", "Time when a day begins (HH:MM); events before it belong to the previous day")
+	fs.BoolVar(&globalOpts.encryptToken, "encrypt-token", false, "Encrypt token.json with a passphrase ($GCAL_TOKEN_PASSPHRASE or prompted) or an age identity ($GCAL_TOKEN_AGE_IDENTITY)")
+	fs.BoolVar(&globalOpts.debugQuota, "debug-quota", false, "Print API calls, bytes transferred, retries and cache hits at the end of the run")
+}
+
+var (
+	sharedTransportOnce sync.Once
+	sharedTransport     *http.Transport
+)
+
+// Google API への通信で共有するトランスポート
+//
+// 接続を使い回して TLS ハンドシェイクの回数を減らすため、プロセス内で1つだけ作る。
+func httpTransport() *http.Transport {
+	sharedTransportOnce.Do(func() {
+		sharedTransport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          20,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		}
+	})
+	return sharedTransport
+}
+
+// 認証前の通信（トークンの取得・更新）にも使う HTTP クライアント
+func baseHTTPClient() *http.Client {
+	return &http.Client{Transport: &loggingTransport{base: httpTransport()}, Timeout: globalOpts.httpTimeout}
+}
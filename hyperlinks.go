@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// --hyperlinks の値から、端末のハイパーリンク（OSC 8）を出力するかを決める
+//
+// auto の場合は標準出力が端末で、対応していることがわかっている端末のときだけ出力する。
+func useHyperlinks(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return isTerminal(os.Stdout) && terminalSupportsHyperlinks(), nil
+	}
+	return false, fmt.Errorf("Invalid --hyperlinks %q (expected auto, always or never)", mode)
+}
+
+// 環境変数から OSC 8 に対応した端末か判定する
+func terminalSupportsHyperlinks() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("WEZTERM_PANE") != "" {
+		return true
+	}
+	// GNOME 端末などの VTE は 0.50 以降で対応している
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	term := os.Getenv("TERM")
+	return strings.Contains(term, "kitty") || strings.Contains(term, "alacritty") || strings.HasPrefix(term, "foot")
+}
+
+// text を uri へのハイパーリンクにする（uri が空ならそのまま）
+func hyperlink(uri, text string) string {
+	if uri == "" {
+		return text
+	}
+	return "\x1b]8;;" + uri + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// 場所を Google マップで検索する URL
+func mapsURL(location string) string {
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(location)
+}
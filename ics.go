@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// ICS に書き出す予定
+type icsEvent struct {
+	calendarID string
+	item       *calendar.Event
+	// 予定の中身を伏せて「Busy」として出す
+	redact bool
+}
+
+// iCalendar（RFC 5545）形式で予定を書き出す
+func writeICS(w io.Writer, name string, events []icsEvent) error {
+	b := &icsBuilder{}
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//" + progName + "//EN")
+	b.line("CALSCALE:GREGORIAN")
+	if name != "" {
+		b.prop("X-WR-CALNAME", name)
+	}
+	for _, z := range icsTimeZones(events) {
+		writeVTimezone(b, z.name, z.loc, z.from)
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		writeICSEvent(b, e, stamp)
+	}
+	b.line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeICSEvent(b *icsBuilder, e icsEvent, stamp string) {
+	item := e.item
+	b.line("BEGIN:VEVENT")
+	uid := item.ICalUID
+	if uid == "" {
+		uid = item.Id + "@google.com"
+	}
+	if item.RecurringEventId != "" {
+		// 展開された繰り返しの各回は同じ iCalUID を持つため、回ごとに区別する
+		uid = item.Id + "-" + uid
+	}
+	b.prop("UID", uid)
+	b.line("DTSTAMP:" + stamp)
+	if item.Start.DateTime == "" {
+		b.line("DTSTART;VALUE=DATE:" + strings.ReplaceAll(item.Start.Date, "-", ""))
+		b.line("DTEND;VALUE=DATE:" + strings.ReplaceAll(item.End.Date, "-", ""))
+	} else if loc := recurrenceLocation(item); loc != nil {
+		// 繰り返しの予定は、夏時間をまたいでも同じ現地時刻に繰り返すよう、TZID を付けた現地時刻で書く
+		start, end := eventTimes(item)
+		b.line("DTSTART;TZID=" + item.Start.TimeZone + ":" + start.In(loc).Format("20060102T150405"))
+		b.line("DTEND;TZID=" + item.Start.TimeZone + ":" + end.In(loc).Format("20060102T150405"))
+	} else {
+		start, end := eventTimes(item)
+		b.line("DTSTART:" + start.UTC().Format("20060102T150405Z"))
+		b.line("DTEND:" + end.UTC().Format("20060102T150405Z"))
+	}
+	if e.redact {
+		b.prop("SUMMARY", "Busy")
+		b.line("CLASS:PRIVATE")
+	} else {
+		b.prop("SUMMARY", item.Summary)
+		if item.Location != "" {
+			b.prop("LOCATION", item.Location)
+		}
+		if item.Description != "" {
+			b.prop("DESCRIPTION", item.Description)
+		}
+		if item.HtmlLink != "" {
+			b.prop("URL", item.HtmlLink)
+		}
+	}
+	// 繰り返しの予定そのもの（export event で書き出す場合）は RRULE と EXDATE を書く
+	// （EXDATE と RDATE の日時は DTSTART と同じタイムゾーンの現地時刻にそろえる）
+	loc := recurrenceLocation(item)
+	for _, r := range item.Recurrence {
+		if loc != nil {
+			r = icsLocalDates(r, item.Start.TimeZone, loc)
+		}
+		b.line(r)
+	}
+	if item.Transparency == "transparent" {
+		b.line("TRANSP:TRANSPARENT")
+	} else {
+		b.line("TRANSP:OPAQUE")
+	}
+	if item.Status == "tentative" {
+		b.line("STATUS:TENTATIVE")
+	}
+	b.line("END:VEVENT")
+}
+
+// 繰り返しの予定の VTIMEZONE に書く期間（最初の回から）
+const icsTimeZoneYears = 20
+
+// 時間指定の繰り返しの予定を書き出すタイムゾーン（それ以外の予定は UTC で書くため nil）
+func recurrenceLocation(item *calendar.Event) *time.Location {
+	if len(item.Recurrence) == 0 || item.Start == nil || item.Start.DateTime == "" || item.Start.TimeZone == "" {
+		return nil
+	}
+	loc, err := loadLocation(item.Start.TimeZone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// VTIMEZONE を書くタイムゾーンと、そのタイムゾーンで最初の回の日時
+type icsTimeZone struct {
+	name string
+	loc  *time.Location
+	from time.Time
+}
+
+// 繰り返しの予定が使うタイムゾーンを、出てきた順に返す
+func icsTimeZones(events []icsEvent) []icsTimeZone {
+	var out []icsTimeZone
+	index := map[string]int{}
+	for _, e := range events {
+		loc := recurrenceLocation(e.item)
+		if loc == nil {
+			continue
+		}
+		name := e.item.Start.TimeZone
+		start := parseDateTime(e.item.Start)
+		if i, ok := index[name]; ok {
+			if start.Before(out[i].from) {
+				out[i].from = start
+			}
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, icsTimeZone{name: name, loc: loc, from: start})
+	}
+	return out
+}
+
+// from から icsTimeZoneYears 年分の、標準時と夏時間の切り替えを VTIMEZONE として書く
+func writeVTimezone(b *icsBuilder, name string, loc *time.Location, from time.Time) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + name)
+	t := from.In(loc)
+	abbr, offset := t.Zone()
+	writeObservance(b, t.IsDST(), abbr, offset, offset, t)
+	until := from.AddDate(icsTimeZoneYears, 0, 0)
+	for t = nextZoneChange(t, until); !t.IsZero(); t = nextZoneChange(t, until) {
+		prev := offset
+		abbr, offset = t.Zone()
+		writeObservance(b, t.IsDST(), abbr, prev, offset, t)
+	}
+	b.line("END:VTIMEZONE")
+}
+
+// t の後、until までに時差か略称が変わる最初の時刻（なければゼロ値）
+//
+// time.Time.ZoneBounds は tzdata の規則で計算する遠い将来の区間を正しく返さないことがあるため、
+// 1日ずつ調べ、変わった日の中で二分探索する。
+func nextZoneChange(t, until time.Time) time.Time {
+	abbr, offset := t.Zone()
+	changed := func(u time.Time) bool {
+		a, o := u.Zone()
+		return a != abbr || o != offset
+	}
+	for lo := t; lo.Before(until); lo = lo.Add(24 * time.Hour) {
+		hi := lo.Add(24 * time.Hour)
+		if !changed(hi) {
+			continue
+		}
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if changed(mid) {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+		return hi
+	}
+	return time.Time{}
+}
+
+// at から始まる標準時（STANDARD）か夏時間（DAYLIGHT）の区間
+func writeObservance(b *icsBuilder, dst bool, abbr string, from, to int, at time.Time) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	b.line("BEGIN:" + kind)
+	// 区間の開始は、切り替える前の時差での現地時刻で書く
+	b.line("DTSTART:" + at.In(time.FixedZone("", from)).Format("20060102T150405"))
+	b.line("TZOFFSETFROM:" + icsOffset(from))
+	b.line("TZOFFSETTO:" + icsOffset(to))
+	b.prop("TZNAME", abbr)
+	b.line("END:" + kind)
+}
+
+// UTC からの時差（"+0900"、"-0430"）
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// EXDATE と RDATE の日時を、tzid（loc）の現地時刻に書き換える
+//
+// Google カレンダーは UTC（"Z"）や別の TZID で書くことがあり、DTSTART と時刻の書き方がそろわないと
+// 除外した回が一致しない。日付だけの値や解釈できない値はそのまま返す。
+func icsLocalDates(line, tzid string, loc *time.Location) string {
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return line
+	}
+	prop, params, _ := strings.Cut(name, ";")
+	if prop != "EXDATE" && prop != "RDATE" {
+		return line
+	}
+	src := loc
+	for _, p := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(p, "=")
+		switch strings.ToUpper(k) {
+		case "VALUE":
+			if !strings.EqualFold(v, "DATE-TIME") {
+				return line
+			}
+		case "TZID":
+			l, err := loadLocation(strings.Trim(v, `"`))
+			if err != nil {
+				return line
+			}
+			src = l
+		}
+	}
+	values := strings.Split(value, ",")
+	for i, v := range values {
+		var t time.Time
+		var err error
+		if strings.HasSuffix(v, "Z") {
+			t, err = time.Parse("20060102T150405Z", v)
+		} else {
+			t, err = time.ParseInLocation("20060102T150405", v, src)
+		}
+		if err != nil {
+			return line
+		}
+		values[i] = t.In(loc).Format("20060102T150405")
+	}
+	return prop + ";TZID=" + tzid + ":" + strings.Join(values, ",")
+}
+
+// 行末を CRLF にし、75 オクテットを超える行を折り返す
+type icsBuilder struct {
+	strings.Builder
+}
+
+func (b *icsBuilder) prop(name, value string) {
+	b.line(name + ":" + icsEscape(value))
+}
+
+func (b *icsBuilder) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		// マルチバイト文字の途中で切らない
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// 文字の先頭が見つからない（UTF-8 として正しくない）場合はそのまま切る
+			cut = limit
+		}
+		b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// 折り返した行は先頭の空白も 1 オクテットに数える
+		limit = 74
+	}
+	b.WriteString(s + "\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+func icsEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\r", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// フィード用に、カレンダーごとに取得した予定を1つにまとめる
+//
+// redactPrivate の場合は、伏せないカレンダーでも非公開の予定は「Busy」にする。
+func collectICSEvents(items map[string][]*calendar.Event, order []string, redacted map[string]bool, redactPrivate bool) []icsEvent {
+	var out []icsEvent
+	seen := map[string]int{}
+	for _, id := range order {
+		for _, item := range items[id] {
+			if item.Status == "cancelled" {
+				continue
+			}
+			redact := redacted[id] || redactPrivate && isPrivate(item)
+			// 同じ予定が複数のカレンダーに入っている場合は1件にまとめ、どれかで伏せるなら伏せる
+			if key := duplicateKey(item); key != "" {
+				if i, ok := seen[key]; ok {
+					out[i].redact = out[i].redact || redact
+					continue
+				}
+				seen[key] = len(out)
+			}
+			out = append(out, icsEvent{calendarID: id, item: item, redact: redact})
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestWriteICS(t *testing.T) {
+	events := loadTestEvents(t, "testdata/day.json")
+	byID := map[string]*calendar.Event{}
+	for _, e := range events {
+		byID[e.item.Id] = e.item
+	}
+	tests := []struct {
+		name    string
+		event   icsEvent
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "timed",
+			event: icsEvent{calendarID: "primary", item: byID["standup"]},
+			want:  []string{"UID:standup@google.com", "DTSTART:20240614T010000Z", "DTEND:20240614T013000Z", "SUMMARY:定例", "TRANSP:OPAQUE"},
+		},
+		{
+			name:  "all day",
+			event: icsEvent{calendarID: "primary", item: byID["training"]},
+			want:  []string{"DTSTART;VALUE=DATE:20240614", "DTEND;VALUE=DATE:20240615", "SUMMARY:研修"},
+		},
+		{
+			name:    "redacted",
+			event:   icsEvent{calendarID: "primary", item: byID["wip"], redact: true},
+			want:    []string{"SUMMARY:Busy", "CLASS:PRIVATE"},
+			notWant: []string{"設計レビュー", "DESCRIPTION"},
+		},
+		{
+			name: "escaped text",
+			event: icsEvent{calendarID: "primary", item: &calendar.Event{
+				Id:          "esc",
+				Summary:     `打ち合わせ, 定例; A\B`,
+				Description: "1行目\n2行目",
+				Start:       &calendar.EventDateTime{DateTime: "2024-06-14T10:00:00+09:00"},
+				End:         &calendar.EventDateTime{DateTime: "2024-06-14T11:00:00+09:00"},
+			}},
+			want: []string{`SUMMARY:打ち合わせ\, 定例\; A\\B`, `DESCRIPTION:1行目\n2行目`},
+		},
+		{
+			// 展開された繰り返しの各回は UID を区別し、空き時間の予定は TRANSPARENT にする
+			name: "recurring instance",
+			event: icsEvent{calendarID: "primary", item: &calendar.Event{
+				Id:               "weekly_20240614T010000Z",
+				ICalUID:          "weekly@google.com",
+				RecurringEventId: "weekly",
+				Summary:          "週次",
+				Transparency:     "transparent",
+				Status:           "tentative",
+				Start:            &calendar.EventDateTime{DateTime: "2024-06-14T10:00:00+09:00"},
+				End:              &calendar.EventDateTime{DateTime: "2024-06-14T11:00:00+09:00"},
+			}},
+			want: []string{"UID:weekly_20240614T010000Z-weekly@google.com", "TRANSP:TRANSPARENT", "STATUS:TENTATIVE"},
+		},
+		{
+			// 繰り返しの予定そのものは現地時刻と VTIMEZONE で書き、EXDATE を同じタイムゾーンにそろえる
+			name: "recurring series",
+			event: icsEvent{calendarID: "primary", item: &calendar.Event{
+				Id:      "series",
+				Summary: "Weekly sync",
+				Start:   &calendar.EventDateTime{DateTime: "2024-03-04T10:00:00-05:00", TimeZone: "America/New_York"},
+				End:     &calendar.EventDateTime{DateTime: "2024-03-04T10:30:00-05:00", TimeZone: "America/New_York"},
+				Recurrence: []string{
+					"RRULE:FREQ=WEEKLY;UNTIL=20241231T235959Z",
+					"EXDATE;TZID=America/New_York:20240311T100000",
+					"EXDATE:20240318T140000Z",
+					"EXDATE;TZID=Asia/Tokyo:20240326T000000",
+				},
+			}},
+			want: []string{
+				"BEGIN:VTIMEZONE", "TZID:America/New_York",
+				"BEGIN:DAYLIGHT\r\nDTSTART:20240310T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nEND:DAYLIGHT",
+				"BEGIN:STANDARD\r\nDTSTART:20241103T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nEND:STANDARD",
+				"DTSTART;TZID=America/New_York:20240304T100000",
+				"DTEND;TZID=America/New_York:20240304T103000",
+				"RRULE:FREQ=WEEKLY;UNTIL=20241231T235959Z",
+				"EXDATE;TZID=America/New_York:20240311T100000",
+				"EXDATE;TZID=America/New_York:20240318T100000",
+				"EXDATE;TZID=America/New_York:20240325T110000",
+			},
+			notWant: []string{"DTSTART:20240304T150000Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeICS(&b, "テスト", []icsEvent{tt.event}); err != nil {
+				t.Fatal(err)
+			}
+			out := b.String()
+			if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n") {
+				t.Errorf("not a calendar:\n%s", out)
+			}
+			unfolded := strings.ReplaceAll(out, "\r\n ", "")
+			for _, w := range append(tt.want, "X-WR-CALNAME:テスト") {
+				if !strings.Contains(unfolded, w+"\r\n") {
+					t.Errorf("missing %q in:\n%s", w, out)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(unfolded, w) {
+					t.Errorf("unexpected %q in:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+// 75 オクテットを超える行は、マルチバイト文字の途中で切らずに折り返す
+func TestICSLineFolding(t *testing.T) {
+	summary := strings.Repeat("長い件名の予定", 10)
+	var b icsBuilder
+	b.prop("SUMMARY", summary)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("line was not folded: %q", b.String())
+	}
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("line %d has %d octets", i, len(l))
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a character: %q", i, l)
+		}
+		if i > 0 && !strings.HasPrefix(l, " ") {
+			t.Errorf("continuation line %d does not start with a space: %q", i, l)
+		}
+	}
+	if got := strings.ReplaceAll(b.String(), "\r\n ", ""); got != "SUMMARY:"+summary+"\r\n" {
+		t.Errorf("unfolded = %q", got)
+	}
+}
+
+// 件名・場所・説明に何が入っていても、書き出した行は 75 オクテット以内で、改行を含まず、折り返しを戻すと元の値になる
+func FuzzWriteICS(f *testing.F) {
+	f.Add("定例", "会議室A", "議題:\n1. 進捗")
+	f.Add(strings.Repeat("長い件名の予定", 10), "", "")
+	f.Add(`a;b,c\d`, "Tokyo, Japan", "line1\r\nline2\rline3")
+	f.Add("\xe3\x81", strings.Repeat("\x80", 100), "\xff")
+	f.Fuzz(func(t *testing.T, summary, location, description string) {
+		item := &calendar.Event{
+			Id:          "fuzz",
+			Summary:     summary,
+			Location:    location,
+			Description: description,
+			Start:       &calendar.EventDateTime{DateTime: "2024-06-14T10:00:00+09:00"},
+			End:         &calendar.EventDateTime{DateTime: "2024-06-14T11:00:00+09:00"},
+		}
+		var b strings.Builder
+		if err := writeICS(&b, "", []icsEvent{{calendarID: "primary", item: item}}); err != nil {
+			t.Fatal(err)
+		}
+		out := b.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("output does not end with CRLF: %q", out)
+		}
+		for i, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+			if len(l) > 75 {
+				t.Errorf("line %d has %d octets: %q", i, len(l), l)
+			}
+			if strings.ContainsAny(l, "\r\n") {
+				t.Errorf("line %d contains a line break: %q", i, l)
+			}
+		}
+		unfolded := strings.ReplaceAll(out, "\r\n ", "")
+		if w := "SUMMARY:" + icsEscape(summary) + "\r\n"; !strings.Contains(unfolded, w) {
+			t.Errorf("missing %q in:\n%q", w, out)
+		}
+		if utf8.ValidString(summary) && utf8.ValidString(location) && utf8.ValidString(description) && !utf8.ValidString(out) {
+			t.Errorf("output splits a character: %q", out)
+		}
+	})
+}
+
+// Google カレンダーから受け取った EXDATE・RDATE の行を書き換えても 1 行のままで、解釈できない行はそのまま返す
+func FuzzICSLocalDates(f *testing.F) {
+	for _, s := range []string{
+		"EXDATE;TZID=America/New_York:20240614T100000",
+		"EXDATE:20240614T010000Z,20240621T010000Z",
+		"RDATE;VALUE=DATE-TIME:20240614T100000",
+		"RDATE;VALUE=DATE:20240614",
+		"EXDATE;TZID=\"Asia/Tokyo\":20240614T100000",
+		"EXDATE;TZID=Nowhere/Zone:20240614T100000",
+		"RRULE:FREQ=WEEKLY;BYDAY=FR",
+		"EXDATE:2024",
+		"EXDATE:",
+		"",
+	} {
+		f.Add(s)
+	}
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		got := icsLocalDates(line, "Asia/Tokyo", loc)
+		if got == line {
+			return
+		}
+		if !strings.HasPrefix(got, "EXDATE;TZID=Asia/Tokyo:") && !strings.HasPrefix(got, "RDATE;TZID=Asia/Tokyo:") {
+			t.Errorf("icsLocalDates(%q) = %q, want the line unchanged or rewritten with TZID=Asia/Tokyo", line, got)
+		}
+		if strings.ContainsAny(got, "\r\n") {
+			t.Errorf("icsLocalDates(%q) = %q, contains a line break", line, got)
+		}
+	})
+}
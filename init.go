@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+)
+
+// Google Cloud で OAuth クライアントを作成する手順
+var initSteps = []string{
+	"プロジェクトを作成します: https://console.cloud.google.com/projectcreate",
+	"Google Calendar API を有効にします: https://console.cloud.google.com/apis/library/calendar-json.googleapis.com",
+	"OAuth 同意画面を設定します（User Type は「外部」、テストユーザーに自分のアドレスを追加）: https://console.cloud.google.com/apis/credentials/consent",
+	"「認証情報を作成」→「OAuth クライアント ID」で、アプリケーションの種類を「デスクトップ アプリ」にして作成し、JSON をダウンロードします: https://console.cloud.google.com/apis/credentials",
+}
+
+// 初めて使う人のために、OAuth クライアントの設定（credentials.json）の作成から認可までを案内する
+func setupInit(fs *flag.FlagSet) func(args []string) error {
+	force := fs.Bool("force", false, "Replace existing credentials.json and token.json")
+	write := fs.Bool("write", false, "Also grant access to change events (for plan, move, delete and other write commands)")
+	return func(args []string) error {
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("init needs a terminal to paste credentials and the authorization code")
+		}
+		in := bufio.NewReader(os.Stdin)
+		scope := calendar.CalendarReadonlyScope
+		if *write {
+			scope = calendar.CalendarEventsScope
+		}
+
+		_, statErr := os.Stat(credentialsFile)
+		switch {
+		case statErr == nil && !*force:
+			fmt.Printf("%s はすでにあります（作り直すには --force を付けてください）。\n", credentialsFile)
+		case !errors.Is(statErr, os.ErrNotExist) && statErr != nil:
+			return fmt.Errorf("Unable to read client secret file: %w", statErr)
+		case statErr != nil && hasEmbeddedCredentials():
+			fmt.Println("このビルドには OAuth クライアントが埋め込まれているため、Google Cloud の設定は不要です。")
+		default:
+			fmt.Println("Google Calendar API を使うための OAuth クライアントを作成します。")
+			for i, step := range initSteps {
+				fmt.Printf("  %d. %s\n", i+1, step)
+			}
+			fmt.Println()
+			b, err := readCredentialsInput(in, os.Stdout)
+			if err != nil {
+				return err
+			}
+			if _, err := google.ConfigFromJSON(b, scope); err != nil {
+				return fmt.Errorf("Unable to parse client secret: %w", err)
+			}
+			if err := os.WriteFile(credentialsFile, b, 0600); err != nil {
+				return fmt.Errorf("Unable to write client secret file: %w", err)
+			}
+			fmt.Printf("%s に保存しました。\n", credentialsFile)
+		}
+
+		if *force {
+			if err := os.Remove(tokenFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("Unable to remove token.json: %w", err)
+			}
+		}
+		fmt.Println()
+		fmt.Println("ブラウザで Google アカウントへのアクセスを許可してください。")
+		ctx := context.Background()
+		client, err := newGoogleClient(ctx, scope)
+		if err != nil {
+			return err
+		}
+		entries, err := client.ListCalendars(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("設定が完了しました（%d 件のカレンダーを読み込めます）。%s で今日の予定を表示します。\n", len(entries), progName)
+		return nil
+	}
+}
+
+// ダウンロードした JSON のパスか、貼り付けた JSON の内容を読み込む
+func readCredentialsInput(in *bufio.Reader, out io.Writer) ([]byte, error) {
+	fmt.Fprint(out, "ダウンロードした JSON のパスを入力するか、内容を貼り付けてください: ")
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("Unable to read client secret: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		path := strings.Trim(line, `"'`)
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read client secret file: %w", err)
+		}
+		return b, nil
+	}
+	// 貼り付けた JSON は複数行になることがあるため、閉じるまで読み込む
+	buf := line
+	for !json.Valid([]byte(buf)) {
+		more, err := in.ReadString('\n')
+		buf += more
+		if err != nil {
+			if json.Valid([]byte(buf)) {
+				break
+			}
+			return nil, fmt.Errorf("Unable to parse client secret: incomplete JSON")
+		}
+	}
+	return []byte(buf), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 場所や説明に書かれた会議 URL を見つける
+var meetingURLPattern = regexp.MustCompile(`https://[^\s"'<>]*(zoom\.us/j/|teams\.microsoft\.com/l/meetup-join/|meet\.google\.com/|meet\.jit\.si/|webex\.com/)[^\s"'<>]*`)
+
+// 開催中または次に始まる、会議リンク付きの予定を開く
+func setupJoin(fs *flag.FlagSet) func(args []string) error {
+	within := fs.Duration("within", 12*time.Hour, "Only consider events starting within this duration")
+	printOnly := fs.Bool("print", false, "Print the meeting URL instead of opening it")
+	muted := fs.Bool("muted", false, "Join with microphone and camera off where the service supports it via URL (Jitsi)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		events, err := client.ListEvents(ctx, "primary", now, now.Add(*within))
+		if err != nil {
+			return err
+		}
+		item, link := nextMeeting(events.Items, now, *within)
+		if item == nil {
+			return fmt.Errorf("No meeting with a conference link in the next %v: %w", *within, errNoEvents)
+		}
+		if *muted {
+			var ok bool
+			if link, ok = mutedURL(link); !ok {
+				fmt.Fprintln(os.Stderr, "This meeting service does not support joining muted via URL; opening as is.")
+			}
+		}
+
+		start, _ := eventTimes(item)
+		fmt.Fprintf(os.Stderr, "%s (%s) %s\n", item.Summary, start.Format("15:04"), link)
+		if *printOnly {
+			fmt.Println(link)
+			return nil
+		}
+		return openBrowser(link)
+	}
+}
+
+// 開催中または now から within 以内に始まる予定のうち、会議リンクのある最初のものを返す
+func nextMeeting(items []*calendar.Event, now time.Time, within time.Duration) (*calendar.Event, string) {
+	for _, item := range items {
+		if item.Start.DateTime == "" || attendanceStatus(item) == "declined" {
+			continue
+		}
+		start, end := eventTimes(item)
+		if !end.After(now) || start.After(now.Add(within)) {
+			continue
+		}
+		if link := conferenceURL(item); link != "" {
+			return item, link
+		}
+	}
+	return nil, ""
+}
+
+// 予定の会議 URL（Google Meet、会議データのビデオ入口、場所や説明に書かれた URL の順）を返す
+func conferenceURL(item *calendar.Event) string {
+	if item.HangoutLink != "" {
+		return item.HangoutLink
+	}
+	if item.ConferenceData != nil {
+		for _, ep := range item.ConferenceData.EntryPoints {
+			if ep.EntryPointType == "video" && ep.Uri != "" {
+				return ep.Uri
+			}
+		}
+	}
+	for _, s := range []string{item.Location, item.Description} {
+		if m := meetingURLPattern.FindString(s); m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+// 自分の出欠の回答（招待されていない予定は空文字）
+func attendanceStatus(item *calendar.Event) string {
+	for _, a := range item.Attendees {
+		if a.Self {
+			return a.ResponseStatus
+		}
+	}
+	return ""
+}
+
+// マイクとカメラをオフにして参加する URL に変換する（対応しているサービスのみ）
+func mutedURL(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link, false
+	}
+	if strings.HasSuffix(u.Host, "jit.si") {
+		u.Fragment = "config.startWithAudioMuted=true&config.startWithVideoMuted=true"
+		return u.String(), true
+	}
+	return link, false
+}
+
+// OS の既定のブラウザで URL を開く
+func openBrowser(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Unable to open browser: %w", err)
+	}
+	return nil
+}
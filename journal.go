@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 自分の出欠状況の表示名
+var attendanceLabels = map[string]string{
+	"accepted":    "✓参加",
+	"tentative":   "?仮承諾",
+	"declined":    "✗不参加",
+	"needsAction": "-未回答",
+}
+
+// 指定された日が今日より前かどうか
+func isPastDate(targetDate time.Time) bool {
+	now := time.Now().Add(-dayStart)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return targetDate.Before(today)
+}
+
+// 過去の日について「実際にどうだったか」を出力する
+//
+// 繰り返し予定の一部だけが移動された場合は当初の開始時刻（originalStartTime）を、
+// 予定の開始後に内容が更新されていればその旨を、招待された予定には自分の出欠を併記する。
+func printJournal(w io.Writer, displayDate string, events []agendaEvent) {
+	fmt.Fprintf(w, "%sの記録:\n", displayDate)
+
+	if len(events) == 0 {
+		fmt.Fprintf(w, "%sの予定はありません。\n", displayDate)
+		return
+	}
+	for _, e := range events {
+		fmt.Fprintln(w, formatJournalEntry(e))
+	}
+}
+
+func formatJournalEntry(e agendaEvent) string {
+	item, eventStart, eventEnd := e.item, e.start, e.end
+
+	var b strings.Builder
+	if e.allDay {
+		b.WriteString("終日")
+	} else {
+		fmt.Fprintf(&b, "%s-%s", eventStart.Format("15:04"), eventEnd.Format("15:04"))
+	}
+	fmt.Fprintf(&b, " %s", item.Summary)
+
+	if label := attendanceLabel(item); label != "" {
+		fmt.Fprintf(&b, " %s", label)
+	}
+
+	var notes []string
+	if original := parseDateTime(item.OriginalStartTime); !original.IsZero() && !original.Equal(eventStart) {
+		if original.Format("2006-01-02") == eventStart.Format("2006-01-02") {
+			notes = append(notes, fmt.Sprintf("当初 %s 開始", original.Format("15:04")))
+		} else {
+			notes = append(notes, fmt.Sprintf("当初 %s 開始", original.Format("2006-01-02 15:04")))
+		}
+	}
+	if updated, err := time.Parse(time.RFC3339, item.Updated); err == nil && !e.allDay && updated.After(eventStart) {
+		notes = append(notes, fmt.Sprintf("%s に更新", updated.In(eventStart.Location()).Format("2006-01-02 15:04")))
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(&b, "（%s）", strings.Join(notes, "、"))
+	}
+	return b.String()
+}
+
+// 招待された予定の場合、自分の出欠状況を返す（自分だけの予定は空文字）
+func attendanceLabel(item *calendar.Event) string {
+	return attendanceLabels[attendanceStatus(item)]
+}
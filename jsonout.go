@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"gcal-daily-agenda/schema"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// JSON で出力する予定と1日分の予定（形式は schema パッケージで定義する）
+type (
+	jsonEvent  = schema.Event
+	jsonAgenda = schema.Agenda
+)
+
+func newJSONEvent(calendarID string, item *calendar.Event) jsonEvent {
+	e := jsonEvent{
+		ID:        item.Id,
+		Calendar:  calendarID,
+		Summary:   item.Summary,
+		AllDay:    item.Start.DateTime == "",
+		ColorID:   item.ColorId,
+		ColorName: colorName(item.ColorId),
+		Location:  item.Location,
+		Private:   isPrivate(item),
+		ReadOnly:  readOnlyReason(item) != "",
+		Tags:      eventTags(item),
+	}
+	if addr := physicalLocation(item.Location); addr != "" {
+		e.MapsURL = mapsURL(addr)
+	}
+	if e.AllDay {
+		e.Start, e.End = item.Start.Date, item.End.Date
+	} else {
+		e.Start, e.End = item.Start.DateTime, item.End.DateTime
+	}
+	return e
+}
+
+// 複数のカレンダーから1日分の予定を取得し、開始時刻順（同じなら長さ、件名、カレンダーの順）にまとめる
+//
+// 転送された招待などで同じ予定が複数のカレンダーに入っている場合は、最初のカレンダーの予定だけを残し、
+// Sources に入っているカレンダーを記録する。取得できなかったカレンダーは Errors に記録する。
+func fetchJSONAgenda(ctx context.Context, client calendarClient, calendarIDs []string, targetDate time.Time) (*jsonAgenda, error) {
+	startTime, endTime := dayWindow(targetDate)
+	agenda := schema.NewAgenda(targetDate.Format("2006-01-02"))
+	seen := map[string]int{}
+	pal := colors(ctx, client)
+	errs, err := eachCalendar(calendarIDs, func(id string) error {
+		events, err := client.ListEvents(ctx, id, startTime, endTime)
+		if err != nil {
+			return err
+		}
+		for _, item := range eventsForDay(events.Items, targetDate) {
+			if key := duplicateKey(item); key != "" {
+				if i, ok := seen[key]; ok {
+					e := &agenda.Events[i]
+					if len(e.Sources) == 0 {
+						e.Sources = []string{e.Calendar}
+					}
+					e.Sources = append(e.Sources, id)
+					continue
+				}
+				seen[key] = len(agenda.Events)
+			}
+			e := newJSONEvent(id, item)
+			e.ColorHex = pal.eventColor(item.ColorId)
+			agenda.Events = append(agenda.Events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	agenda.Errors = errs
+	sortJSONEvents(agenda.Events, "start", false)
+	return agenda, nil
+}
+
+// 複数のカレンダーに入っている同じ予定を見分けるキー（iCalUID がなければ空）
+//
+// 繰り返し予定の各回は iCalUID が同じになるため、開始時刻と組み合わせる。
+func duplicateKey(item *calendar.Event) string {
+	if item.ICalUID == "" || item.Start == nil {
+		return ""
+	}
+	start := item.Start.Date
+	if item.Start.DateTime != "" {
+		t, _ := eventTimes(item)
+		start = t.UTC().Format(time.RFC3339)
+	}
+	return item.ICalUID + "@" + start
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 予定の件名や説明欄から課題へのリンクを作る設定
+type linksConfig struct {
+	// Jira のサイト（"https://example.atlassian.net"）。jira_projects と合わせて設定すると "PROJ-1234" を課題へのリンクにする
+	Jira string `json:"jira,omitempty"`
+	// リンクにする Jira のプロジェクトのキー（"PROJ"）。"UTF-8" や "SHA-256" をリンクにしないよう、書いたものだけをリンクにする
+	JiraProjects []string `json:"jira_projects,omitempty"`
+	// "#123" だけで書かれた番号に使う GitHub のリポジトリ（"owner/repo"）
+	GitHubRepo string `json:"github_repo,omitempty"`
+	// 課題の件名を Jira / GitHub の API から取得して併記する
+	FetchTitles bool `json:"fetch_titles,omitempty"`
+}
+
+// 予定に書かれていた課題や Pull Request
+type issueLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	// 件名を取得する API の URL
+	api string
+}
+
+var (
+	jiraKeyPattern     = regexp.MustCompile(`\b([A-Z][A-Z0-9]+)-\d+\b`)
+	githubURLPattern   = regexp.MustCompile(`https://github\.com/([\w.-]+/[\w.-]+)/(?:issues|pull)/(\d+)`)
+	githubRefPattern   = regexp.MustCompile(`\b([\w.-]+/[\w.-]+)#(\d+)\b`)
+	githubShortPattern = regexp.MustCompile(`(?:^|[\s(])#(\d+)\b`)
+)
+
+// text に書かれている課題へのリンクを、書かれている順に重複を除いて返す
+func (c linksConfig) find(text string) []issueLink {
+	type found struct {
+		pos  int
+		link issueLink
+	}
+	var all []found
+	githubLink := func(repo, num string) issueLink {
+		return issueLink{
+			Label: repo + "#" + num,
+			URL:   "https://github.com/" + repo + "/issues/" + num,
+			api:   "https://api.github.com/repos/" + repo + "/issues/" + num,
+		}
+	}
+	for _, m := range githubURLPattern.FindAllStringSubmatchIndex(text, -1) {
+		// Pull Request の URL はそのまま使う
+		link := githubLink(text[m[2]:m[3]], text[m[4]:m[5]])
+		link.URL = text[m[0]:m[1]]
+		all = append(all, found{m[0], link})
+	}
+	for _, m := range githubRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		all = append(all, found{m[0], githubLink(text[m[2]:m[3]], text[m[4]:m[5]])})
+	}
+	if c.GitHubRepo != "" {
+		for _, m := range githubShortPattern.FindAllStringSubmatchIndex(text, -1) {
+			all = append(all, found{m[2], githubLink(c.GitHubRepo, text[m[2]:m[3]])})
+		}
+	}
+	if c.Jira != "" && len(c.JiraProjects) > 0 {
+		base := strings.TrimSuffix(c.Jira, "/")
+		for _, m := range jiraKeyPattern.FindAllStringSubmatchIndex(text, -1) {
+			if !slices.Contains(c.JiraProjects, text[m[2]:m[3]]) {
+				continue
+			}
+			key := text[m[0]:m[1]]
+			all = append(all, found{m[0], issueLink{
+				Label: key,
+				URL:   base + "/browse/" + key,
+				api:   base + "/rest/api/2/issue/" + url.PathEscape(key) + "?fields=summary",
+			}})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].pos < all[j].pos })
+
+	var out []issueLink
+	seen := map[string]bool{}
+	for _, f := range all {
+		if seen[f.link.URL] {
+			continue
+		}
+		seen[f.link.URL] = true
+		out = append(out, f.link)
+	}
+	return out
+}
+
+// 件名と説明欄の課題へのリンク（FetchTitles の場合は課題の件名も取得する）
+func (c linksConfig) eventLinks(summary, description string) []issueLink {
+	links := c.find(summary + "\n" + description)
+	if c.FetchTitles {
+		for i := range links {
+			links[i].Title = issueTitle(links[i])
+		}
+	}
+	return links
+}
+
+var (
+	issueTitlesMu sync.Mutex
+	issueTitles   = map[string]string{}
+)
+
+// 課題の件名（取得できなければ空）
+//
+// 同じ課題が何度も出てくるため、プロセス内でキャッシュする。
+func issueTitle(link issueLink) string {
+	issueTitlesMu.Lock()
+	title, ok := issueTitles[link.api]
+	issueTitlesMu.Unlock()
+	if ok {
+		return title
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	title, err := fetchIssueTitle(ctx, link)
+	if err != nil {
+		// 取得できない課題は件名なしで表示する
+		title = ""
+	}
+	issueTitlesMu.Lock()
+	issueTitles[link.api] = title
+	issueTitlesMu.Unlock()
+	return title
+}
+
+// GitHub は GITHUB_TOKEN、Jira は JIRA_EMAIL と JIRA_API_TOKEN があれば認証に使う
+func fetchIssueTitle(ctx context.Context, link issueLink) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.api, nil)
+	if err != nil {
+		return "", err
+	}
+	github := strings.HasPrefix(link.api, "https://api.github.com/")
+	if github {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	} else if email, token := os.Getenv("JIRA_EMAIL"), os.Getenv("JIRA_API_TOKEN"); email != "" && token != "" {
+		req.SetBasicAuth(email, token)
+	}
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", link.api, resp.Status)
+	}
+	var body struct {
+		Title  string `json:"title"`
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if github {
+		return body.Title, nil
+	}
+	return body.Fields.Summary, nil
+}
+
+// "PROJ-1234 ログイン画面の修正 https://..."
+func (l issueLink) String() string {
+	if l.Title != "" {
+		return l.Label + " " + l.Title + " " + l.URL
+	}
+	return l.Label + " " + l.URL
+}
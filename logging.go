@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// --log-format と --log-level に従ってログの出力先を設定する
+func setupLogging(format, level string) error {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("Invalid log level %q: %w", level, err)
+	}
+	switch format {
+	case "text":
+		// 既定のハンドラ（log パッケージと同じ形式）のままレベルだけ変える
+		slog.SetLogLoggerLevel(lv)
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lv})))
+	default:
+		return fmt.Errorf("Unsupported log format: %s", format)
+	}
+	return nil
+}
+
+// API 呼び出しごとにリクエストIDを振ってログに出すトランスポート
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := newRequestID()
+	start := time.Now()
+	logger := slog.With("request_id", id, "method", req.Method, "host", req.URL.Host, "path", req.URL.Path)
+	logger.Debug("API request")
+
+	resp, err := t.base.RoundTrip(req)
+	recordAPIUsage(req, resp)
+	if err != nil {
+		logger.Warn("API request failed", "error", err, "elapsed", time.Since(start))
+		return nil, err
+	}
+	logger.Debug("API response", "status", resp.StatusCode, "elapsed", time.Since(start))
+	return resp, nil
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,192 +1,365 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"time"
+	"strings"
+)
 
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
+// 終了コード
+//
+//	0: 予定が見つかった（または正常終了）
+//	1: エラー
+//	2: 認可が必要（token.json がない、または失効している）
+//	3: 予定がない（--fail-on-empty 指定時）
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitAuthRequired = 2
+	exitNoEvents     = 3
 )
 
-// カラーIDと色名のマッピング
-var colorNames = map[string]string{
-	"1":  "薄紫",
-	"2":  "緑",
-	"3":  "紫",
-	"4":  "赤",
-	"5":  "黄",
-	"6":  "オレンジ",
-	"7":  "水色",
-	"8":  "グレー",
-	"9":  "青紫",
-	"10": "緑",
-	"11": "赤",
-}
+// --fail-on-empty 指定時に予定が1件もなかったことを表すエラー
+var errNoEvents = errors.New("no events")
 
-// Retrieve a token, saves the token, then returns the generated client.
-func getClient(config *oauth2.Config) *http.Client {
-	// The file token.json stores the user's access and refresh tokens, and is
-	// created automatically when the authorization flow completes for the first
-	// time.
-	tokFile := "token.json"
-	tok, err := tokenFromFile(tokFile)
-	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(tokFile, tok)
-	}
-	return config.Client(context.Background(), tok)
+// サブコマンドの定義
+type command struct {
+	name    string
+	summary string
+	// 補完候補として使う位置引数
+	args []string
+	// 定義されていないフラグの形の引数（tag の "-tentative" など）を位置引数として扱う
+	dashArgs bool
+	// フラグを登録し、パース後に実行する関数を返す
+	setup func(fs *flag.FlagSet) func(args []string) error
 }
 
-// Request a token from the web, then returns the retrieved token.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
+const progName = "gcal-daily-agenda"
 
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("Unable to read authorization code: %v", err)
-	}
+// サブコマンドなしで実行した場合のコマンド
+var rootCommand = command{name: progName, setup: setupAgenda}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
-	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+func commands() []command {
+	return []command{
+		{
+			name:    "init",
+			summary: "Set up Google API credentials and authorize step by step",
+			setup:   setupInit,
+		},
+		{
+			name:    "auth",
+			summary: "Show the permissions granted to token.json, or revoke them",
+			args:    []string{"scopes", "revoke"},
+			setup:   setupAuth,
+		},
+		{
+			name:    "week",
+			summary: "Print the agenda for a week (Monday to Sunday)",
+			setup:   setupWeek,
+		},
+		{
+			name:    "month",
+			summary: "Print the agenda for a month",
+			setup:   setupMonth,
+		},
+		{
+			name:    "batch",
+			summary: "Print agendas for a range of days with a single API request",
+			setup:   setupBatch,
+		},
+		{
+			name:    "availability",
+			summary: "Print free slots for the next business days",
+			setup:   setupAvailability,
+		},
+		{
+			name:    "join",
+			summary: "Open the conference link of the current or next meeting",
+			setup:   setupJoin,
+		},
+		{
+			name:    "pick",
+			summary: "Fuzzy-search the day's events and open, join, copy or respond to one",
+			setup:   setupPick,
+		},
+		{
+			name:    "daemon",
+			summary: "Run in the background and send reminders before events",
+			setup:   setupDaemon,
+		},
+		{
+			name:    "remind",
+			summary: "Send due reminders (use --once from cron)",
+			setup:   setupRemind,
+		},
+		{
+			name:    "snooze",
+			summary: "Snooze a delivered reminder (event ID, optional duration)",
+			setup:   setupSnooze,
+		},
+		{
+			name:    "ack",
+			summary: "Acknowledge a reminder so it is not repeated",
+			setup:   setupAck,
+		},
+		{
+			name:    "pending",
+			summary: "List invitations I have not responded to",
+			setup:   setupPending,
+		},
+		{
+			name:    "invites",
+			summary: "List pending invitations and accept or decline them by number",
+			args:    []string{"accept", "decline", "tentative"},
+			setup:   setupInvites,
+		},
+		{
+			name:    "plan",
+			summary: "Create the day's events from a plain-text plan (plan apply plan.txt)",
+			args:    []string{"apply"},
+			setup:   setupPlan,
+		},
+		{
+			name:    "template",
+			summary: "Create a day's events from a named template in the config",
+			args:    []string{"list", "apply"},
+			setup:   setupTemplate,
+		},
+		{
+			name:    "copy-day",
+			summary: "Recreate one day's events on another date",
+			setup:   setupCopyDay,
+		},
+		{
+			name:     "tag",
+			summary:  "Add or remove tags stored on an event",
+			dashArgs: true,
+			setup:    setupTag,
+		},
+		{
+			name:    "move",
+			summary: "Move an event to another day or time",
+			setup:   setupMove,
+		},
+		{
+			name:    "delete",
+			summary: "Delete an event",
+			setup:   setupDelete,
+		},
+		{
+			name:    "undo",
+			summary: "Undo the most recent change recorded in the audit log",
+			setup:   setupUndo,
+		},
+		{
+			name:    "audit",
+			summary: "List recent changes to events recorded in the audit log",
+			setup:   setupAudit,
+		},
+		{
+			name:    "render",
+			summary: "Render agenda JSON from stdin without calling Google",
+			setup:   setupRender,
+		},
+		{
+			name:    "export",
+			summary: "Export one event as an .ics file for forwarding",
+			args:    []string{"event"},
+			setup:   setupExport,
+		},
+		{
+			name:    "set-reminder",
+			summary: "Override the reminders of an event",
+			setup:   setupSetReminder,
+		},
+		{
+			name:    "mirror",
+			summary: "Copy events to another calendar as private busy blocks",
+			setup:   setupMirror,
+		},
+		{
+			name:    "serve",
+			summary: "Serve the agenda over HTTP with a web UI, or over gRPC",
+			args:    []string{"http", "grpc"},
+			setup:   setupServe,
+		},
+		{
+			name:    "ooo",
+			summary: "Show who on the team is out of office on a day or week",
+			setup:   setupOOO,
+		},
+		{
+			name:    "team",
+			summary: "Show the team's busy times for a day side by side",
+			setup:   setupTeam,
+		},
+		{
+			name:    "rooms",
+			summary: "Show which meeting rooms are free at a time or for the next event without a room",
+			setup:   setupRooms,
+		},
+		{
+			name:    "show",
+			summary: "Show an archived day's agenda or the details of one event",
+			setup:   setupShow,
+		},
+		{
+			name:    "track-diff",
+			summary: "Compare the day's events with Toggl or Clockify time entries",
+			setup:   setupTrackDiff,
+		},
+		{
+			name:    "heatmap",
+			summary: "Show daily meeting hours over a range as a heatmap",
+			setup:   setupHeatmap,
+		},
+		{
+			name:    "where",
+			summary: "Show the planned working location for the day or week",
+			setup:   setupWhere,
+		},
+		{
+			name:    "people",
+			summary: "List the people you meet on a day or week",
+			setup:   setupPeople,
+		},
+		{
+			name:    "one-on-ones",
+			summary: "List 1:1s with a person and warn when none is scheduled",
+			setup:   setupOneOnOnes,
+		},
+		{
+			name:    "audit-colors",
+			summary: "List events without a color and suggest colors from color_rules",
+			setup:   setupAuditColors,
+		},
+		{
+			name:    "standup",
+			summary: "Summarize yesterday's and today's events for a standup thread",
+			setup:   setupStandup,
+		},
+		{
+			name:    "speak",
+			summary: "Read the day's agenda aloud with say, espeak or Google Text-to-Speech",
+			setup:   setupSpeak,
+		},
+		{
+			name:    "stats",
+			summary: "Summarize meeting and focus hours for a day or week",
+			setup:   setupStats,
+		},
+		{
+			name:    "completion",
+			summary: "Generate shell completion script (bash, zsh, fish)",
+			args:    []string{"bash", "zsh", "fish"},
+			setup:   setupCompletion,
+		},
 	}
-	return tok
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
+func findCommand(name string) (command, bool) {
+	for _, c := range commands() {
+		if c.name == name {
+			return c, true
+		}
 	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
+	return command{}, false
 }
 
-// Saves a token to a file path.
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+// エラーを終了コードに変換する
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errNoEvents):
+		return exitNoEvents
+	case errors.Is(err, errAuthRequired), isAuthError(err):
+		return exitAuthRequired
+	default:
+		return exitError
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
 }
 
 func main() {
-	// 日付引数の処理
-	var targetDate time.Time
-	var err error
-
-	dateStr := flag.String("date", "", "Date to fetch events (format: YYYY-MM-DD)")
-	flag.Parse()
-
-	if *dateStr != "" {
-		targetDate, err = time.Parse("2006-01-02", *dateStr)
-		if err != nil {
-			log.Fatalf("Invalid date format. Please use YYYY-MM-DD format: %v", err)
+	cmd, args := rootCommand, os.Args[1:]
+	if len(args) > 0 {
+		if c, ok := findCommand(args[0]); ok {
+			cmd, args = c, args[1:]
 		}
-	} else {
-		targetDate = time.Now()
 	}
 
-	ctx := context.Background()
-	b, err := os.ReadFile("credentials.json")
+	// flag.ExitOnError は終了コード 2 を使うため、自前で終了コードを決める
+	auditCommand = cmd.name
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	run := cmd.setup(fs)
+	addGlobalFlags(fs)
+	positional, err := parseArgs(fs, args, cmd.dashArgs)
 	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitError)
 	}
-
-	config, err := google.ConfigFromJSON(b, calendar.CalendarReadonlyScope)
-	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+	if err := setupLogging(globalOpts.logFormat, globalOpts.logLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
 	}
-	client := getClient(config)
-
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		log.Fatalf("Unable to retrieve Calendar client: %v", err)
+	if dayStart, err = parseClock(globalOpts.dayStart); err != nil {
+		slog.Error(fmt.Sprintf("Invalid --day-start: %v", err))
+		os.Exit(exitError)
 	}
 
-	// 前日の開始時刻から当日の終了時刻までを設定
-	startTime := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 0, 0, 0, 0, targetDate.Location()).
-		AddDate(0, 0, -1) // 前日の00:00:00
-	endTime := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 23, 59, 59, 0, targetDate.Location())
-
-	events, err := srv.Events.List("primary").
-		ShowDeleted(false).
-		SingleEvents(true).
-		TimeMin(startTime.Format(time.RFC3339)).
-		TimeMax(endTime.Format(time.RFC3339)).
-		OrderBy("startTime").
-		Do()
-	if err != nil {
-		log.Fatalf("Unable to retrieve events: %v", err)
+	err = run(positional)
+	if err != nil && err != errNoEvents {
+		slog.Error(err.Error())
 	}
+	if globalOpts.debugQuota {
+		printAPIUsage(os.Stderr)
+	}
+	os.Exit(exitCode(err))
+}
 
-	// 日付を表示用にフォーマット
-	displayDate := targetDate.Format("2006-01-02")
-	fmt.Printf("%sの予定:\n", displayDate)
-
-	if len(events.Items) == 0 {
-		fmt.Printf("%sの予定はありません。\n", displayDate)
-	} else {
-		for _, item := range events.Items {
-			// イベントの開始時刻と終了時刻を取得
-			startDateTime := item.Start.DateTime
-			if startDateTime == "" {
-				startDateTime = item.Start.Date
-			}
-			endDateTime := item.End.DateTime
-			if endDateTime == "" {
-				endDateTime = item.End.Date
-			}
-
-			// イベントの開始時刻と終了時刻をパース
-			eventStart, _ := time.Parse(time.RFC3339, startDateTime)
-			eventEnd, _ := time.Parse(time.RFC3339, endDateTime)
-
-			// イベントが指定された日に終了するか、指定された日をまたぐ場合に表示
-			if (eventEnd.Format("2006-01-02") == displayDate) ||
-				(eventStart.Before(endTime) && eventEnd.After(startTime.AddDate(0, 0, 1))) {
-
-				// 表示形式を整える
-				startDisplay := eventStart.Format("15:04")
-				endDisplay := eventEnd.Format("15:04")
-
-				// 色情報の取得と変換
-				colorName := "デフォルト"
-				if item.ColorId != "" {
-					if name, ok := colorNames[item.ColorId]; ok {
-						colorName = name
-					}
-				}
+// フラグと位置引数が混在していても（"serve http --addr :8080" など）すべてのフラグを解釈し、
+// 位置引数を返す。"--" 以降はすべて位置引数として扱う
+//
+// dashArgs の場合、定義されていないフラグの形の引数もエラーにせず位置引数として扱う。
+func parseArgs(fs *flag.FlagSet, args []string, dashArgs bool) ([]string, error) {
+	var rest []string
+	for i, a := range args {
+		if a == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
 
-				// 終日イベントの場合は時刻を表示しない
-				if item.Start.DateTime == "" {
-					fmt.Printf("【%s】%v (終日) \n",
-						colorName,
-						item.Summary)
-				} else {
-					fmt.Printf("【%s】%v (%v-%v)\n",
-						colorName,
-						item.Summary,
-						startDisplay,
-						endDisplay)
-				}
+	var positional []string
+	for {
+		if dashArgs {
+			for len(args) > 0 && isUndefinedFlag(fs, args[0]) {
+				positional = append(positional, args[0])
+				args = args[1:]
 			}
 		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func isUndefinedFlag(fs *flag.FlagSet, arg string) bool {
+	name := strings.TrimLeft(arg, "-")
+	if name == arg || name == "" {
+		return false
 	}
+	name, _, _ = strings.Cut(name, "=")
+	return fs.Lookup(name) == nil && name != "h" && name != "help"
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// テストは表示するタイムゾーンを Asia/Tokyo に固定して実行する
+func TestMain(m *testing.M) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		panic(err)
+	}
+	time.Local = loc
+	os.Exit(m.Run())
+}
+
+// runAgenda は一覧の番号で予定を指定できるよう作業ディレクトリに last-listing.json を書くため、テストの間は一時ディレクトリに移る
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// フィクスチャの予定を fixtureClient で取得し、表示に使う形にする
+func loadTestEvents(t testing.TB, path string) []agendaEvent {
+	t.Helper()
+	client, err := newFixtureClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := client.ListEvents(context.Background(), "primary", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return normalizeEvents("primary", events.Items)
+}
+
+// テストの間だけ --day-start を変更する
+func setDayStart(t testing.TB, d time.Duration) {
+	t.Helper()
+	saved := dayStart
+	dayStart = d
+	t.Cleanup(func() { dayStart = saved })
+}
+
+// 予定の ID（並びを比べるため）
+func eventIDs(events []agendaEvent) []string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.item.Id
+	}
+	return ids
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 場所への移動時間を見積もる設定
+type mapsConfig struct {
+	// 出発地（自宅やオフィスの住所）。設定すると --verbose で移動時間を併記する
+	Origin string `json:"origin,omitempty"`
+	// driving、transit、walking、bicycling（省略時は transit）
+	Mode string `json:"mode,omitempty"`
+	// Distance Matrix API のキー（省略時は環境変数 GOOGLE_MAPS_API_KEY）
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// オンライン会議を表す場所（小文字で比べる）
+var onlineLocations = []string{"zoom", "teams", "meet.google", "webex", "オンライン", "online", "remote", "リモート"}
+
+// 住所として扱う場所に整える（URL やオンライン会議の場所は空にする）
+//
+// 全角の空白や連続した空白を1つにし、前後の空白と区切りの記号を取り除く。
+func physicalLocation(location string) string {
+	loc := strings.Join(strings.Fields(strings.ReplaceAll(location, "　", " ")), " ")
+	loc = strings.Trim(loc, " ,、")
+	if loc == "" || strings.Contains(loc, "://") {
+		return ""
+	}
+	lower := strings.ToLower(loc)
+	for _, w := range onlineLocations {
+		if strings.Contains(lower, w) {
+			return ""
+		}
+	}
+	return loc
+}
+
+var (
+	travelTimesMu sync.Mutex
+	travelTimes   = map[string]string{}
+)
+
+// 出発地から location までの移動時間（"約25分"）。設定がないか取得できなければ空
+func (c mapsConfig) travelTime(location string) string {
+	key := c.APIKey
+	if key == "" {
+		key = os.Getenv("GOOGLE_MAPS_API_KEY")
+	}
+	if c.Origin == "" || key == "" || location == "" {
+		return ""
+	}
+	mode := c.Mode
+	if mode == "" {
+		mode = "transit"
+	}
+
+	cacheKey := mode + "|" + location
+	travelTimesMu.Lock()
+	t, ok := travelTimes[cacheKey]
+	travelTimesMu.Unlock()
+	if ok {
+		return t
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	t, err := fetchTravelTime(ctx, key, c.Origin, location, mode)
+	if err != nil {
+		t = ""
+	}
+	travelTimesMu.Lock()
+	travelTimes[cacheKey] = t
+	travelTimesMu.Unlock()
+	return t
+}
+
+func fetchTravelTime(ctx context.Context, key, origin, destination, mode string) (string, error) {
+	q := url.Values{
+		"origins":      {origin},
+		"destinations": {destination},
+		"mode":         {mode},
+		"language":     {"ja"},
+		"key":          {key},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://maps.googleapis.com/maps/api/distancematrix/json?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+		Rows   []struct {
+			Elements []struct {
+				Status   string `json:"status"`
+				Duration struct {
+					Value int `json:"value"`
+				} `json:"duration"`
+			} `json:"elements"`
+		} `json:"rows"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Status != "OK" || len(body.Rows) == 0 || len(body.Rows[0].Elements) == 0 || body.Rows[0].Elements[0].Status != "OK" {
+		return "", fmt.Errorf("Unable to estimate travel time: %s", body.Status)
+	}
+	return "約" + formatMinutes(time.Duration(body.Rows[0].Elements[0].Duration.Value)*time.Second), nil
+}
+
+// "25分" / "1時間10分"
+func formatMinutes(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	if m >= 60 {
+		if m%60 == 0 {
+			return fmt.Sprintf("%d時間", m/60)
+		}
+		return fmt.Sprintf("%d時間%d分", m/60, m%60)
+	}
+	return fmt.Sprintf("%d分", m)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 予定をほかのカレンダーに中身を伏せた予定として複製する設定
+type mirrorConfig struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// 今日から何日後までを複製するか（省略時は 14）
+	Days int `json:"days,omitempty"`
+	// 複製した予定の件名（省略時は Busy）
+	Summary string `json:"summary,omitempty"`
+}
+
+// 複製した予定に付ける非公開の拡張プロパティ（値は "複製元のカレンダーID/予定ID"）
+const mirrorProperty = "gcalDailyAgendaMirror"
+
+// daemon で複製を同期する間隔
+const mirrorInterval = 15 * time.Minute
+
+func (m mirrorConfig) days() int {
+	if m.Days <= 0 {
+		return 14
+	}
+	return m.Days
+}
+
+func (m mirrorConfig) summary() string {
+	if m.Summary == "" {
+		return "Busy"
+	}
+	return m.Summary
+}
+
+// 予定を別のカレンダー（別のアカウントなど）に「Busy」として複製し、空き状況を揃える
+func setupMirror(fs *flag.FlagSet) func(args []string) error {
+	source := fs.String("source", "", "Calendar to copy events from (default: mirror entries in the config)")
+	target := fs.String("target", "", "Calendar to create busy blocks on")
+	days := fs.Int("days", 0, "Number of days to mirror from today (default 14)")
+	dryRun := fs.Bool("dry-run", false, "Print the changes without applying them")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		mirrors := cfg.Mirror
+		if *source != "" || *target != "" {
+			if *source == "" || *target == "" {
+				return fmt.Errorf("--source and --target must be given together")
+			}
+			mirrors = []mirrorConfig{{Source: *source, Target: *target}}
+		}
+		if len(mirrors) == 0 {
+			return fmt.Errorf("Usage: %s mirror --source ID --target ID (or set mirror in the config)", progName)
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		for _, m := range mirrors {
+			if *days > 0 {
+				m.Days = *days
+			}
+			res, err := runMirror(ctx, client, os.Stdout, m, time.Now(), *dryRun)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s → %s: 作成 %d件、更新 %d件、削除 %d件\n", m.Source, m.Target, res.created, res.updated, res.deleted)
+		}
+		return nil
+	}
+}
+
+type mirrorResult struct {
+	created, updated, deleted int
+}
+
+// 複製元の予定と複製先の「Busy」の予定を比べ、足りないものを作成し、時間が変わったものを更新し、
+// 複製元から消えたものを削除する
+func runMirror(ctx context.Context, client calendarWriter, w io.Writer, m mirrorConfig, now time.Time, dryRun bool) (mirrorResult, error) {
+	var res mirrorResult
+	timeMin := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	timeMax := timeMin.AddDate(0, 0, m.days())
+
+	sourceEvents, err := client.ListEvents(ctx, m.Source, timeMin, timeMax)
+	if err != nil {
+		return res, err
+	}
+	targetEvents, err := client.ListEvents(ctx, m.Target, timeMin, timeMax)
+	if err != nil {
+		return res, err
+	}
+
+	existing := map[string]*calendar.Event{}
+	for _, item := range targetEvents.Items {
+		if key := mirrorKey(item); key != "" {
+			existing[key] = item
+		}
+	}
+
+	want := map[string]bool{}
+	for _, item := range sourceEvents.Items {
+		if !shouldMirror(item) {
+			continue
+		}
+		key := m.Source + "/" + item.Id
+		want[key] = true
+		block := mirrorEvent(m, item, key)
+
+		old, ok := existing[key]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "作成: %s %s\n", formatMirrorTime(item), item.Summary)
+			res.created++
+			if !dryRun {
+				if _, err := client.InsertEvent(ctx, m.Target, block); err != nil {
+					return res, err
+				}
+			}
+		case !sameTimes(old, item):
+			fmt.Fprintf(w, "更新: %s %s\n", formatMirrorTime(item), item.Summary)
+			res.updated++
+			if !dryRun {
+				block.Id = old.Id
+				if _, err := client.UpdateEvent(ctx, m.Target, block); err != nil {
+					return res, err
+				}
+			}
+		}
+	}
+
+	for key, old := range existing {
+		if want[key] {
+			continue
+		}
+		fmt.Fprintf(w, "削除: %s\n", formatMirrorTime(old))
+		res.deleted++
+		if !dryRun {
+			if err := client.DeleteEvent(ctx, m.Target, old.Id); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// 複製した予定であれば複製元を表すキーを返す
+func mirrorKey(item *calendar.Event) string {
+	if item.ExtendedProperties == nil {
+		return ""
+	}
+	return item.ExtendedProperties.Private[mirrorProperty]
+}
+
+// 複製の対象にする予定か（辞退した予定・「空き時間」の予定・複製した予定自身は除く）
+func shouldMirror(item *calendar.Event) bool {
+	return item.Status != "cancelled" &&
+		item.Transparency != "transparent" &&
+		attendanceStatus(item) != "declined" &&
+		mirrorKey(item) == ""
+}
+
+// 複製先に作成する予定（件名だけで内容は含めず、通知もしない）
+func mirrorEvent(m mirrorConfig, item *calendar.Event, key string) *calendar.Event {
+	start, end := *item.Start, *item.End
+	return &calendar.Event{
+		Summary:      m.summary(),
+		Start:        &start,
+		End:          &end,
+		Transparency: "opaque",
+		Visibility:   "private",
+		Reminders:    &calendar.EventReminders{UseDefault: false, ForceSendFields: []string{"UseDefault"}},
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{mirrorProperty: key},
+		},
+	}
+}
+
+// 開始・終了が同じか
+func sameTimes(a, b *calendar.Event) bool {
+	if a.Start.Date != b.Start.Date || a.End.Date != b.End.Date {
+		return false
+	}
+	as, ae := eventTimes(a)
+	bs, be := eventTimes(b)
+	return as.Equal(bs) && ae.Equal(be)
+}
+
+func formatMirrorTime(item *calendar.Event) string {
+	if item.Start.DateTime == "" {
+		return item.Start.Date + " (終日)"
+	}
+	start, end := eventTimes(item)
+	return fmt.Sprintf("%s %s-%s", start.Format("2006-01-02"), start.Format("15:04"), end.Format("15:04"))
+}
+
+// daemon から定期的に複製を同期する
+func syncMirrors(ctx context.Context, client calendarWriter, mirrors []mirrorConfig, now time.Time) error {
+	for _, m := range mirrors {
+		res, err := runMirror(ctx, client, io.Discard, m, now, false)
+		if err != nil {
+			return err
+		}
+		if res != (mirrorResult{}) {
+			slog.Info("Mirrored events", "source", m.Source, "target", m.Target,
+				"created", res.created, "updated", res.updated, "deleted", res.deleted)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 予定の日時を変更できない理由（変更できる場合は ""）
+//
+// 主催者でない招待は、主催者がゲストに変更を許可していない限り変更できない。
+// ロックされた予定（Gmail から作成された予定など）は日時を変更できない。
+func readOnlyReason(item *calendar.Event) string {
+	if item.Locked {
+		return "the event is locked"
+	}
+	if item.Organizer == nil || item.Organizer.Self || item.GuestsCanModify {
+		return ""
+	}
+	for _, a := range item.Attendees {
+		if a.Self {
+			if org := organizerLabel(item); org != "" {
+				return "only the organizer (" + org + ") can modify it"
+			}
+			return "only the organizer can modify it"
+		}
+	}
+	return ""
+}
+
+// 予定を別の日時に移動する（長さは変えない）
+func setupMove(fs *flag.FlagSet) func(args []string) error {
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event")
+	dateStr := fs.String("date", "", "Day to move the event to (format: YYYY-MM-DD; default: the same day)")
+	atStr := fs.String("at", "", "New start time (HH:MM; default: the same time)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 1 || (*dateStr == "" && *atStr == "") {
+			return fmt.Errorf("Usage: %s move <event-id> [--date YYYY-MM-DD] [--at HH:MM]", progName)
+		}
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		event, err := client.GetEvent(ctx, *calendarID, args[0])
+		if err != nil {
+			return err
+		}
+		if reason := readOnlyReason(event); reason != "" {
+			return fmt.Errorf("Cannot move %q: %s", event.Summary, reason)
+		}
+		updated, err := movedEvent(event, *dateStr, *atStr)
+		if err != nil {
+			return err
+		}
+		if _, err := client.UpdateEvent(ctx, *calendarID, updated); err != nil {
+			return err
+		}
+		fmt.Printf("移動しました: %s（元に戻すには %s undo）\n", formatEvent(updated), progName)
+		return nil
+	}
+}
+
+// event の開始を dateStr の日の atStr の時刻（空ならそれぞれ元の日と時刻）にした予定を返す
+func movedEvent(event *calendar.Event, dateStr, atStr string) (*calendar.Event, error) {
+	updated := *event
+	if event.Start == nil || event.End == nil {
+		return nil, fmt.Errorf("Unable to move event %s: it has no start or end", event.Id)
+	}
+	if event.Start.DateTime == "" {
+		// 終日の予定は日付だけを移動する
+		if atStr != "" {
+			return nil, fmt.Errorf("Cannot move an all-day event to a time; use --date only")
+		}
+		start, err := time.ParseInLocation("2006-01-02", event.Start.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse event start: %w", err)
+		}
+		end, err := time.ParseInLocation("2006-01-02", event.End.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse event end: %w", err)
+		}
+		day, err := parseDateFlag(dateStr)
+		if err != nil {
+			return nil, err
+		}
+		days := int(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+		updated.Start = &calendar.EventDateTime{Date: start.AddDate(0, 0, days).Format("2006-01-02")}
+		updated.End = &calendar.EventDateTime{Date: end.AddDate(0, 0, days).Format("2006-01-02")}
+		return &updated, nil
+	}
+
+	start, end := eventTimes(event)
+	day := start
+	if dateStr != "" {
+		d, err := parseDateFlag(dateStr)
+		if err != nil {
+			return nil, err
+		}
+		day = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, start.Location())
+	}
+	clock := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	if atStr != "" {
+		c, err := parseClock(atStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --at: %w", err)
+		}
+		clock = c
+	}
+	newStart := atClock(day, clock)
+	newEnd := newStart.Add(end.Sub(start))
+	updated.Start = &calendar.EventDateTime{DateTime: newStart.Format(time.RFC3339), TimeZone: event.Start.TimeZone}
+	updated.End = &calendar.EventDateTime{DateTime: newEnd.Format(time.RFC3339), TimeZone: event.End.TimeZone}
+	return &updated, nil
+}
+
+// 予定を削除する
+func setupDelete(fs *flag.FlagSet) func(args []string) error {
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: %s delete <event-id>", progName)
+		}
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		event, err := client.GetEvent(ctx, *calendarID, args[0])
+		if err != nil {
+			return err
+		}
+		if reason := readOnlyReason(event); reason != "" {
+			return fmt.Errorf("Cannot delete %q: %s", event.Summary, reason)
+		}
+		if err := client.DeleteEvent(ctx, *calendarID, event.Id); err != nil {
+			return err
+		}
+		fmt.Printf("削除しました: %s（元に戻すには %s undo）\n", formatEvent(event), progName)
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+// Package mqtt は MQTT 3.1.1 でメッセージを送るだけの最小限のクライアント。
+//
+// 常駐モードから Home Assistant などに状態を送るために使う。購読や QoS 1 以上には対応していない。
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// 接続の設定
+type Options struct {
+	// "tcp://host:1883" や "ssl://host:8883"（"mqtt://"、"mqtts://" も可）
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// 接続を切る前に最後の送信から待てる時間（0 なら 60 秒）
+	KeepAlive time.Duration
+}
+
+// ブローカーとの接続
+type Client struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// Dial はブローカーに接続し、CONNACK を受け取るまで待つ
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	u, err := url.Parse(opts.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL %q: %w", opts.Broker, err)
+	}
+	var d net.Dialer
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = d.DialContext(ctx, "tcp", hostPort(u, "1883"))
+	case "ssl", "tls", "mqtts":
+		td := &tls.Dialer{NetDialer: &d, Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = td.DialContext(ctx, "tcp", hostPort(u, "8883"))
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &Client{conn: conn, w: bufio.NewWriter(conn)}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return u.Host
+}
+
+// CONNACK の戻りコード
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+func (c *Client) connect(opts Options) error {
+	keepAlive := opts.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = 60 * time.Second
+	}
+	// クリーンセッション
+	flags := byte(0x02)
+	var payload []byte
+	payload = appendString(payload, opts.ClientID)
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+		payload = appendString(payload, opts.Password)
+	}
+
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags)
+	secs := uint16(keepAlive / time.Second)
+	body = append(body, byte(secs>>8), byte(secs))
+	body = append(body, payload...)
+	if err := c.writePacket(0x10, body); err != nil {
+		return err
+	}
+
+	// CONNACK: 固定ヘッダー 0x20、残りの長さ 2、フラグ、戻りコード
+	var ack [4]byte
+	if _, err := io.ReadFull(c.conn, ack[:]); err != nil {
+		return fmt.Errorf("unable to read CONNACK: %w", err)
+	}
+	if ack[0] != 0x20 || ack[1] != 2 {
+		return errors.New("unexpected response to CONNECT")
+	}
+	if ack[3] != 0 {
+		if msg, ok := connackErrors[ack[3]]; ok {
+			return fmt.Errorf("connection refused: %s", msg)
+		}
+		return fmt.Errorf("connection refused: code %d", ack[3])
+	}
+	return nil
+}
+
+// Publish はメッセージを QoS 0 で送る。retain ならブローカーが最後の値を保持する
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.writePacket(header, body)
+}
+
+// Close は DISCONNECT を送って接続を閉じる
+func (c *Client) Close() error {
+	err := c.writePacket(0xe0, nil)
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c *Client) writePacket(header byte, body []byte) error {
+	if len(body) > 268435455 {
+		return errors.New("packet too large")
+	}
+	c.w.WriteByte(header)
+	// 残りの長さ（7 ビットずつの可変長）
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		c.w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+// 2 バイトの長さを前に付けた UTF-8 文字列
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
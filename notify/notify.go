@@ -0,0 +1,61 @@
+// Package notify はデスクトップ通知を OS ごとの仕組み（Windows のトースト、
+// macOS の terminal-notifier / osascript、Linux などの notify-send）で表示する。
+//
+// どの仕組みも使えない環境では端末のベルを鳴らすだけにとどめ、呼び出し側を失敗させない。
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 通知の内容
+type Notification struct {
+	Title string
+	Body  string
+	// 通知に表示するボタン。空でなければ、対応している環境ではボタンが押されるか通知が閉じられるまで待つ
+	Actions []Action
+}
+
+// 通知のボタン
+type Action struct {
+	ID    string
+	Label string
+}
+
+// 通知を表示する仕組み
+type Notifier interface {
+	// Notify は通知を表示し、押されたボタンの ID（押されなかった、またはボタンに対応していなければ空文字）を返す。
+	Notify(ctx context.Context, n Notification) (string, error)
+}
+
+// New はこの OS で使える通知の仕組みを返す。表示に失敗した場合は端末のベルで代用する
+func New(appName string) Notifier {
+	return &fallback{primary: platformNotifier(appName), secondary: Bell{W: os.Stderr}}
+}
+
+// Bell は端末のベルを鳴らすだけの Notifier
+type Bell struct {
+	W io.Writer
+}
+
+func (b Bell) Notify(ctx context.Context, n Notification) (string, error) {
+	_, err := fmt.Fprint(b.W, "\a")
+	return "", err
+}
+
+type fallback struct {
+	primary, secondary Notifier
+}
+
+func (f *fallback) Notify(ctx context.Context, n Notification) (string, error) {
+	if f.primary != nil {
+		action, err := f.primary.Notify(ctx, n)
+		if err == nil {
+			return action, nil
+		}
+	}
+	return f.secondary.Notify(ctx, n)
+}
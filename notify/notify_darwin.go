@@ -0,0 +1,54 @@
+//go:build darwin
+
+package notify
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// terminal-notifier があればボタン付きで、なければ osascript で通知する
+type macNotifier struct {
+	appName          string
+	terminalNotifier bool
+}
+
+func platformNotifier(appName string) Notifier {
+	_, err := exec.LookPath("terminal-notifier")
+	return &macNotifier{appName: appName, terminalNotifier: err == nil}
+}
+
+func (m *macNotifier) Notify(ctx context.Context, n Notification) (string, error) {
+	if m.terminalNotifier {
+		args := []string{"-title", n.Title, "-message", n.Body, "-group", m.appName}
+		if len(n.Actions) > 0 {
+			labels := make([]string, len(n.Actions))
+			for i, a := range n.Actions {
+				labels[i] = a.Label
+			}
+			args = append(args, "-actions", strings.Join(labels, ","))
+		}
+		out, err := exec.CommandContext(ctx, "terminal-notifier", args...).Output()
+		if err != nil {
+			return "", err
+		}
+		// 押されたボタンのラベルが出力される
+		selected := strings.TrimSpace(string(out))
+		for _, a := range n.Actions {
+			if a.Label == selected {
+				return a.ID, nil
+			}
+		}
+		return "", nil
+	}
+
+	// 引数で渡してスクリプト中のエスケープを不要にする
+	script := []string{
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		n.Title, n.Body,
+	}
+	return "", exec.CommandContext(ctx, "osascript", script...).Run()
+}
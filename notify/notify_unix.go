@@ -0,0 +1,36 @@
+//go:build !windows && !darwin
+
+package notify
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// notify-send（libnotify、D-Bus 経由）で通知する
+type notifySend struct {
+	appName string
+}
+
+func platformNotifier(appName string) Notifier {
+	if _, err := exec.LookPath("notify-send"); err != nil {
+		return nil
+	}
+	return &notifySend{appName: appName}
+}
+
+func (s *notifySend) Notify(ctx context.Context, n Notification) (string, error) {
+	if len(n.Actions) > 0 {
+		args := []string{"--app-name=" + s.appName, "--wait"}
+		for _, a := range n.Actions {
+			args = append(args, "--action="+a.ID+"="+a.Label)
+		}
+		out, err := exec.CommandContext(ctx, "notify-send", append(args, n.Title, n.Body)...).Output()
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+		// ボタンに対応していない古い notify-send では通知だけ表示する
+	}
+	return "", exec.CommandContext(ctx, "notify-send", "--app-name="+s.appName, n.Title, n.Body).Run()
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package notify
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// PowerShell からトースト通知を表示する（タイトルと本文は環境変数で渡す）
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName("text")
+$texts.Item(0).AppendChild($template.CreateTextNode($env:NOTIFY_TITLE)) > $null
+$texts.Item(1).AppendChild($template.CreateTextNode($env:NOTIFY_BODY)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:NOTIFY_APP).Show($toast)
+`
+
+// トースト通知（ボタンには対応していない）
+type toast struct {
+	appName string
+}
+
+func platformNotifier(appName string) Notifier {
+	if _, err := exec.LookPath("powershell"); err != nil {
+		return nil
+	}
+	return &toast{appName: appName}
+}
+
+func (t *toast) Notify(ctx context.Context, n Notification) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(),
+		"NOTIFY_APP="+t.appName,
+		"NOTIFY_TITLE="+n.Title,
+		"NOTIFY_BODY="+n.Body,
+	)
+	return "", cmd.Run()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 特定の人との1on1の予定を一覧にし、間隔と今後の予定の有無を表示する
+func setupOneOnOnes(fs *flag.FlagSet) func(args []string) error {
+	with := fs.String("with", "", "Email address of the other person (required)")
+	days := fs.Int("days", 90, "Number of past days to look at")
+	weeks := fs.Int("weeks", 2, "Warn when no 1:1 is scheduled within this many weeks")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if *with == "" {
+			return fmt.Errorf("--with is required")
+		}
+		if *days < 1 || *weeks < 1 {
+			return fmt.Errorf("--days and --weeks must be at least 1")
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		since, horizon := now.AddDate(0, 0, -*days), now.AddDate(0, 0, 7**weeks)
+		events, err := client.ListEvents(ctx, "primary", since, horizon)
+		if err != nil {
+			return err
+		}
+		var past, upcoming []*calendar.Event
+		for _, item := range events.Items {
+			if !isOneOnOne(item, *with) {
+				continue
+			}
+			start, _ := eventTimes(item)
+			if start.Before(since) {
+				continue
+			}
+			if start.Before(now) {
+				past = append(past, item)
+			} else if start.Before(horizon) {
+				upcoming = append(upcoming, item)
+			}
+		}
+		printOneOnOnes(os.Stdout, *with, *days, *weeks, past, upcoming)
+		return nil
+	}
+}
+
+// 自分と email の2人だけの時間指定の予定か（会議室は数えない。どちらかが欠席なら除く）
+func isOneOnOne(item *calendar.Event, email string) bool {
+	if item.Start.DateTime == "" || item.Status == "cancelled" {
+		return false
+	}
+	found := false
+	others := 0
+	for _, a := range item.Attendees {
+		if a.Resource {
+			continue
+		}
+		if a.ResponseStatus == "declined" && (a.Self || strings.EqualFold(a.Email, email)) {
+			return false
+		}
+		if a.Self {
+			continue
+		}
+		others++
+		if strings.EqualFold(a.Email, email) {
+			found = true
+		}
+	}
+	return found && others == 1
+}
+
+func printOneOnOnes(w io.Writer, email string, days, weeks int, past, upcoming []*calendar.Event) {
+	fmt.Fprintf(w, "%sとの1on1（過去%d日）:\n", email, days)
+	printOneOnOneList(w, past)
+	fmt.Fprintln(w, "今後の予定:")
+	printOneOnOneList(w, upcoming)
+
+	all := append(append([]*calendar.Event{}, past...), upcoming...)
+	if len(all) >= 2 {
+		first, _ := eventTimes(all[0])
+		last, _ := eventTimes(all[len(all)-1])
+		avg := last.Sub(first).Hours() / 24 / float64(len(all)-1)
+		fmt.Fprintf(w, "間隔: 平均%.1f日\n", avg)
+	}
+	if len(upcoming) == 0 {
+		fmt.Fprintf(w, "⚠ 今後%d週間に1on1の予定がありません\n", weeks)
+	}
+}
+
+func printOneOnOneList(w io.Writer, items []*calendar.Event) {
+	if len(items) == 0 {
+		fmt.Fprintln(w, "  なし")
+		return
+	}
+	for _, item := range items {
+		start, end := eventTimes(item)
+		fmt.Fprintf(w, "  %s(%s) %s-%s %s\n", start.Format("2006-01-02"), weekdayNames["ja"][start.Weekday()],
+			start.Format("15:04"), end.Format("15:04"), item.Summary)
+	}
+}
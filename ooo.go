@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 終日の予定の件名に含まれていれば休暇として扱う語（「昼休み」などと区別するため時間指定の予定には使わない）
+var vacationKeywords = []string{"休暇", "休み", "有給", "有休", "不在", "vacation", "holiday", "pto", "ooo", "out of office"}
+
+// 不在の予定が入っている期間
+type absence struct {
+	member   string
+	interval interval
+	allDay   bool
+}
+
+// チームのカレンダーから不在（不在の予定や休暇の終日の予定）の人を日ごとに表示する
+func setupOOO(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to check (format: YYYY-MM-DD, default today)")
+	rangeStr := fs.String("range", "day", "Period to check: day or week (Monday to Sunday)")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Team) == 0 {
+			return fmt.Errorf("No team members in %s (add \"team\": [{\"name\": ..., \"calendar\": ...}])", *configPath)
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+		from, to := date, date
+		switch *rangeStr {
+		case "day":
+		case "week":
+			from, to = weekRange(date)
+		default:
+			return fmt.Errorf("Invalid --range %q (expected day or week)", *rangeStr)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		absences, err := teamAbsences(ctx, client, cfg.Team, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		printAbsences(os.Stdout, absences, daysBetween(from, to))
+		return nil
+	}
+}
+
+// メンバーごとの timeMin から timeMax までの不在の予定
+//
+// 見られないカレンダーがあっても、ほかのメンバーの分は表示できるよう警告だけにする。
+func teamAbsences(ctx context.Context, client calendarClient, team []namedCalendar, timeMin, timeMax time.Time) ([]absence, error) {
+	var out []absence
+	failed := 0
+	for _, m := range team {
+		events, err := client.ListEvents(ctx, m.Calendar, timeMin, timeMax)
+		if err != nil {
+			if isAuthError(err) {
+				return nil, err
+			}
+			slog.Warn("Skipping team calendar", "name", m.Name, "calendar", m.Calendar, "error", err)
+			failed++
+			continue
+		}
+		for _, item := range events.Items {
+			if !isAbsence(item) {
+				continue
+			}
+			out = append(out, absence{member: m.Name, interval: absenceInterval(item), allDay: item.Start.DateTime == ""})
+		}
+	}
+	if failed == len(team) {
+		return nil, fmt.Errorf("Unable to retrieve any team calendar")
+	}
+	return out, nil
+}
+
+// 不在の予定か（Google カレンダーの「不在」か、休暇を表す件名の終日の予定）
+func isAbsence(item *calendar.Event) bool {
+	if item.Status == "cancelled" {
+		return false
+	}
+	if item.EventType == "outOfOffice" {
+		return true
+	}
+	if item.Start.DateTime != "" {
+		return false
+	}
+	summary := strings.ToLower(item.Summary)
+	for _, k := range vacationKeywords {
+		if strings.Contains(summary, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// 予定の期間（終日の予定はローカル時刻の 0 時から終了日の 0 時まで）
+func absenceInterval(item *calendar.Event) interval {
+	if item.Start.DateTime == "" {
+		start, _ := time.ParseInLocation("2006-01-02", item.Start.Date, time.Local)
+		end, _ := time.ParseInLocation("2006-01-02", item.End.Date, time.Local)
+		return interval{start: start, end: end}
+	}
+	start, end := eventTimes(item)
+	return interval{start: start.In(time.Local), end: end.In(time.Local)}
+}
+
+func printAbsences(w io.Writer, absences []absence, days []time.Time) {
+	for _, day := range days {
+		next := day.AddDate(0, 0, 1)
+		var names []string
+		seen := map[string]bool{}
+		for _, a := range absences {
+			if !a.interval.start.Before(next) || !a.interval.end.After(day) || seen[a.member] {
+				continue
+			}
+			seen[a.member] = true
+			names = append(names, a.member+"（"+formatAbsence(a, day)+"）")
+		}
+		header := day.Format("2006-01-02") + "(" + weekdayNames["ja"][day.Weekday()] + ")"
+		if len(names) == 0 {
+			fmt.Fprintf(w, "%s: 不在の人はいません\n", header)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s\n", header, strings.Join(names, "、"))
+	}
+}
+
+// day のうち不在の時間帯（1日を通して不在なら「終日」）
+func formatAbsence(a absence, day time.Time) string {
+	next := day.AddDate(0, 0, 1)
+	if a.allDay || (!a.interval.start.After(day) && !a.interval.end.Before(next)) {
+		return "終日"
+	}
+	switch {
+	case a.interval.start.After(day) && a.interval.end.Before(next):
+		return a.interval.start.Format("15:04") + "-" + a.interval.end.Format("15:04")
+	case a.interval.start.After(day):
+		return a.interval.start.Format("15:04") + "から"
+	default:
+		return a.interval.end.Format("15:04") + "まで"
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"gcal-daily-agenda/schema"
+)
+
+// 予定を取得できなかったカレンダー
+type calendarError = schema.CalendarError
+
+// カレンダーごとに fetch を呼び出す
+//
+// 権限がなくなったり削除されたりしたカレンダーがあっても残りのカレンダーの予定は表示できるよう、
+// 失敗したカレンダーは飛ばして記録する。認証のエラーと、すべてのカレンダーが失敗した場合はエラーを返す。
+func eachCalendar(calendarIDs []string, fetch func(id string) error) ([]calendarError, error) {
+	var errs []calendarError
+	var first error
+	for _, id := range calendarIDs {
+		err := fetch(id)
+		if err == nil {
+			continue
+		}
+		if isAuthError(err) {
+			return nil, err
+		}
+		slog.Warn("Skipping calendar", "calendar", id, "error", err)
+		errs = append(errs, calendarError{Calendar: id, Error: err.Error()})
+		if first == nil {
+			first = err
+		}
+	}
+	if len(calendarIDs) > 0 && len(errs) == len(calendarIDs) {
+		return nil, first
+	}
+	return errs, nil
+}
+
+// 取得できなかったカレンダーがあることを予定の前に表示する
+func printCalendarErrors(w io.Writer, errs []calendarError) {
+	if len(errs) == 0 {
+		return
+	}
+	ids := make([]string, len(errs))
+	for i, e := range errs {
+		ids[i] = e.Calendar
+	}
+	fmt.Fprintf(w, "⚠ 次のカレンダーは取得できなかったため含まれていません: %s\n", strings.Join(ids, "、"))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 会う人と、その人と一緒の予定の件数
+type person struct {
+	name     string
+	email    string
+	meetings int
+}
+
+// その日（または週）の予定で会う人を、一緒の予定が多い順に表示する
+func setupPeople(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to look at (format: YYYY-MM-DD, default today)")
+	week := fs.Bool("week", false, "Look at the whole week (Monday to Sunday) containing --date")
+	asCSV := fs.Bool("csv", false, "Print name, email and number of meetings as CSV")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		from, to := date, date
+		if *week {
+			from, to = weekRange(date)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		events, err := fetchRange(ctx, client, from, to)
+		if err != nil {
+			return err
+		}
+		// 期間の各日に表示される予定（日をまたぐ予定は1回だけ数える）
+		var items []*calendar.Event
+		seen := map[string]bool{}
+		for _, day := range daysBetween(from, to) {
+			for _, item := range eventsForDay(events.Items, day) {
+				if !seen[item.Id] {
+					seen[item.Id] = true
+					items = append(items, item)
+				}
+			}
+		}
+		people := meetingPeople(items)
+		if *asCSV {
+			return writePeopleCSV(os.Stdout, people)
+		}
+		printPeople(os.Stdout, from, to, people)
+		return nil
+	}
+}
+
+// 自分と会議室を除いた参加者を集計する（欠席した予定と、相手が欠席した予定は数えない）
+func meetingPeople(items []*calendar.Event) []person {
+	byEmail := map[string]*person{}
+	for _, item := range items {
+		if attendanceStatus(item) == "declined" {
+			continue
+		}
+		for _, a := range item.Attendees {
+			if a.Self || a.Resource || a.Email == "" || a.ResponseStatus == "declined" {
+				continue
+			}
+			key := strings.ToLower(a.Email)
+			p, ok := byEmail[key]
+			if !ok {
+				p = &person{email: a.Email}
+				byEmail[key] = p
+			}
+			if p.name == "" {
+				p.name = a.DisplayName
+			}
+			p.meetings++
+		}
+	}
+	out := make([]person, 0, len(byEmail))
+	for _, p := range byEmail {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].meetings != out[j].meetings {
+			return out[i].meetings > out[j].meetings
+		}
+		return strings.ToLower(out[i].email) < strings.ToLower(out[j].email)
+	})
+	return out
+}
+
+func printPeople(w io.Writer, from, to time.Time, people []person) {
+	period := from.Format("2006-01-02")
+	if !to.Equal(from) {
+		period += "〜" + to.Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "%sに会う人:\n", period)
+	if len(people) == 0 {
+		fmt.Fprintln(w, "  いません")
+		return
+	}
+	for _, p := range people {
+		if p.name != "" {
+			fmt.Fprintf(w, "  %d件 %s <%s>\n", p.meetings, p.name, p.email)
+		} else {
+			fmt.Fprintf(w, "  %d件 %s\n", p.meetings, p.email)
+		}
+	}
+}
+
+func writePeopleCSV(w io.Writer, people []person) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "email", "meetings"})
+	for _, p := range people {
+		cw.Write([]string{p.name, p.email, strconv.Itoa(p.meetings)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
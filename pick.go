@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// pick で選んだ予定に対する操作
+var pickActions = []struct {
+	name, key, label string
+}{
+	{"open", "o", "開く"},
+	{"join", "j", "参加"},
+	{"copy", "c", "リンクをコピー"},
+	{"accept", "a", "出席"},
+	{"decline", "d", "欠席"},
+	{"tentative", "t", "未定"},
+}
+
+// 一度に表示する候補の数
+const pickMaxCandidates = 10
+
+// その日（または週）の予定をあいまい検索で選び、開く・参加・リンクのコピー・出欠の返事をする
+func setupPick(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Date to pick events from (format: YYYY-MM-DD)")
+	week := fs.Bool("week", false, "Pick from the whole week containing the date")
+	action := fs.String("action", "", "Action for the picked event: open, join, copy, accept, decline or tentative (default: ask)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if *action != "" && pickActionName(*action) == "" {
+			return fmt.Errorf("Unknown action %q (use open, join, copy, accept, decline or tentative)", *action)
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		from, to := date, date
+		if *week {
+			from, to = weekRange(date)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		events, err := fetchRange(ctx, client, from, to)
+		if err != nil {
+			return err
+		}
+		var candidates []agendaEvent
+		for _, day := range daysBetween(from, to) {
+			for _, e := range selectDay(normalizeEvents("primary", events.Items), day) {
+				if e.item.Status != "cancelled" {
+					candidates = append(candidates, e)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			return errNoEvents
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		picked, ok := pickEvent(in, os.Stderr, candidates, strings.Join(args, " "))
+		if !ok {
+			return fmt.Errorf("Cancelled")
+		}
+		name := pickActionName(*action)
+		if name == "" {
+			if name = askPickAction(in, os.Stderr); name == "" {
+				return fmt.Errorf("Cancelled")
+			}
+		}
+		// 書き込みの権限は、出欠の返事をすると決まってから求める
+		var writer calendarWriter
+		if _, rsvp := inviteResponses[name]; rsvp {
+			if writer, err = newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope); err != nil {
+				return err
+			}
+		}
+		return runPickAction(ctx, writer, picked, name)
+	}
+}
+
+// 候補の表示（週の予定から選ぶときのために日付を付ける）
+func pickLabel(e agendaEvent) string {
+	return e.start.Format("01/02 ") + formatEvent(e.item)
+}
+
+// 検索語で候補を絞り込み、番号で選ぶ
+//
+// 文字を入力すると検索語を置き換え、番号を入力するとその候補を、空行で先頭の候補を選ぶ。
+// 入力が終わる（Ctrl-D）と選ばずに戻る。
+func pickEvent(in *bufio.Reader, w io.Writer, candidates []agendaEvent, query string) (agendaEvent, bool) {
+	for {
+		matches := fuzzyFilter(candidates, query)
+		if len(matches) == 1 && query != "" {
+			return matches[0], true
+		}
+		for i, e := range matches {
+			if i == pickMaxCandidates {
+				fmt.Fprintf(w, "  …ほか%d件\n", len(matches)-i)
+				break
+			}
+			fmt.Fprintf(w, "%2d) %s\n", i+1, pickLabel(e))
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(w, "「%s」に一致する予定はありません\n", query)
+		}
+		fmt.Fprint(w, "検索> ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(w)
+			return agendaEvent{}, false
+		}
+		line = strings.TrimSpace(line)
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(matches) && n <= pickMaxCandidates {
+			return matches[n-1], true
+		}
+		if line == "" && len(matches) > 0 {
+			return matches[0], true
+		}
+		query = line
+	}
+}
+
+// query の文字を順に含む候補を、よく一致する順に返す（query が空なら元の順）
+func fuzzyFilter(candidates []agendaEvent, query string) []agendaEvent {
+	if query == "" {
+		return candidates
+	}
+	type scored struct {
+		e     agendaEvent
+		score int
+	}
+	var out []scored
+	for _, e := range candidates {
+		if s := fuzzyScore(query, pickLabel(e)); s >= 0 {
+			out = append(out, scored{e, s})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].score > out[j].score })
+	events := make([]agendaEvent, len(out))
+	for i, s := range out {
+		events[i] = s.e
+	}
+	return events
+}
+
+// query の文字（空白は無視）が text にこの順で含まれていればその点数、含まれていなければ -1
+//
+// 連続して一致した文字と、単語の先頭で一致した文字を高く数える（大文字と小文字は区別しない）。
+func fuzzyScore(query, text string) int {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	t := []rune(strings.ToLower(text))
+	score, qi, prev := 0, 0, -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+		score++
+		if ti == prev+1 {
+			score += 2
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score++
+		}
+		prev = ti
+		qi++
+	}
+	if qi < len(q) {
+		return -1
+	}
+	return score
+}
+
+// 操作の名前かキー（open または o など）を操作の名前にする（不明なら ""）
+func pickActionName(s string) string {
+	for _, a := range pickActions {
+		if s == a.name || s == a.key {
+			return a.name
+		}
+	}
+	return ""
+}
+
+// 選んだ予定に対する操作を尋ねる（入力が終わったら ""）
+func askPickAction(in *bufio.Reader, w io.Writer) string {
+	var choices []string
+	for _, a := range pickActions {
+		choices = append(choices, fmt.Sprintf("[%s]%s", a.key, a.label))
+	}
+	for {
+		fmt.Fprintf(w, "操作 %s: ", strings.Join(choices, " "))
+		line, err := in.ReadString('\n')
+		if name := pickActionName(strings.ToLower(strings.TrimSpace(line))); name != "" {
+			return name
+		}
+		if err != nil {
+			fmt.Fprintln(w)
+			return ""
+		}
+	}
+}
+
+// 選んだ予定に操作を行う（client は出欠の返事にだけ使い、それ以外の操作では nil でよい）
+func runPickAction(ctx context.Context, client calendarWriter, e agendaEvent, action string) error {
+	item := e.item
+	switch action {
+	case "open":
+		if item.HtmlLink == "" {
+			return fmt.Errorf("%q has no link to open", item.Summary)
+		}
+		return openBrowser(item.HtmlLink)
+	case "join":
+		link := conferenceURL(item)
+		if link == "" {
+			return fmt.Errorf("%q has no conference link", item.Summary)
+		}
+		fmt.Fprintf(os.Stderr, "%s %s\n", item.Summary, link)
+		return openBrowser(link)
+	case "copy":
+		// 会議リンクがなければ予定のページのリンク
+		link := conferenceURL(item)
+		if link == "" {
+			link = item.HtmlLink
+		}
+		if link == "" {
+			return fmt.Errorf("%q has no link to copy", item.Summary)
+		}
+		if err := copyToClipboard(link); err != nil {
+			return err
+		}
+		fmt.Printf("コピーしました: %s\n", link)
+		return nil
+	}
+
+	response := inviteResponses[action]
+	if attendanceStatus(item) == "" {
+		return fmt.Errorf("%q has no invitation to respond to", item.Summary)
+	}
+	inv := pendingInvite{calendarID: e.calendarID, item: item, start: e.start}
+	if err := respondToInvite(ctx, client, inv, response); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", attendanceLabels[response], formatPendingInvite(inv))
+	return nil
+}
+
+// OS のクリップボードに text をコピーする
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("pbcopy")
+	case runtime.GOOS == "windows":
+		cmd = exec.Command("clip")
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		cmd = exec.Command("wl-copy")
+	default:
+		cmd = exec.Command("xclip", "-selection", "clipboard")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Unable to copy to clipboard: %w", err)
+	}
+	return nil
+}
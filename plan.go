@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// このツールで作成した予定に付ける非公開の拡張プロパティ（値は作成元を表すキー）
+//
+// 同じ計画やテンプレートを適用し直したときに、作成済みの予定を見分けるために使う。
+const createdProperty = "gcalDailyAgendaCreated"
+
+// 作成する予定の指定
+type eventSpec struct {
+	// HH:MM
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Summary string `json:"summary"`
+	// "focus" のように # を付けずに書く。説明欄に "#focus" として入れる
+	Tags    []string `json:"tags,omitempty"`
+	ColorID string   `json:"color,omitempty"`
+}
+
+// 同じ予定を見分けるキー
+func (s eventSpec) key() string {
+	return s.Start + "-" + s.End + " " + s.Summary
+}
+
+func (s eventSpec) String() string {
+	out := s.key()
+	for _, t := range s.Tags {
+		out += " #" + t
+	}
+	return out
+}
+
+// day の予定として作成する内容（day は 0 時の時刻で、そのタイムゾーンで作成する）
+func (s eventSpec) event(day time.Time, source string) (*calendar.Event, error) {
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("End must be after start: %s", s.key())
+	}
+	e := &calendar.Event{
+		Summary: s.Summary,
+		ColorId: s.ColorID,
+		Start:   &calendar.EventDateTime{DateTime: atClock(day, start).Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: atClock(day, end).Format(time.RFC3339)},
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{createdProperty: source + "/" + s.key()},
+		},
+	}
+	if len(s.Tags) > 0 {
+		tags := make([]string, len(s.Tags))
+		for i, t := range s.Tags {
+			tags[i] = "#" + t
+		}
+		e.Description = strings.Join(tags, " ")
+	}
+	return e, nil
+}
+
+// 計画のファイルの1行（"09:00-10:30 Deep work #focus"）
+var planLinePattern = regexp.MustCompile(`^(\d{1,2}:\d{2})\s*-\s*(\d{1,2}:\d{2})\s+(.+)$`)
+
+// 予定の出力の1行（"【緑】朝会 (09:00-09:30)"、テーマの記号が前に付いた "▲【緑】..." も）。出力をそのまま計画として読み込めるようにする
+var agendaLinePattern = regexp.MustCompile(`^[^\s【]?【(.+?)】(.+) \((\d{1,2}:\d{2})-(\d{1,2}:\d{2})\)$`)
+
+func normalizeClock(s string) string {
+	if len(s) == len("9:00") {
+		return "0" + s
+	}
+	return s
+}
+
+// 計画のファイルを読み込む（空行と # で始まる行は無視する）
+//
+// "@2" のようにカラーIDを書くと色を付ける。予定の出力（【色】件名 (開始-終了)）もそのまま読み込める。
+func parsePlan(r io.Reader) ([]eventSpec, error) {
+	var specs []eventSpec
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") ||
+			strings.HasSuffix(line, "の予定:") || strings.HasSuffix(line, "の予定はありません。") ||
+			strings.HasSuffix(line, "(終日)") {
+			continue
+		}
+		if m := agendaLinePattern.FindStringSubmatch(line); m != nil {
+			line = m[3] + "-" + m[4] + " " + m[2]
+			if id := colorID(m[1]); id != "" {
+				line += " @" + id
+			}
+		}
+		m := planLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("Invalid plan at line %d: %q (expected \"HH:MM-HH:MM title\")", n, line)
+		}
+		// キーが書き方で変わらないよう "9:00" は "09:00" にそろえる
+		spec := eventSpec{Start: normalizeClock(m[1]), End: normalizeClock(m[2])}
+		var words []string
+		for _, w := range strings.Fields(m[3]) {
+			if tag, ok := strings.CutPrefix(w, "#"); ok && tag != "" {
+				spec.Tags = append(spec.Tags, tag)
+				continue
+			}
+			if id, ok := strings.CutPrefix(w, "@"); ok && colorNames[id] != "" {
+				spec.ColorID = id
+				continue
+			}
+			words = append(words, w)
+		}
+		spec.Summary = strings.Join(words, " ")
+		if _, err := spec.event(time.Time{}, ""); err != nil {
+			return nil, fmt.Errorf("Invalid plan at line %d: %w", n, err)
+		}
+		specs = append(specs, spec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read plan: %w", err)
+	}
+	return specs, nil
+}
+
+// テキストの計画から1日分の予定を作成する（plan apply）
+func setupPlan(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to create the events on (format: YYYY-MM-DD, default today)")
+	calendarID := fs.String("calendar", "primary", "Calendar to create the events on")
+	dryRun := fs.Bool("dry-run", false, "Print the changes without applying them")
+	prune := fs.Bool("prune", false, "Delete events created from an earlier plan for the day that are no longer in the plan")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 2 || args[0] != "apply" {
+			return fmt.Errorf("Usage: %s plan apply <plan.txt|-> [--date YYYY-MM-DD] [--dry-run]", progName)
+		}
+		in := os.Stdin
+		if args[1] != "-" {
+			f, err := os.Open(args[1])
+			if err != nil {
+				return fmt.Errorf("Unable to read plan: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		specs, err := parsePlan(in)
+		if err != nil {
+			return err
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		res, err := applySpecs(ctx, client, os.Stdout, *calendarID, date, specs, applyOptions{source: "plan", dryRun: *dryRun, prune: *prune})
+		if err != nil {
+			return err
+		}
+		res.print(os.Stdout)
+		return nil
+	}
+}
+
+type applyOptions struct {
+	// 作成元（"plan" や "template/名前"）。適用し直したときに作成済みの予定を見分けるのに使う
+	source string
+	dryRun bool
+	// 同じ作成元から作成した予定のうち、specs にないものを削除する
+	prune bool
+}
+
+type applyResult struct {
+	created, existing, deleted int
+}
+
+func (r applyResult) print(w io.Writer) {
+	fmt.Fprintf(w, "作成 %d件、作成済み %d件", r.created, r.existing)
+	if r.deleted > 0 {
+		fmt.Fprintf(w, "、削除 %d件", r.deleted)
+	}
+	fmt.Fprintln(w)
+}
+
+// specs の予定を date の日に作成する
+//
+// 同じ作成元から作成済みの予定と、件名と開始時刻が同じ予定はすでにあるものとして作成しない。
+func applySpecs(ctx context.Context, client calendarWriter, w io.Writer, calendarID string, date time.Time, specs []eventSpec, opts applyOptions) (applyResult, error) {
+	var res applyResult
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	events, err := client.ListEvents(ctx, calendarID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return res, err
+	}
+
+	created := map[string]*calendar.Event{}
+	existing := map[string]bool{}
+	prefix := opts.source + "/"
+	for _, item := range events.Items {
+		if item.Status == "cancelled" {
+			continue
+		}
+		if key, ok := strings.CutPrefix(createdKey(item), prefix); ok {
+			created[key] = item
+		}
+		if item.Start.DateTime != "" {
+			start, _ := eventTimes(item)
+			existing[start.In(time.Local).Format("15:04")+" "+item.Summary] = true
+		}
+	}
+
+	want := map[string]bool{}
+	for _, spec := range specs {
+		want[spec.key()] = true
+		event, err := spec.event(day, opts.source)
+		if err != nil {
+			return res, err
+		}
+		start, _ := time.Parse(time.RFC3339, event.Start.DateTime)
+		if created[spec.key()] != nil || existing[start.Format("15:04")+" "+spec.Summary] {
+			fmt.Fprintf(w, "作成済み: %s\n", spec)
+			res.existing++
+			continue
+		}
+		fmt.Fprintf(w, "作成: %s\n", spec)
+		res.created++
+		if !opts.dryRun {
+			if _, err := client.InsertEvent(ctx, calendarID, event); err != nil {
+				return res, err
+			}
+		}
+		existing[start.Format("15:04")+" "+spec.Summary] = true
+	}
+
+	if opts.prune {
+		keys := make([]string, 0, len(created))
+		for key := range created {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if want[key] {
+				continue
+			}
+			item := created[key]
+			fmt.Fprintf(w, "削除: %s\n", key)
+			res.deleted++
+			if !opts.dryRun {
+				if err := client.DeleteEvent(ctx, calendarID, item.Id); err != nil {
+					return res, err
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// 色名からカラーIDを返す（デフォルトや不明な色は空）
+func colorID(name string) string {
+	for i := 1; i <= len(colorNames); i++ {
+		id := strconv.Itoa(i)
+		if colorNames[id] == name {
+			return id
+		}
+	}
+	return ""
+}
+
+// このツールで作成した予定であれば作成元を表すキーを返す
+func createdKey(item *calendar.Event) string {
+	if item.ExtendedProperties == nil {
+		return ""
+	}
+	return item.ExtendedProperties.Private[createdProperty]
+}
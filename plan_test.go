@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 読み込んだ計画を計画の書き方に戻して読み込み直すと、同じ計画になる
+func FuzzParsePlan(f *testing.F) {
+	for _, s := range []string{
+		"09:00-10:30 Deep work #focus",
+		"# 朝の計画\n\n9:00 - 9:30 朝会 @2\n10:00-12:00 設計 #design #wip @5\n",
+		"2024-06-14 (金)の予定:\n【緑】朝会 (09:00-09:30)\n▲【黄】設計レビュー (10:00-11:00)\n【デフォルト】研修 (終日)\n",
+		"10:00-09:00 逆順",
+		"09:00-09:00 長さなし",
+		"25:00-26:00 範囲外",
+		"09:00-10:00 #",
+		"09:00-10:00 @99 色なし",
+		"09:00-10:00\tタブ区切り\r\n",
+		"予定",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		specs, err := parsePlan(strings.NewReader(s))
+		if err != nil {
+			return
+		}
+		var lines []string
+		for _, spec := range specs {
+			if len(spec.Start) != len("09:00") || len(spec.End) != len("09:00") {
+				t.Errorf("parsePlan(%q) did not normalize %s", s, spec)
+			}
+			line := spec.String()
+			if spec.ColorID != "" {
+				line += " @" + spec.ColorID
+			}
+			lines = append(lines, line)
+		}
+		again, err := parsePlan(strings.NewReader(strings.Join(lines, "\n")))
+		if err != nil {
+			t.Fatalf("parsePlan(%q) = %v, but reading it back failed: %v", s, specs, err)
+		}
+		if !slices.EqualFunc(again, specs, func(a, b eventSpec) bool {
+			return a.key() == b.key() && slices.Equal(a.Tags, b.Tags) && a.ColorID == b.ColorID
+		}) {
+			t.Errorf("parsePlan(%q) = %v, read back as %v", s, specs, again)
+		}
+	})
+}
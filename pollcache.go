@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// 全件を取り直す間隔
+const fullRefreshInterval = time.Hour
+
+// 取得する期間の余裕（期間が少しずつ進んでも取り直さずに済むようにする）
+const windowSlack = 24 * time.Hour
+
+// 常駐モードで同じ期間を繰り返し取得するときに、変更分だけを取得するクライアント
+//
+// 最初に期間全体を取得したあとは、updatedMin を付けて前回の全件取得以降に更新された予定だけを問い合わせる。
+// 問い合わせ内容は全件を取り直すまで変わらないため、ETag（If-None-Match）を付けておけば
+// カレンダーに変更がない間は 304 Not Modified が返り、ほとんど転送が発生しない。
+type pollingClient struct {
+	*googleClient
+
+	mu      sync.Mutex
+	windows map[string]*cachedWindow
+}
+
+// カレンダーごとのキャッシュ
+type cachedWindow struct {
+	timeMin, timeMax time.Time
+	fetchedAt        time.Time
+	etag             string
+	meta             *calendar.Events
+	events           map[string]*calendar.Event
+}
+
+func newPollingClient(g *googleClient) *pollingClient {
+	return &pollingClient{googleClient: g, windows: map[string]*cachedWindow{}}
+}
+
+// 常駐モード用のクライアントを作成する（フィクスチャの場合はそのまま使う）
+func newDaemonClient(ctx context.Context, fromFixture, scope string) (calendarWriter, error) {
+	client, err := newWriteClient(ctx, fromFixture, scope)
+	if err != nil {
+		return nil, err
+	}
+	switch c := client.(type) {
+	case *googleClient:
+		return newPollingClient(c), nil
+	case *auditClient:
+		if g, ok := c.calendarWriter.(*googleClient); ok {
+			c.calendarWriter = newPollingClient(g)
+		}
+	}
+	return client, nil
+}
+
+func (c *pollingClient) ListEvents(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*calendar.Events, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.windows[calendarID]
+	now := time.Now()
+	if w == nil || timeMin.Before(w.timeMin) || timeMax.After(w.timeMax) || now.Sub(w.fetchedAt) > fullRefreshInterval {
+		var err error
+		if w, err = c.fetchWindow(ctx, calendarID, timeMin, timeMax.Add(windowSlack)); err != nil {
+			return nil, err
+		}
+		c.windows[calendarID] = w
+	} else if err := c.fetchChanges(ctx, calendarID, w); err != nil {
+		return nil, err
+	}
+	return w.slice(timeMin, timeMax), nil
+}
+
+// 期間全体を取得する
+func (c *pollingClient) fetchWindow(ctx context.Context, calendarID string, timeMin, timeMax time.Time) (*cachedWindow, error) {
+	fetchedAt := time.Now()
+	events, err := c.googleClient.ListEvents(ctx, calendarID, timeMin, timeMax)
+	if err != nil {
+		return nil, err
+	}
+	w := &cachedWindow{
+		timeMin:   timeMin,
+		timeMax:   timeMax,
+		fetchedAt: fetchedAt,
+		meta:      events,
+		events:    map[string]*calendar.Event{},
+	}
+	for _, item := range events.Items {
+		w.events[item.Id] = item
+	}
+	return w, nil
+}
+
+// 前回の全件取得以降に更新された予定を取得してキャッシュに反映する
+func (c *pollingClient) fetchChanges(ctx context.Context, calendarID string, w *cachedWindow) error {
+	var changes []*calendar.Event
+	etag := ""
+	for pageToken := ""; ; {
+		call := c.srv.Events.List(calendarID).
+			ShowDeleted(true).
+			SingleEvents(true).
+			TimeMin(w.timeMin.Format(time.RFC3339)).
+			TimeMax(w.timeMax.Format(time.RFC3339)).
+			UpdatedMin(w.fetchedAt.Add(-time.Minute).UTC().Format(time.RFC3339)).
+			PageToken(pageToken).
+			Context(ctx)
+		if pageToken == "" {
+			// 変更の有無は最初のページの ETag で確かめる
+			call.IfNoneMatch(w.etag)
+		}
+		var page *calendar.Events
+		err := withRetry(ctx, func() error {
+			var err error
+			page, err = call.Do()
+			return err
+		})
+		if pageToken == "" && googleapi.IsNotModified(err) {
+			apiUsage.cacheHits.Add(1)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to retrieve events: %w", err)
+		}
+		if pageToken == "" {
+			etag = page.Etag
+		}
+		changes = append(changes, page.Items...)
+		if pageToken = page.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	w.etag = etag
+	for _, item := range changes {
+		if item.Status == "cancelled" {
+			delete(w.events, item.Id)
+			continue
+		}
+		// 期間の外に移動した予定はキャッシュから外す
+		if start, end := eventTimes(item); !start.Before(w.timeMax) || !end.After(w.timeMin) {
+			delete(w.events, item.Id)
+			continue
+		}
+		w.events[item.Id] = item
+	}
+	return nil
+}
+
+// キャッシュから timeMin から timeMax までの予定を開始時刻順に返す
+func (w *cachedWindow) slice(timeMin, timeMax time.Time) *calendar.Events {
+	out := *w.meta
+	out.Items = nil
+	for _, item := range w.events {
+		start, end := eventTimes(item)
+		if start.Before(timeMax) && end.After(timeMin) {
+			out.Items = append(out.Items, item)
+		}
+	}
+	sort.SliceStable(out.Items, func(i, j int) bool {
+		si, _ := eventTimes(out.Items[i])
+		sj, _ := eventTimes(out.Items[j])
+		return si.Before(sj)
+	})
+	return &out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// pageToken ごとに pages のレスポンスを返すサーバーに接続した googleClient
+func newPagedGoogleClient(t *testing.T, pages map[string]*calendar.Events) *googleClient {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Query().Get("pageToken")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(ts.Close)
+	srv, err := calendar.NewService(context.Background(), option.WithHTTPClient(ts.Client()), option.WithEndpoint(ts.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &googleClient{srv: srv}
+}
+
+func timedEvent(id, start, end string) *calendar.Event {
+	return &calendar.Event{
+		Id:    id,
+		Start: &calendar.EventDateTime{DateTime: start},
+		End:   &calendar.EventDateTime{DateTime: end},
+	}
+}
+
+// 変更はすべてのページを反映し、期間の外に移動した予定はキャッシュから外す
+func TestFetchChanges(t *testing.T) {
+	cancelled := timedEvent("cancelled", "2024-06-14T13:00:00+09:00", "2024-06-14T14:00:00+09:00")
+	cancelled.Status = "cancelled"
+	client := newPollingClient(newPagedGoogleClient(t, map[string]*calendar.Events{
+		"": {
+			Etag:          `"1"`,
+			Items:         []*calendar.Event{timedEvent("moved", "2024-06-20T10:00:00+09:00", "2024-06-20T11:00:00+09:00")},
+			NextPageToken: "2",
+		},
+		"2": {
+			Items: []*calendar.Event{cancelled, timedEvent("added", "2024-06-14T15:00:00+09:00", "2024-06-14T16:00:00+09:00")},
+		},
+	}))
+	w := &cachedWindow{
+		timeMin:   date(2024, 6, 13),
+		timeMax:   date(2024, 6, 16),
+		fetchedAt: time.Now(),
+		meta:      &calendar.Events{},
+		events: map[string]*calendar.Event{
+			"moved":     timedEvent("moved", "2024-06-14T10:00:00+09:00", "2024-06-14T11:00:00+09:00"),
+			"cancelled": timedEvent("cancelled", "2024-06-14T13:00:00+09:00", "2024-06-14T14:00:00+09:00"),
+			"kept":      timedEvent("kept", "2024-06-14T09:00:00+09:00", "2024-06-14T09:30:00+09:00"),
+		},
+	}
+	if err := client.fetchChanges(context.Background(), "primary", w); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for id := range w.events {
+		got = append(got, id)
+	}
+	sort.Strings(got)
+	if want := []string{"added", "kept"}; !slices.Equal(got, want) {
+		t.Errorf("cached events = %v, want %v", got, want)
+	}
+	if w.etag != `"1"` {
+		t.Errorf("etag = %q, want the first page's %q", w.etag, `"1"`)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 通知を送らない時間帯を設定できる通知の方法（デスクトップの通知と Webhook）
+var quietNotifiers = []string{"reminders", "webhook"}
+
+// daemon と remind で通知を送らない時間帯の設定
+//
+// notifiers に通知の方法ごとの設定を書くと、書いた項目だけを上書きする。
+type quietConfig struct {
+	quietRule
+	Notifiers map[string]quietRule `json:"notifiers,omitempty"`
+}
+
+type quietRule struct {
+	// 通知しない時間帯（"22:00-08:00" のように書き、日をまたいでもよい。"" は制限なし）
+	Hours *string `json:"hours,omitempty"`
+	// 土日に通知しない
+	Weekends *bool `json:"weekends,omitempty"`
+	// 祝日（holidays.calendar の祝日）に通知しない
+	Holidays *bool `json:"holidays,omitempty"`
+}
+
+// 通知の方法ごとに解決した、通知しない時間帯
+type quietWindow struct {
+	hours    bool
+	from, to time.Duration
+	weekends bool
+	holidays bool
+}
+
+// 通知を送ってよいかを判定する（設定がなければ nil で、常に送る）
+type quietGuard struct {
+	windows  map[string]quietWindow
+	calendar holidaysConfig
+	// 取得した祝日と、取得した日（日が変わったら取得し直す）
+	holidays   map[string]string
+	fetchedDay string
+}
+
+// 設定から quietGuard を作成する（どの通知の方法にも制限がなければ nil）
+func newQuietGuard(cfg quietConfig, h holidaysConfig) (*quietGuard, error) {
+	for name := range cfg.Notifiers {
+		if !slices.Contains(quietNotifiers, name) {
+			return nil, fmt.Errorf("Unknown notifier %q in quiet.notifiers (expected %s)", name, strings.Join(quietNotifiers, ", "))
+		}
+	}
+	g := &quietGuard{windows: map[string]quietWindow{}, calendar: h}
+	for _, name := range quietNotifiers {
+		rule := cfg.quietRule
+		if o, ok := cfg.Notifiers[name]; ok {
+			if o.Hours != nil {
+				rule.Hours = o.Hours
+			}
+			if o.Weekends != nil {
+				rule.Weekends = o.Weekends
+			}
+			if o.Holidays != nil {
+				rule.Holidays = o.Holidays
+			}
+		}
+		var w quietWindow
+		if rule.Hours != nil && *rule.Hours != "" {
+			var err error
+			if w.from, w.to, err = parseQuietHours(*rule.Hours); err != nil {
+				return nil, err
+			}
+			w.hours = true
+		}
+		w.weekends = rule.Weekends != nil && *rule.Weekends
+		w.holidays = rule.Holidays != nil && *rule.Holidays
+		if w != (quietWindow{}) {
+			g.windows[name] = w
+		}
+	}
+	if len(g.windows) == 0 {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// "22:00-08:00" を解釈する
+func parseQuietHours(s string) (time.Duration, time.Duration, error) {
+	fromStr, toStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("Invalid quiet hours %q. Please use HH:MM-HH:MM format", s)
+	}
+	from, err := parseClock(strings.TrimSpace(fromStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid quiet hours %q: %w", s, err)
+	}
+	to, err := parseClock(strings.TrimSpace(toStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid quiet hours %q: %w", s, err)
+	}
+	if from == to {
+		return 0, 0, fmt.Errorf("Invalid quiet hours %q: the start and end must differ", s)
+	}
+	return from, to, nil
+}
+
+// notifier で now に通知を送ってよいか
+//
+// 祝日を取得できなかった場合は、通知を失わないよう祝日ではないものとして扱う。
+func (g *quietGuard) allows(ctx context.Context, client calendarClient, notifier string, now time.Time) bool {
+	if g == nil {
+		return true
+	}
+	w, ok := g.windows[notifier]
+	if !ok {
+		return true
+	}
+	if w.hours {
+		clock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+		if w.from < w.to && w.from <= clock && clock < w.to {
+			return false
+		}
+		if w.from > w.to && (clock >= w.from || clock < w.to) {
+			return false
+		}
+	}
+	if w.weekends && !isBusinessDay(now) {
+		return false
+	}
+	if w.holidays {
+		day := now.Format("2006-01-02")
+		if g.fetchedDay != day {
+			start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			holidays, err := fetchHolidays(ctx, client, g.calendar, start, start.AddDate(0, 0, 1))
+			if err != nil {
+				slog.Warn("Unable to check holidays for quiet hours", "error", err)
+				return true
+			}
+			g.holidays, g.fetchedDay = holidays, day
+		}
+		if _, ok := g.holidays[day]; ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// --debug-quota で表示する API の使用状況（プロセス全体の合計）
+var apiUsage struct {
+	calls     atomic.Int64
+	sent      atomic.Int64
+	received  atomic.Int64
+	retries   atomic.Int64
+	cacheHits atomic.Int64
+}
+
+// Google API へのリクエストの送信量を数え、レスポンスの本文は読まれた分を受信量として数える
+//
+// Webhook など Google 以外への通信は数えない。
+func recordAPIUsage(req *http.Request, resp *http.Response) {
+	if !strings.HasSuffix(req.URL.Hostname(), "googleapis.com") {
+		return
+	}
+	apiUsage.calls.Add(1)
+	if req.ContentLength > 0 {
+		apiUsage.sent.Add(req.ContentLength)
+	}
+	if resp != nil && resp.Body != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body}
+	}
+}
+
+type countingBody struct {
+	io.ReadCloser
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	apiUsage.received.Add(int64(n))
+	return n, err
+}
+
+// "API 呼び出し 12回、送信 1.2KB、受信 34.5KB、再試行 0回、キャッシュヒット 3回"
+func printAPIUsage(w io.Writer) {
+	fmt.Fprintf(w, "API 呼び出し %d回、送信 %s、受信 %s、再試行 %d回、キャッシュヒット %d回\n",
+		apiUsage.calls.Load(), formatBytes(apiUsage.sent.Load()), formatBytes(apiUsage.received.Load()),
+		apiUsage.retries.Load(), apiUsage.cacheHits.Load())
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
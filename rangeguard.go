@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 確認なしで取得する期間の日数（設定ファイルの max_range_days の既定値）
+const defaultMaxRangeDays = 90
+
+// 長い期間の予定を何回かに分けて取得するときの、1回に取得する日数
+//
+// 期間全体の予定をメモリに載せないよう、取得した分を出力してから次を取得する。
+const rangeChunkDays = 31
+
+// 確認なしで取得する期間の日数（負の値は制限なし）
+func (c *config) maxRangeDays() int {
+	if c.MaxRangeDays == 0 {
+		return defaultMaxRangeDays
+	}
+	return c.MaxRangeDays
+}
+
+// from から to までの日数（両端を含む）
+func rangeDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours()/24) + 1
+}
+
+// 期間が limit 日を超える場合は、--yes がなければ端末で確認する（端末でなければエラー）
+func confirmRange(from, to time.Time, limit int, yes bool) error {
+	n := rangeDays(from, to)
+	if yes || limit < 0 || n <= limit {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("The range of %d days exceeds max_range_days (%d); pass --yes to continue", n, limit)
+	}
+	fmt.Fprintf(os.Stderr, "%s〜%s の%d日分の予定を取得します。続けますか？ [y/N]: ", from.Format("2006-01-02"), to.Format("2006-01-02"), n)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("Cancelled")
+}
+
+// 日付順の days を、最初の日から span 日以内の日ごとの組に分ける
+func chunkDays(days []time.Time, span int) [][]time.Time {
+	var chunks [][]time.Time
+	for len(days) > 0 {
+		n := 1
+		for n < len(days) && rangeDays(days[0], days[n]) <= span {
+			n++
+		}
+		chunks = append(chunks, days[:n])
+		days = days[n:]
+	}
+	return chunks
+}
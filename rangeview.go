@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 1週間（月曜始まり）の予定を表示する
+func setupWeek(fs *flag.FlagSet) func(args []string) error {
+	return setupRangeView(fs, weekRange, true)
+}
+
+// 1か月の予定を表示する
+func setupMonth(fs *flag.FlagSet) func(args []string) error {
+	return setupRangeView(fs, monthRange, false)
+}
+
+// date を含む週の月曜日から日曜日まで
+func weekRange(date time.Time) (time.Time, time.Time) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	offset := (int(day.Weekday()) + 6) % 7 // 月曜日からの日数
+	from := day.AddDate(0, 0, -offset)
+	return from, from.AddDate(0, 0, 6)
+}
+
+// date を含む月の1日から末日まで
+func monthRange(date time.Time) (time.Time, time.Time) {
+	from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	return from, from.AddDate(0, 1, -1)
+}
+
+// weekly の場合は --week で ISO 8601 の週を指定でき、見出しに週番号を付ける
+func setupRangeView(fs *flag.FlagSet, span func(time.Time) (time.Time, time.Time), weekly bool) func(args []string) error {
+	dateStr := fs.String("date", "", "Any date in the period to show (format: YYYY-MM-DD)")
+	weekStr := new(string)
+	if weekly {
+		fs.StringVar(weekStr, "week", "", "ISO 8601 week to show instead of --date (format: YYYY-Www, e.g. 2024-W25)")
+	}
+	fromFixture := fs.String("from-fixture", "", "Render events from a saved API response instead of calling Google")
+	opts := agendaOptions{}
+	fs.BoolVar(&opts.journal, "journal", false, "Print what actually happened (moved events and attendance) for past dates")
+	addDisplayFlags(fs, &opts)
+	return func(args []string) error {
+		if *weekStr != "" && *dateStr != "" {
+			return fmt.Errorf("--week and --date cannot be combined")
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		if *weekStr != "" {
+			if date, err = parseISOWeek(*weekStr); err != nil {
+				return err
+			}
+		}
+		from, to := span(date)
+		if err := opts.loadConfig(); err != nil {
+			return err
+		}
+		heading := fmt.Sprintf("%s〜%s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		if weekly {
+			heading += " (" + formatWeekNumber(from, opts.lang) + ")"
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		return runRangeView(ctx, client, os.Stdout, heading, from, to, opts)
+	}
+}
+
+func runRangeView(ctx context.Context, client calendarClient, w io.Writer, heading string, from, to time.Time, opts agendaOptions) error {
+	days := daysBetween(from, to)
+	events, err := fetchRange(ctx, client, from, to)
+	if err != nil {
+		return err
+	}
+	opts.defaultReminders = events.DefaultReminders
+	opts.archive(events, days)
+
+	fmt.Fprintln(w, heading)
+	for _, notice := range dstTransitions(from, to, eventZones(events.Items)) {
+		fmt.Fprintln(w, notice)
+	}
+	for _, out := range renderDays(events.Items, days, opts.print) {
+		fmt.Fprintln(w)
+		w.Write(out)
+	}
+	return nil
+}
+
+// 表示しているタイムゾーンと、予定が作成されたタイムゾーン
+func eventZones(items []*calendar.Event) []*time.Location {
+	zones := []*time.Location{time.Local}
+	for _, item := range items {
+		if item.Start == nil || item.Start.TimeZone == "" {
+			continue
+		}
+		if loc, err := loadLocation(item.Start.TimeZone); err == nil {
+			zones = append(zones, loc)
+		}
+	}
+	return zones
+}
+
+// from から to までの各日（両端を含む）
+func daysBetween(from, to time.Time) []time.Time {
+	var days []time.Time
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// 期間全体の予定を1回の呼び出しで取得する
+func fetchRange(ctx context.Context, client calendarClient, from, to time.Time) (*calendar.Events, error) {
+	startTime, _ := dayWindow(from)
+	_, endTime := dayWindow(to)
+	return client.ListEvents(ctx, "primary", startTime, endTime)
+}
+
+// 取得済みの予定を日ごとに振り分けて描画し、日付順の出力を返す
+//
+// 日ごとの振り分けと描画は互いに独立しているため、CPU の数だけ並行して行う。
+func renderDays(items []*calendar.Event, days []time.Time, render func(w io.Writer, day time.Time, events []agendaEvent)) [][]byte {
+	// 日時は期間全体で1回だけパースする
+	events := normalizeEvents("primary", items)
+	out := make([][]byte, len(days))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(days) {
+		workers = len(days)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var buf bytes.Buffer
+				render(&buf, days[i], selectDay(events, days[i]))
+				out[i] = buf.Bytes()
+			}
+		}()
+	}
+	for i := range days {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// 期間の各日の出力は、1日ずつ描画した場合と同じで日付順に並ぶ
+func TestRenderDays(t *testing.T) {
+	items := benchmarkItems(300, 30)
+	days := daysBetween(date(2024, 6, 1), date(2024, 6, 30))
+	opts := goldenOptions(t)
+	out := renderDays(items, days, opts.print)
+	if len(out) != len(days) {
+		t.Fatalf("renderDays returned %d days, want %d", len(out), len(days))
+	}
+	events := normalizeEvents("primary", items)
+	for i, day := range days {
+		var want bytes.Buffer
+		opts.print(&want, day, selectDay(events, day))
+		if !bytes.Equal(out[i], want.Bytes()) {
+			t.Errorf("output for %s differs:\n got %s\nwant %s", day.Format("2006-01-02"), out[i], want.Bytes())
+		}
+	}
+}
+
+// 月の表示（30日分）を、日ごとに並行して描画する場合と、1日ずつ順に描画する場合
+func BenchmarkRenderDays(b *testing.B) {
+	days := daysBetween(date(2024, 6, 1), date(2024, 6, 30))
+	opts := goldenOptions(b)
+	for _, n := range []int{500, 5000} {
+		items := benchmarkItems(n, len(days))
+		b.Run(fmt.Sprintf("pipeline/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				renderDays(items, days, opts.print)
+			}
+		})
+		b.Run(fmt.Sprintf("sequential/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				// renderDays と同じく、日時のパースは期間全体で1回だけ行う
+				events := normalizeEvents("primary", items)
+				for _, day := range days {
+					opts.print(io.Discard, day, selectDay(events, day))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package redis は Redis にコマンドを送るだけの最小限のクライアント（RESP2）。
+//
+// 常駐モードの通知済みの状態を複数の端末で共有するために使う。パイプラインや購読には対応していない。
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// サーバーが返したエラー（"ERR ..." など）
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// サーバーとの接続
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Dial は "redis://[:password@]host:6379/0"（TLS は "rediss://"）のサーバーに接続し、
+// パスワードとデータベースの指定があれば AUTH と SELECT を送る
+func Dial(ctx context.Context, rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL %q: %w", rawURL, err)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	var d net.Dialer
+	var conn net.Conn
+	switch u.Scheme {
+	case "redis":
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	case "rediss":
+		td := &tls.Dialer{NetDialer: &d, Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = td.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("unsupported server scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if name := u.User.Username(); name != "" {
+			args = []string{"AUTH", name, password}
+		}
+		if _, err := c.Do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err := c.Do("SELECT", db); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Do はコマンドを送って応答を返す
+//
+// 応答は文字列（string）、整数（int64）、配列（[]any）のいずれかで、値がない場合（GET で
+// キーがない、EXEC が中止されたなど）は nil。サーバーのエラーは Error として返す。
+func (c *Client) Do(args ...string) (any, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// Close は接続を閉じる
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch kind, rest := line[0], line[1:]; kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, Error(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", rest)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", rest)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			// 配列の中のエラー（EXEC の各コマンドの失敗）はそのまま値として返す
+			v, err := c.readReply()
+			var rerr Error
+			if errors.As(err, &rerr) {
+				v, err = rerr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
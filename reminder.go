@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+
+	"gcal-daily-agenda/notify"
+)
+
+// 通知からスヌーズしたときに再通知するまでの時間
+const defaultSnooze = 5 * time.Minute
+
+// 予定に適用する通知タイミング
+func (opts daemonOptions) leadsFor(calendarID string, item *calendar.Event) []time.Duration {
+	for _, r := range opts.rules {
+		if r.Color != "" && r.Color != item.ColorId {
+			continue
+		}
+		if r.Calendar != "" && r.Calendar != calendarID {
+			continue
+		}
+		if r.Keyword != "" && !strings.Contains(strings.ToLower(item.Summary), strings.ToLower(r.Keyword)) {
+			continue
+		}
+		return durations(r.Leads)
+	}
+	return opts.defaultLeads
+}
+
+// 設定されている中で最も長い通知タイミング
+func (opts daemonOptions) maxLead() time.Duration {
+	max := time.Duration(0)
+	for _, d := range opts.defaultLeads {
+		if d > max {
+			max = d
+		}
+	}
+	for _, r := range opts.rules {
+		for _, d := range durations(r.Leads) {
+			if d > max {
+				max = d
+			}
+		}
+	}
+	return max
+}
+
+// 通知の時刻になった予定を通知する
+func checkReminders(ctx context.Context, client calendarClient, store *stateStore, now time.Time, opts daemonOptions) error {
+	// 通知しない時間帯は通知済みにしない（まだ始まっていない予定は、通知できるようになってから通知する）
+	if !opts.quiet.allows(ctx, client, "reminders", now) {
+		slog.Debug("Reminders are quiet", "time", now)
+		return nil
+	}
+	if err := store.reload(); err != nil {
+		return err
+	}
+	// 取得できなかったカレンダーがあっても残りのカレンダーの通知は続ける
+	events := map[string][]*calendar.Event{}
+	if _, err := eachCalendar(opts.calendars, func(calendarID string) error {
+		list, err := client.ListEvents(ctx, calendarID, now, now.Add(opts.maxLead()+opts.interval))
+		if err != nil {
+			return err
+		}
+		events[calendarID] = list.Items
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, calendarID := range opts.calendars {
+		for _, item := range events[calendarID] {
+			if err := checkEventReminders(store, calendarID, item, now, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkEventReminders(store *stateStore, calendarID string, item *calendar.Event, now time.Time, opts daemonOptions) error {
+	if item.Start.DateTime == "" || item.Status == "cancelled" || attendanceStatus(item) == "declined" {
+		return nil
+	}
+	start, _ := eventTimes(item)
+	if !start.After(now) {
+		return nil
+	}
+	key := reminderKey(item)
+
+	// 通知の時刻を過ぎていてまだ通知していないタイミング（停止中に複数過ぎていても通知は1回にまとめる）
+	var dueKeys []string
+	store.view(func(st *reminderState) {
+		if _, ok := st.Acked[key]; ok {
+			return
+		}
+		if until, ok := st.Snoozed[key]; ok {
+			if !now.Before(until) {
+				dueKeys = append(dueKeys, key+"/snooze")
+			}
+			return
+		}
+		for _, lead := range opts.leadsFor(calendarID, item) {
+			deliveredKey := key + "/" + lead.String()
+			if _, delivered := st.Delivered[deliveredKey]; !delivered && !now.Before(start.Add(-lead)) {
+				dueKeys = append(dueKeys, deliveredKey)
+			}
+		}
+	})
+	if len(dueKeys) == 0 {
+		return nil
+	}
+
+	// 通知する前に記録し、再起動しても二重に通知しないようにする
+	//
+	// 状態を共有している別の端末の常駐モードが先に記録していれば、その端末が通知するためここでは通知しない。
+	var claimed []string
+	if err := store.update(func(st *reminderState) {
+		claimed = nil
+		for _, k := range dueKeys {
+			if strings.HasSuffix(k, "/snooze") {
+				if until, ok := st.Snoozed[key]; !ok || now.Before(until) {
+					continue
+				}
+			} else if _, delivered := st.Delivered[k]; delivered {
+				continue
+			}
+			st.Delivered[k] = now
+			claimed = append(claimed, k)
+		}
+		if len(claimed) > 0 {
+			delete(st.Snoozed, key)
+		}
+	}); err != nil {
+		return err
+	}
+	if len(claimed) == 0 {
+		slog.Debug("Reminder already delivered by another daemon", "event_id", item.Id)
+		return nil
+	}
+	slog.Info("Reminder due", "event_id", item.Id, "calendar", calendarID, "summary", item.Summary, "start", start)
+	runReminderHooks(context.Background(), calendarID, item, start.Sub(now))
+	if opts.once {
+		// cron から実行した場合は通知の操作を待たずに終了する
+		notifyWithActions(reminderTitle(item), reminderBody(start.Sub(now)), item.Id, false)
+		return nil
+	}
+	go deliverReminder(store, key, item, start.Sub(now))
+	return nil
+}
+
+// 予定の回ごとのキー（繰り返し予定の各回や、移動された予定を区別する）
+func reminderKey(item *calendar.Event) string {
+	start, _ := eventTimes(item)
+	return item.Id + "@" + start.UTC().Format(time.RFC3339)
+}
+
+// 通知を表示し、スヌーズや確認の操作があれば状態に反映する
+func deliverReminder(store *stateStore, key string, item *calendar.Event, until time.Duration) {
+	action := notifyWithActions(reminderTitle(item), reminderBody(until), item.Id, true)
+	var err error
+	switch action {
+	case "snooze":
+		err = store.update(func(st *reminderState) {
+			st.Snoozed[key] = time.Now().Add(defaultSnooze)
+		})
+	case "ack":
+		err = store.update(func(st *reminderState) {
+			st.Acked[key] = time.Now()
+		})
+	}
+	if err != nil {
+		slog.Error("Unable to save reminder action", "event_id", item.Id, "action", action, "error", err)
+	}
+}
+
+func reminderTitle(item *calendar.Event) string {
+	start, _ := eventTimes(item)
+	return fmt.Sprintf("%s %s", start.Format("15:04"), item.Summary)
+}
+
+func reminderBody(until time.Duration) string {
+	minutes := int(until.Round(time.Minute).Minutes())
+	if minutes >= 120 {
+		return fmt.Sprintf("%d時間%d分後に開始します", minutes/60, minutes%60)
+	}
+	return fmt.Sprintf("%d分後に開始します", minutes)
+}
+
+// デスクトップ通知
+var notifier = notify.New(progName)
+
+// 通知を表示する。wait の場合、ボタンに対応している環境では押された操作（"snooze" / "ack"）を返す
+//
+// 端末にも出力し、イベントIDを使って snooze / ack コマンドから操作できるようにする。
+func notifyWithActions(title, body, eventID string, wait bool) string {
+	fmt.Printf("⏰ %s (%s) [id: %s]\n", title, body, eventID)
+	n := notify.Notification{Title: title, Body: body}
+	if wait {
+		n.Actions = []notify.Action{{ID: "snooze", Label: "スヌーズ"}, {ID: "ack", Label: "OK"}}
+	}
+	action, err := notifier.Notify(context.Background(), n)
+	if err != nil {
+		slog.Warn("Unable to show notification", "event_id", eventID, "error", err)
+	}
+	return action
+}
+
+// 通知を一定時間後に再表示させる
+func setupSnooze(fs *flag.FlagSet) func(args []string) error {
+	statePath := fs.String("state", defaultStateFile, "File or redis:// URL to persist delivered reminders (share it between machines to avoid double notifications)")
+	return func(args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("Usage: %s snooze <event-id> [duration]", progName)
+		}
+		d := defaultSnooze
+		if len(args) == 2 {
+			var err error
+			if d, err = time.ParseDuration(args[1]); err != nil {
+				return fmt.Errorf("Invalid duration: %w", err)
+			}
+		}
+		return updateReminderKeys(*statePath, args[0], func(st *reminderState, key string) {
+			st.Snoozed[key] = time.Now().Add(d)
+		})
+	}
+}
+
+// 通知を確認済みにし、以降は通知しない
+func setupAck(fs *flag.FlagSet) func(args []string) error {
+	statePath := fs.String("state", defaultStateFile, "File or redis:// URL to persist delivered reminders (share it between machines to avoid double notifications)")
+	return func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: %s ack <event-id>", progName)
+		}
+		return updateReminderKeys(*statePath, args[0], func(st *reminderState, key string) {
+			st.Acked[key] = time.Now()
+			delete(st.Snoozed, key)
+		})
+	}
+}
+
+// 通知済みの予定のうち eventID に一致するものに update を適用する
+func updateReminderKeys(statePath, eventID string, update func(st *reminderState, key string)) error {
+	store, err := openStateStore(statePath)
+	if err != nil {
+		return err
+	}
+	found := false
+	err = store.update(func(st *reminderState) {
+		found = false
+		for k := range st.Delivered {
+			key, _, _ := strings.Cut(k, "/")
+			if keyMatchesEvent(key, eventID) {
+				update(st, key)
+				found = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("No delivered reminder for event %s", eventID)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gcal-daily-agenda/schema"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// このツールの JSON（schema）を標準入力から読み込み、Google の API を使わずに出力する
+func setupRender(fs *flag.FlagSet) func(args []string) error {
+	stdin := fs.Bool("stdin", false, "Read the agenda JSON (serve /api/agenda, batch --format json or ndjson) from stdin")
+	format := fs.String("format", "text", "Output format: text, json (one object per day and line) or ics")
+	opts := agendaOptions{}
+	addDisplayFlags(fs, &opts)
+	return func(args []string) error {
+		if !*stdin {
+			return fmt.Errorf("Usage: %s render --stdin [--format text|json|ics] < agenda.json", progName)
+		}
+		if *format != "text" && *format != "json" && *format != "ics" {
+			return fmt.Errorf("Unsupported --format %q (expected text, json or ics)", *format)
+		}
+		if err := opts.loadConfig(); err != nil {
+			return err
+		}
+		days, err := readRenderInput(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return renderAgendas(os.Stdout, days, *format, opts)
+	}
+}
+
+// 読み込んだ1日分の予定
+type renderDay struct {
+	date   time.Time
+	events []agendaEvent
+	errors []schema.CalendarError
+}
+
+// JSON の値（1日分の予定か、ndjson の予定1件）を順に読み込み、日付ごとにまとめる（最初に現れた日の順）
+func readRenderInput(r io.Reader) ([]renderDay, error) {
+	var days []renderDay
+	index := map[string]int{}
+	dayFor := func(date string) (*renderDay, error) {
+		if i, ok := index[date]; ok {
+			return &days[i], nil
+		}
+		day, err := parseDateFlag(date)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid date in input: %w", err)
+		}
+		index[date] = len(days)
+		days = append(days, renderDay{date: day})
+		return &days[len(days)-1], nil
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var v struct {
+			SchemaVersion int    `json:"schema_version"`
+			Date          string `json:"date"`
+			// 1日分の予定
+			Events *[]schema.Event        `json:"events"`
+			Errors []schema.CalendarError `json:"errors"`
+			// ndjson の予定1件
+			schema.Event
+		}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse input: %w", err)
+		}
+		if v.SchemaVersion > schema.Version {
+			return nil, fmt.Errorf("Unsupported schema_version %d (this version reads up to %d)", v.SchemaVersion, schema.Version)
+		}
+		if v.Date == "" {
+			return nil, fmt.Errorf("Unable to parse input: missing date")
+		}
+		day, err := dayFor(v.Date)
+		if err != nil {
+			return nil, err
+		}
+		events := []schema.Event{v.Event}
+		if v.Events != nil {
+			events = *v.Events
+			day.errors = append(day.errors, v.Errors...)
+		}
+		for _, e := range events {
+			item := itemFromJSON(e)
+			ev, err := newAgendaEvent(e.Calendar, item)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid event in input: %w", err)
+			}
+			day.events = append(day.events, ev)
+		}
+	}
+	return days, nil
+}
+
+// JSON の予定を API の予定に戻す（表示に使う項目のみ）
+func itemFromJSON(e schema.Event) *calendar.Event {
+	item := &calendar.Event{
+		Id:       e.ID,
+		Summary:  e.Summary,
+		ColorId:  e.ColorID,
+		Location: e.Location,
+		Start:    &calendar.EventDateTime{},
+		End:      &calendar.EventDateTime{},
+	}
+	if e.AllDay {
+		item.Start.Date, item.End.Date = e.Start, e.End
+	} else {
+		item.Start.DateTime, item.End.DateTime = e.Start, e.End
+	}
+	if e.Private {
+		item.Visibility = "private"
+	}
+	// 🔒 の表示のため
+	item.Locked = e.ReadOnly
+	if len(e.Tags) > 0 {
+		item.ExtendedProperties = &calendar.EventExtendedProperties{
+			Private: map[string]string{tagsProperty: strings.Join(e.Tags, ",")},
+		}
+	}
+	return item
+}
+
+func renderAgendas(w io.Writer, days []renderDay, format string, opts agendaOptions) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		pal := colorPalette{}
+		for _, day := range days {
+			agenda := schema.NewAgenda(day.date.Format("2006-01-02"))
+			agenda.Errors = day.errors
+			for _, ev := range opts.prepare(day.events) {
+				e := newJSONEvent(ev.calendarID, ev.item)
+				e.ColorHex = pal.eventColor(ev.item.ColorId)
+				agenda.Events = append(agenda.Events, e)
+			}
+			if err := enc.Encode(agenda); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "ics":
+		var events []icsEvent
+		seen := map[string]bool{}
+		for _, day := range days {
+			for _, ev := range opts.prepare(day.events) {
+				// 複数日にまたがる予定は日ごとに入っているため1件にする
+				if seen[ev.item.Id] {
+					continue
+				}
+				seen[ev.item.Id] = true
+				events = append(events, icsEvent{calendarID: ev.calendarID, item: ev.item})
+			}
+		}
+		return writeICS(w, "", events)
+	}
+	for i, day := range days {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		printCalendarErrors(w, day.errors)
+		opts.print(w, day.date, day.events)
+	}
+	return nil
+}
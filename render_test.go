@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gcal-daily-agenda/schema"
+)
+
+// serve の /api/agenda と同じ JSON を render --stdin --format json で読み込んで書き出すと、元の JSON に戻る
+func TestSchemaRoundTrip(t *testing.T) {
+	client, err := newFixtureClient("testdata/day.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []string{"2024-06-14", "2024-06-15"} {
+		t.Run(day, func(t *testing.T) {
+			targetDate, err := parseDateFlag(day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := fetchJSONAgenda(context.Background(), client, []string{"primary"}, targetDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var in bytes.Buffer
+			if err := json.NewEncoder(&in).Encode(want); err != nil {
+				t.Fatal(err)
+			}
+			days, err := readRenderInput(&in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := renderAgendas(&out, days, "json", agendaOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			var got schema.Agenda
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, want) {
+				t.Errorf("round trip changed the agenda:\n got %+v\nwant %+v", got, *want)
+			}
+		})
+	}
+}
+
+// ndjson（batch --format ndjson）の予定は日付ごとにまとめる
+func TestReadRenderInputNDJSON(t *testing.T) {
+	input := `{"schema_version":1,"date":"2024-06-14","id":"a","summary":"定例","start":"2024-06-14T10:00:00+09:00","end":"2024-06-14T10:30:00+09:00","all_day":false,"color_name":""}
+{"schema_version":1,"date":"2024-06-15","id":"b","summary":"休み","start":"2024-06-15","end":"2024-06-16","all_day":true,"color_name":""}
+{"schema_version":1,"date":"2024-06-14","id":"c","summary":"Lunch","start":"2024-06-14T12:00:00+09:00","end":"2024-06-14T13:00:00+09:00","all_day":false,"color_name":""}
+`
+	days, err := readRenderInput(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range days {
+		got = append(got, d.date.Format("2006-01-02")+":"+strings.Join(eventIDs(d.events), ","))
+	}
+	if want := []string{"2024-06-14:a,c", "2024-06-15:b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readRenderInput = %v, want %v", got, want)
+	}
+}
+
+func TestReadRenderInputErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not json", "予定"},
+		{"missing date", `{"schema_version":1,"events":[]}`},
+		{"invalid date", `{"schema_version":1,"date":"2024/06/14","events":[]}`},
+		{"newer schema", `{"schema_version":99,"date":"2024-06-14","events":[]}`},
+		{"invalid start", `{"schema_version":1,"date":"2024-06-14","events":[{"id":"a","start":"10:00","end":"11:00"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readRenderInput(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("readRenderInput(%q) succeeded", tt.input)
+			}
+		})
+	}
+}
+
+// 読み込めた入力は表示でき、JSON に書き出して読み込み直しても日と予定の数が変わらない
+func FuzzReadRenderInput(f *testing.F) {
+	for _, s := range []string{
+		`{"schema_version":1,"date":"2024-06-14","events":[{"id":"a","calendar":"primary","summary":"定例","start":"2024-06-14T10:00:00+09:00","end":"2024-06-14T10:30:00+09:00","all_day":false,"color_id":"2","color_name":"緑","tags":["meeting"],"private":true}]}`,
+		`{"schema_version":1,"date":"2024-06-14","events":[],"errors":[{"calendar":"team@example.com","error":"forbidden"}]}`,
+		`{"schema_version":1,"date":"2024-06-14","id":"a","summary":"定例","start":"2024-06-14T10:00:00+09:00","end":"2024-06-14T10:30:00+09:00","all_day":false,"color_name":""}
+{"schema_version":1,"date":"2024-06-15","id":"b","summary":"休み","start":"2024-06-15","end":"2024-06-16","all_day":true,"color_name":""}`,
+		`{"schema_version":1,"date":"2024-06-14","events":[{"id":"a","start":"2024-06-14T10:00:00+09:00","end":"2024-06-14T09:00:00+09:00"}]}`,
+		`{"schema_version":1,"date":"2024-06-14","events":[{"id":"a","start":"10:00","end":"11:00"}]}`,
+		`{"schema_version":99,"date":"2024-06-14","events":[]}`,
+		`{"date":"2024-06-14","events":null}`,
+		`[]`,
+		``,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		days, err := readRenderInput(strings.NewReader(s))
+		if err != nil {
+			return
+		}
+		var text bytes.Buffer
+		if err := renderAgendas(&text, days, "text", agendaOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := renderAgendas(&out, days, "json", agendaOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		again, err := readRenderInput(&out)
+		if err != nil {
+			t.Fatalf("readRenderInput(%q) succeeded, but reading the JSON output failed: %v\n%s", s, err, out.String())
+		}
+		if len(again) != len(days) {
+			t.Fatalf("readRenderInput(%q) read %d days, read back %d", s, len(days), len(again))
+		}
+		for i := range days {
+			if len(again[i].events) != len(days[i].events) {
+				t.Errorf("readRenderInput(%q) read %d events on %v, read back %d", s, len(days[i].events), days[i].date, len(again[i].events))
+			}
+		}
+	})
+}
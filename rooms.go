@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 会議室の予約がない次の予定を探す期間
+const roomLookahead = 7 * 24 * time.Hour
+
+// 会議室（リソースカレンダー）の空きを表示する
+//
+// --at を省略すると、これからの予定のうち会議室の入っていない最初の予定の時間で調べる。
+func setupRooms(fs *flag.FlagSet) func(args []string) error {
+	atStr := fs.String("at", "", "Start time to check (format: \"YYYY-MM-DD HH:MM\" or HH:MM for today; default: next event without a room)")
+	length := fs.Duration("duration", time.Hour, "Length of the meeting when --at is given")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Rooms) == 0 {
+			return fmt.Errorf("No rooms in %s (add \"rooms\": [{\"name\": ..., \"calendar\": ...}])", *configPath)
+		}
+		if *length <= 0 {
+			return fmt.Errorf("--duration must be positive")
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var slot interval
+		if *atStr != "" {
+			start, err := parseRoomTime(*atStr, now)
+			if err != nil {
+				return err
+			}
+			slot = interval{start: start, end: start.Add(*length)}
+		} else {
+			item, err := nextEventWithoutRoom(ctx, client, cfg.Rooms, now)
+			if err != nil {
+				return err
+			}
+			if item == nil {
+				fmt.Println("会議室の入っていない予定はありません。")
+				return nil
+			}
+			start, end := eventTimes(item)
+			slot = interval{start: start.In(time.Local), end: end.In(time.Local)}
+			fmt.Printf("会議室の入っていない次の予定: %s\n", formatEvent(item))
+		}
+		return printRooms(ctx, client, os.Stdout, cfg.Rooms, slot)
+	}
+}
+
+// "YYYY-MM-DD HH:MM"、または今日の "HH:MM"
+func parseRoomTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local); err == nil {
+		return t, nil
+	}
+	clock, err := parseClock(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid --at %q. Please use \"YYYY-MM-DD HH:MM\" or HH:MM format", s)
+	}
+	return atClock(now.In(time.Local), clock), nil
+}
+
+// これから始まる予定のうち、会議室が予約されていない最初の予定（参加者のいない予定や終日の予定は除く）
+func nextEventWithoutRoom(ctx context.Context, client calendarClient, rooms []namedCalendar, now time.Time) (*calendar.Event, error) {
+	events, err := client.ListEvents(ctx, "primary", now, now.Add(roomLookahead))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range events.Items {
+		if item.Start.DateTime == "" || item.Status == "cancelled" || len(item.Attendees) == 0 ||
+			attendanceStatus(item) == "declined" {
+			continue
+		}
+		if start, _ := eventTimes(item); start.Before(now) {
+			continue
+		}
+		if !hasRoom(item, rooms) {
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+// 会議室が参加者として予約されているか
+func hasRoom(item *calendar.Event, rooms []namedCalendar) bool {
+	for _, a := range item.Attendees {
+		if a.Resource && a.ResponseStatus != "declined" {
+			return true
+		}
+		for _, r := range rooms {
+			if strings.EqualFold(a.Email, r.Calendar) && a.ResponseStatus != "declined" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// slot の時間に空いている会議室と予約の入っている会議室を表示する
+func printRooms(ctx context.Context, client calendarClient, w io.Writer, rooms []namedCalendar, slot interval) error {
+	var free, busy []string
+	for _, r := range rooms {
+		// FreeBusy はまとめて問い合わせると時間帯が合算されるため会議室ごとに呼び出す
+		ivs, err := client.FreeBusy(ctx, []string{r.Calendar}, slot.start, slot.end)
+		if err != nil {
+			return err
+		}
+		if overlaps(ivs, slot) {
+			busy = append(busy, r.Name)
+		} else {
+			free = append(free, r.Name)
+		}
+	}
+	fmt.Fprintf(w, "%s %s-%s の会議室:\n", slot.start.Format("2006-01-02"), slot.start.Format("15:04"), slot.end.Format("15:04"))
+	fmt.Fprintf(w, "空き: %s\n", joinOrNone(free))
+	fmt.Fprintf(w, "予約あり: %s\n", joinOrNone(busy))
+	return nil
+}
+
+func overlaps(ivs []interval, slot interval) bool {
+	for _, iv := range ivs {
+		if iv.start.Before(slot.end) && iv.end.After(slot.start) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "なし"
+	}
+	return strings.Join(names, "、")
+}
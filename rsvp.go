@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 自分の出欠状況の記号（--markers）
+var responseMarkers = map[string]string{
+	"accepted":    "✓",
+	"tentative":   "~",
+	"declined":    "✗",
+	"needsAction": "?",
+}
+
+// 予定の後ろに付ける出欠と主催者（自分が主催者の予定や招待のない予定には付けない）と、変更できない予定の 🔒
+func eventMarkers(item *calendar.Event) string {
+	var marks []string
+	if m, ok := responseMarkers[attendanceStatus(item)]; ok {
+		marks = append(marks, m)
+	}
+	if readOnlyReason(item) != "" {
+		marks = append(marks, "🔒")
+	}
+	if org := organizerLabel(item); org != "" {
+		marks = append(marks, org)
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return " " + strings.Join(marks, " ")
+}
+
+// 自分が主催する予定の、ほかの参加者の出欠の数（「 5✓ 1✗ 2?」、会議室などのリソースは数えない）
+func responseCounts(item *calendar.Event) string {
+	if item.Organizer == nil || !item.Organizer.Self {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, a := range item.Attendees {
+		if a.Self || a.Resource {
+			continue
+		}
+		counts[a.ResponseStatus]++
+	}
+	var parts []string
+	for _, status := range []string{"accepted", "tentative", "declined", "needsAction"} {
+		if n := counts[status]; n > 0 {
+			parts = append(parts, strconv.Itoa(n)+responseMarkers[status])
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// 主催者の表示名（なければメールアドレスのドメイン）
+func organizerLabel(item *calendar.Event) string {
+	org := item.Organizer
+	if org == nil || org.Self {
+		return ""
+	}
+	if org.DisplayName != "" {
+		return org.DisplayName
+	}
+	if _, domain, ok := strings.Cut(org.Email, "@"); ok {
+		return "@" + domain
+	}
+	return org.Email
+}
+
+// まだ返事をしていない招待を一覧にする
+func setupPending(fs *flag.FlagSet) func(args []string) error {
+	days := fs.Int("days", 14, "Number of days to look ahead")
+	var calendars stringList
+	fs.Var(&calendars, "calendar", "Calendar ID, @group from the config, or a regular expression for calendar names (repeatable; default: calendars in the config)")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		ids, err := cfg.selectCalendars(ctx, client, calendars)
+		if err != nil {
+			return err
+		}
+		invites, errs, err := pendingInvites(ctx, client, ids, time.Now(), *days)
+		if err != nil {
+			return err
+		}
+		printCalendarErrors(os.Stdout, errs)
+		printPendingInvites(os.Stdout, invites, false)
+		return nil
+	}
+}
+
+// invites の操作と返事の対応
+var inviteResponses = map[string]string{
+	"accept":    "accepted",
+	"decline":   "declined",
+	"tentative": "tentative",
+}
+
+// 未回答の招待を番号付きで一覧にし、番号（またはイベントID）を指定して返事をする
+func setupInvites(fs *flag.FlagSet) func(args []string) error {
+	days := fs.Int("days", 14, "Number of days to look ahead")
+	var calendars stringList
+	fs.Var(&calendars, "calendar", "Calendar ID, @group from the config, or a regular expression for calendar names (repeatable; default: calendars in the config)")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("Usage: %s invites [accept|decline|tentative <number|event-id>]", progName)
+		}
+		var response string
+		if len(args) == 2 {
+			var ok bool
+			if response, ok = inviteResponses[args[0]]; !ok {
+				return fmt.Errorf("Unknown action %q (use accept, decline or tentative)", args[0])
+			}
+		}
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		scope := calendar.CalendarReadonlyScope
+		if response != "" {
+			scope = calendar.CalendarEventsScope
+		}
+		client, err := newWriteClient(ctx, *fromFixture, scope)
+		if err != nil {
+			return err
+		}
+		ids, err := cfg.selectCalendars(ctx, client, calendars)
+		if err != nil {
+			return err
+		}
+		invites, errs, err := pendingInvites(ctx, client, ids, time.Now(), *days)
+		if err != nil {
+			return err
+		}
+		if response == "" {
+			printCalendarErrors(os.Stdout, errs)
+			printPendingInvites(os.Stdout, invites, true)
+			return nil
+		}
+
+		inv, err := findInvite(invites, args[1])
+		if err != nil {
+			return err
+		}
+		if err := respondToInvite(ctx, client, inv, response); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", attendanceLabels[response], formatPendingInvite(inv))
+		return nil
+	}
+}
+
+// 一覧の番号（1から）またはイベントIDで招待を探す
+func findInvite(invites []pendingInvite, ref string) (pendingInvite, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(invites) {
+			return pendingInvite{}, fmt.Errorf("No invitation numbered %d", n)
+		}
+		return invites[n-1], nil
+	}
+	for _, inv := range invites {
+		if inv.item.Id == ref {
+			return inv, nil
+		}
+	}
+	return pendingInvite{}, fmt.Errorf("No pending invitation for event %s", ref)
+}
+
+// 自分の出欠を response にする
+func respondToInvite(ctx context.Context, client calendarWriter, inv pendingInvite, response string) error {
+	updated := *inv.item
+	updated.Attendees = make([]*calendar.EventAttendee, len(inv.item.Attendees))
+	for i, a := range inv.item.Attendees {
+		if a.Self {
+			self := *a
+			self.ResponseStatus = response
+			a = &self
+		}
+		updated.Attendees[i] = a
+	}
+	_, err := client.UpdateEvent(ctx, inv.calendarID, &updated)
+	return err
+}
+
+// 返事をしていない招待
+type pendingInvite struct {
+	calendarID string
+	item       *calendar.Event
+	start      time.Time
+}
+
+// now から days 日後までの、自分の出欠が未回答の予定を開始時刻順に返す
+//
+// 取得できなかったカレンダーは飛ばし、2つ目の戻り値として返す。
+func pendingInvites(ctx context.Context, client calendarClient, calendarIDs []string, now time.Time, days int) ([]pendingInvite, []calendarError, error) {
+	timeMax := now.AddDate(0, 0, days)
+	var out []pendingInvite
+	seen := map[string]bool{}
+	errs, err := eachCalendar(calendarIDs, func(id string) error {
+		events, err := client.ListEvents(ctx, id, now, timeMax)
+		if err != nil {
+			return err
+		}
+		for _, item := range events.Items {
+			if item.Status == "cancelled" || attendanceStatus(item) != "needsAction" {
+				continue
+			}
+			if key := duplicateKey(item); key != "" {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			start, _ := eventTimes(item)
+			if item.Start.DateTime == "" {
+				start, _ = time.ParseInLocation("2006-01-02", item.Start.Date, now.Location())
+			}
+			out = append(out, pendingInvite{calendarID: id, item: item, start: start})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].start.Before(out[j].start)
+	})
+	return out, errs, nil
+}
+
+// 未回答の招待を出力する（numbered の場合は invites で指定する番号を付ける）
+func printPendingInvites(w io.Writer, invites []pendingInvite, numbered bool) {
+	if len(invites) == 0 {
+		fmt.Fprintln(w, "未回答の招待はありません。")
+		return
+	}
+	fmt.Fprintf(w, "未回答の招待（%d件）:\n", len(invites))
+	for i, inv := range invites {
+		if numbered {
+			fmt.Fprintf(w, "%d. ", i+1)
+		}
+		fmt.Fprintln(w, formatPendingInvite(inv))
+	}
+}
+
+// 「日付(曜日) 時刻 件名 — 主催者」の形式にする
+func formatPendingInvite(inv pendingInvite) string {
+	item := inv.item
+	day := fmt.Sprintf("%s(%s)", inv.start.Format("2006-01-02"), weekdayNames["ja"][inv.start.Weekday()])
+	when := "終日"
+	if item.Start.DateTime != "" {
+		_, end := eventTimes(item)
+		when = inv.start.Format("15:04") + "-" + end.Format("15:04")
+	}
+	line := fmt.Sprintf("%s %s %s", day, when, item.Summary)
+	if org := organizerLabel(item); org != "" {
+		line += " — " + org
+	}
+	return line
+}
@@ -0,0 +1,103 @@
+// Package schema は JSON で出力する予定の形式（serve の /api/agenda、batch --format json / ndjson、
+// --exec-hook と Webhook に渡す JSON）を定義する。
+//
+// 同じ Version の間は、フィールドの追加だけを行い、既存のフィールドの削除や名前・意味の変更はしない。
+// 互換性のない変更をするときは Version を上げる。読み込む側は schema_version を確認し、
+// 知らないフィールドは無視すること。
+package schema
+
+// 出力する JSON の形式のバージョン（schema_version に入る）
+const Version = 1
+
+// 1日分の予定
+type Agenda struct {
+	SchemaVersion int `json:"schema_version"`
+	// YYYY-MM-DD
+	Date   string  `json:"date"`
+	Events []Event `json:"events"`
+	// 取得できなかったカレンダー（Events にはそれ以外のカレンダーの予定だけが入る）
+	Errors []CalendarError `json:"errors,omitempty"`
+}
+
+// date の予定のない Agenda（Events は null ではなく空の配列になる）
+func NewAgenda(date string) *Agenda {
+	return &Agenda{SchemaVersion: Version, Date: date, Events: []Event{}}
+}
+
+// 予定
+type Event struct {
+	ID       string `json:"id"`
+	Calendar string `json:"calendar,omitempty"`
+	Summary  string `json:"summary"`
+	// 時間指定の予定は RFC3339、終日の予定は YYYY-MM-DD
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	AllDay    bool   `json:"all_day"`
+	ColorID   string `json:"color_id,omitempty"`
+	ColorName string `json:"color_name"`
+	// Colors API の背景色（"#a4bdfc" の形式）
+	ColorHex string `json:"color_hex,omitempty"`
+	Location string `json:"location,omitempty"`
+	// 場所が住所の場合、Google マップで検索する URL
+	MapsURL string `json:"maps_url,omitempty"`
+	// 出発地からの移動時間（serve の設定の maps）
+	TravelTime string `json:"travel_time,omitempty"`
+	// 同じ予定が複数のカレンダーに入っている場合、入っているすべてのカレンダー
+	Sources []string `json:"sources,omitempty"`
+	// 公開設定が「非公開」の予定
+	Private bool `json:"private,omitempty"`
+	// 自分では日時を変更できない予定（ゲストに変更が許可されていない招待と、ロックされた予定）
+	ReadOnly bool `json:"read_only,omitempty"`
+	// 件名に書かれた課題へのリンク（serve の設定の links）
+	Links []Link `json:"links,omitempty"`
+	// 拡張プロパティと説明欄の "#tag" のタグ（"#" は付けない）
+	Tags []string `json:"tags,omitempty"`
+}
+
+// 予定に書かれていた課題や Pull Request へのリンク
+type Link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// 取得できなかったカレンダーとその理由
+type CalendarError struct {
+	Calendar string `json:"calendar"`
+	Error    string `json:"error"`
+}
+
+// Apple のショートカットや Scriptable のウィジェット向けの、1日分の予定の要約（serve の /api/widget）
+//
+// 時刻は "10:00" の形式で、そのまま表示できるようにする。
+type Widget struct {
+	SchemaVersion int `json:"schema_version"`
+	// YYYY-MM-DD
+	Date string `json:"date"`
+	// その日の予定の数と、まだ終わっていない予定の数
+	Count     int `json:"count"`
+	Remaining int `json:"remaining"`
+	// 進行中の予定と、この後の最初の予定（なければ null）
+	Current *WidgetEvent `json:"current"`
+	Next    *WidgetEvent `json:"next"`
+	// 開始時刻順の予定
+	Events []WidgetEvent `json:"events"`
+}
+
+// ウィジェットに表示する予定
+type WidgetEvent struct {
+	Title string `json:"title"`
+	// "10:00"（終日の予定は空）
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// "10:00-11:00"（終日の予定は "終日"）
+	Time   string `json:"time"`
+	AllDay bool   `json:"all_day"`
+	// 予定の色（"#a4bdfc" の形式）
+	Color    string `json:"color,omitempty"`
+	Location string `json:"location,omitempty"`
+	// 開始までの分数（始まった予定は 0）
+	MinutesUntil int `json:"minutes_until"`
+	// 終わった予定
+	Done bool `json:"done"`
+}
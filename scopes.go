@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+)
+
+// OAuth のスコープの説明
+var scopeLabels = map[string]string{
+	calendar.CalendarScope:               "カレンダーの完全な管理",
+	calendar.CalendarReadonlyScope:       "予定とカレンダーの閲覧",
+	calendar.CalendarEventsScope:         "予定の閲覧と変更",
+	calendar.CalendarEventsReadonlyScope: "予定の閲覧",
+}
+
+// スコープがあれば使える、より狭いスコープ
+var impliedScopes = map[string][]string{
+	calendar.CalendarScope:         {calendar.CalendarReadonlyScope, calendar.CalendarEventsScope, calendar.CalendarEventsReadonlyScope},
+	calendar.CalendarEventsScope:   {calendar.CalendarEventsReadonlyScope},
+	calendar.CalendarReadonlyScope: {calendar.CalendarEventsReadonlyScope},
+}
+
+func scopeLabel(scope string) string {
+	if l, ok := scopeLabels[scope]; ok {
+		return l
+	}
+	return scope
+}
+
+// トークンの応答の scope（なければ求めたスコープ）
+func grantedScopes(tok *oauth2.Token, requested []string) []string {
+	if s, ok := tok.Extra("scope").(string); ok && s != "" {
+		return strings.Fields(s)
+	}
+	return requested
+}
+
+// needed のうち granted で使えないスコープ
+func missingScopes(granted, needed []string) []string {
+	var missing []string
+	for _, n := range needed {
+		ok := slices.Contains(granted, n)
+		for _, g := range granted {
+			ok = ok || slices.Contains(impliedScopes[g], n)
+		}
+		if !ok {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// Google の tokeninfo で、トークンに認可されているスコープを調べる（期限切れなら更新してから）
+func lookupTokenScopes(ctx context.Context, config *oauth2.Config, tok *oauth2.Token) ([]string, error) {
+	fresh, err := config.TokenSource(ctx, tok).Token()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"https://oauth2.googleapis.com/tokeninfo?"+url.Values{"access_token": {fresh.AccessToken}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tokeninfo returned %s", resp.Status)
+	}
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return strings.Fields(info.Scope), nil
+}
+
+// 足りないスコープを、端末で確認してからこれまでのスコープと合わせて認可し直す
+func upgradeScopes(ctx context.Context, config *oauth2.Config, saved *savedToken, missing []string) (*savedToken, error) {
+	labels := make([]string, len(missing))
+	for i, s := range missing {
+		labels[i] = "「" + scopeLabel(s) + "」"
+	}
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("%w: %s does not allow %s; run the command interactively to grant it", errAuthRequired, tokenFile, strings.Join(missing, ", "))
+	}
+	fmt.Fprintf(os.Stderr, "この操作には%sの権限が必要です。今の権限に追加して認可し直しますか？ [y/N]: ", strings.Join(labels, "と"))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+	default:
+		return nil, fmt.Errorf("%w: %s does not allow %s", errAuthRequired, tokenFile, strings.Join(missing, ", "))
+	}
+	upgraded := *config
+	upgraded.Scopes = append(slices.Clone(saved.Scopes), missing...)
+	return authorize(ctx, &upgraded, oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+}
+
+// 保存したトークンの権限を表示する（auth scopes）、取り消す（auth revoke）
+func setupAuth(fs *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) != 1 || (args[0] != "scopes" && args[0] != "revoke") {
+			return fmt.Errorf("Usage: %s auth scopes|revoke", progName)
+		}
+		saved, err := tokenFromFile(tokenFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s does not exist", errAuthRequired, tokenFile)
+		}
+		if err != nil {
+			return fmt.Errorf("Unable to read %s: %w", tokenFile, err)
+		}
+		b, err := readClientCredentials()
+		if err != nil {
+			return err
+		}
+		config, err := google.ConfigFromJSON(b)
+		if err != nil {
+			return fmt.Errorf("Unable to parse client secret file to config: %w", err)
+		}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, baseHTTPClient())
+		if args[0] == "revoke" {
+			return revokeToken(ctx, saved)
+		}
+
+		// 記録がなければ Google に問い合わせて記録する
+		if len(saved.Scopes) == 0 {
+			if saved.Scopes, err = lookupTokenScopes(ctx, config, saved.Token); err != nil {
+				return fmt.Errorf("Unable to look up scopes: %w", err)
+			}
+			if err := saveToken(tokenFile, saved); err != nil {
+				return err
+			}
+		}
+		for _, s := range saved.Scopes {
+			fmt.Printf("%s\t%s\n", scopeLabel(s), s)
+		}
+		if len(missingScopes(saved.Scopes, []string{calendar.CalendarEventsScope})) > 0 {
+			fmt.Println("予定を変更するコマンドを実行すると、権限を追加して認可し直すか確認します。")
+		}
+		return nil
+	}
+}
+
+// Google でトークンを取り消し、token.json を削除する
+func revokeToken(ctx context.Context, saved *savedToken) error {
+	token := saved.RefreshToken
+	if token == "" {
+		token = saved.AccessToken
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://oauth2.googleapis.com/revoke",
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to revoke token: %w", err)
+	}
+	resp.Body.Close()
+	// すでに無効になったトークンは 400 になるが、手元のファイルは削除する
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("Unable to revoke token: %s", resp.Status)
+	}
+	if err := os.Remove(tokenFile); err != nil {
+		return fmt.Errorf("Unable to remove %s: %w", tokenFile, err)
+	}
+	fmt.Printf("アクセスを取り消し、%s を削除しました。\n", tokenFile)
+	return nil
+}
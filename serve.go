@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gcal-daily-agenda/schema"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// HTTP や gRPC で予定を提供する（serve http / serve grpc）
+func setupServe(flags *flag.FlagSet) func(args []string) error {
+	addr := flags.String("addr", ":8080", "Address to listen on (default :9090 for grpc)")
+	configPath := flags.String("config", defaultConfigFile, "Path to the config file")
+	var tokens, allow stringList
+	flags.Var(&tokens, "token", "Require this bearer token (repeatable; also read from $GCAL_AGENDA_TOKEN and serve.tokens in the config)")
+	flags.Var(&allow, "allow", "Only accept connections from this address or CIDR (repeatable)")
+	fromFixture := flags.String("from-fixture", "", "Serve events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) != 1 || (args[0] != "http" && args[0] != "grpc") {
+			return fmt.Errorf("Usage: %s serve http|grpc [--addr :8080]", progName)
+		}
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, cfg.Serve.Tokens...)
+		if t := os.Getenv("GCAL_AGENDA_TOKEN"); t != "" {
+			tokens = append(tokens, t)
+		}
+		auth, err := newServeAuth(tokens, append(allow, cfg.Serve.Allow...))
+		if err != nil {
+			return err
+		}
+		if !auth.enabled() {
+			slog.Warn("Serving without authentication; use --token or --allow to restrict access")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		if args[0] == "grpc" {
+			if !flagWasSet(flags, "addr") {
+				*addr = ":9090"
+			}
+			return runGRPCServer(ctx, *addr, client, cfg, auth)
+		}
+		handler := auth.wrap(newHTTPHandler(client, cfg))
+		if secret := cfg.Serve.Slack.signingSecret(); secret != "" {
+			// Slack は署名で確認するため、トークンと接続元の制限の外に置く
+			mux := http.NewServeMux()
+			mux.Handle("POST /slack/command", newSlackHandler(client, secret))
+			mux.Handle("/", handler)
+			handler = mux
+		}
+		srv := &http.Server{Addr: *addr, Handler: handler}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		slog.Info("Serving HTTP", "addr", *addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+}
+
+// HTTP API と Web UI
+//
+//	GET /                  Web UI
+//	GET /api/calendars     選択できるカレンダー（設定ファイルの calendars）の一覧
+//	GET /api/agenda        1日分の予定（?date=YYYY-MM-DD&calendar=ID、calendar は複数指定可）
+//	GET /api/widget        ショートカットやウィジェット向けの1日分の要約（?date=YYYY-MM-DD&calendar=ID）
+//	GET /feed.ics          設定したカレンダーをまとめた iCalendar フィード
+//	POST /slack/command    Slack のスラッシュコマンド（serve.slack を設定した場合、slack.go）
+func newHTTPHandler(client calendarClient, cfg *config) http.Handler {
+	mux := http.NewServeMux()
+
+	static, _ := fs.Sub(webFiles, "web")
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	mux.HandleFunc("GET /api/calendars", func(w http.ResponseWriter, r *http.Request) {
+		entries, err := client.ListCalendars(r.Context())
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		// 設定ファイルのカレンダーだけを、選択済みにして返す
+		served := cfg.servedCalendarSet()
+		type calendarJSON struct {
+			ID       string `json:"id"`
+			Summary  string `json:"summary"`
+			Color    string `json:"color,omitempty"`
+			Selected bool   `json:"selected"`
+		}
+		out := []calendarJSON{}
+		pal := colors(r.Context(), client)
+		for _, e := range entries {
+			id := e.Id
+			if e.Primary {
+				id = "primary"
+			}
+			if !served[id] && !served[e.Id] {
+				continue
+			}
+			out = append(out, calendarJSON{
+				ID:       id,
+				Summary:  e.Summary,
+				Color:    calendarColor(pal, e),
+				Selected: true,
+			})
+		}
+		writeJSON(w, out)
+	})
+
+	mux.HandleFunc("GET /api/agenda", func(w http.ResponseWriter, r *http.Request) {
+		date, err := parseDateFlag(r.URL.Query().Get("date"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids, err := cfg.servedCalendars(r.Context(), client, r.URL.Query()["calendar"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key := r.URL.Query().Get("sort")
+		if key == "" {
+			key = "start"
+		}
+		if err := checkSortKey(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		agenda, err := fetchJSONAgenda(r.Context(), client, ids, date)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		sortJSONEvents(agenda.Events, key, r.URL.Query().Get("reverse") == "true")
+		if cfg.Serve.RedactPrivate {
+			redactPrivateJSON(agenda.Events)
+		}
+		for i := range agenda.Events {
+			e := &agenda.Events[i]
+			for _, l := range cfg.Links.eventLinks(e.Summary, "") {
+				e.Links = append(e.Links, schema.Link{Label: l.Label, URL: l.URL, Title: l.Title})
+			}
+			if e.MapsURL != "" {
+				e.TravelTime = cfg.Maps.travelTime(physicalLocation(e.Location))
+			}
+		}
+		writeJSON(w, agenda)
+	})
+	mux.HandleFunc("GET /api/widget", func(w http.ResponseWriter, r *http.Request) {
+		date, err := parseDateFlag(r.URL.Query().Get("date"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids, err := cfg.servedCalendars(r.Context(), client, r.URL.Query()["calendar"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		agenda, err := fetchJSONAgenda(r.Context(), client, ids, date)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		if cfg.Serve.RedactPrivate {
+			redactPrivateJSON(agenda.Events)
+		}
+		writeJSON(w, newWidget(agenda, time.Now()))
+	})
+	mux.HandleFunc("GET /feed.ics", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := writeFeed(r.Context(), &buf, client, cfg, time.Now()); err != nil {
+			httpError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+// 設定したカレンダーの予定をまとめ、伏せるカレンダーは「Busy」にしてフィードとして書き出す
+func writeFeed(ctx context.Context, w io.Writer, client calendarClient, cfg *config, now time.Time) error {
+	feed := cfg.Serve.Feed
+	past, future := feed.PastDays, feed.FutureDays
+	if past == 0 {
+		past = 7
+	}
+	if future == 0 {
+		future = 60
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	timeMin, timeMax := today.AddDate(0, 0, -past), today.AddDate(0, 0, future+1)
+
+	redacted := map[string]bool{}
+	for _, id := range feed.Redact {
+		redacted[id] = true
+	}
+	// フィードには指定を受け付けず、常に設定ファイルの calendars だけを含める
+	ids, err := cfg.servedCalendars(ctx, client, nil)
+	if err != nil {
+		return err
+	}
+	items := map[string][]*calendar.Event{}
+	// 取得できなかったカレンダーの予定は含めない（警告はログに出す）
+	if _, err := eachCalendar(ids, func(id string) error {
+		events, err := client.ListEvents(ctx, id, timeMin, timeMax)
+		if err != nil {
+			return err
+		}
+		items[id] = events.Items
+		return nil
+	}); err != nil {
+		return err
+	}
+	return writeICS(w, progName, collectICSEvents(items, ids, redacted, cfg.Serve.RedactPrivate))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	slog.Error("Request failed", "error", err)
+	http.Error(w, "Unable to retrieve events", http.StatusBadGateway)
+}
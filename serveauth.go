@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// serve の認可の設定
+type serveAuth struct {
+	// いずれかに一致する Bearer トークン（または ?token=）が必要。空なら不要
+	tokens []string
+	// 接続元 IP の許可リスト。空なら制限しない
+	allow []*net.IPNet
+}
+
+func newServeAuth(tokens, allow []string) (*serveAuth, error) {
+	for _, t := range tokens {
+		// 空のトークンを受け付けると、Authorization ヘッダのないリクエストが通ってしまう
+		if strings.TrimSpace(t) == "" {
+			return nil, fmt.Errorf("Empty token in --token or serve.tokens")
+		}
+	}
+	a := &serveAuth{tokens: tokens}
+	for _, s := range allow {
+		if !strings.Contains(s, "/") {
+			// 単一のアドレス
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid address in allowlist %q: %w", s, err)
+		}
+		a.allow = append(a.allow, n)
+	}
+	return a, nil
+}
+
+func (a *serveAuth) enabled() bool {
+	return len(a.tokens) > 0 || len(a.allow) > 0
+}
+
+// 認可されていないリクエストを拒否するハンドラを返す
+func (a *serveAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.allowedAddr(r.RemoteAddr) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !a.validToken(requestToken(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+progName+`"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *serveAuth) allowedAddr(remoteAddr string) bool {
+	if len(a.allow) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *serveAuth) validToken(token string) bool {
+	if len(a.tokens) == 0 {
+		return true
+	}
+	if token == "" {
+		return false
+	}
+	ok := false
+	for _, t := range a.tokens {
+		// 一致するかどうかを処理時間から推測されないようにする
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// Authorization: Bearer ヘッダ、なければ ?token= のトークン（ブラウザからの表示用）
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if token, ok := strings.CutPrefix(h, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewServeAuthEmptyToken(t *testing.T) {
+	for _, tokens := range [][]string{{""}, {"secret", ""}, {" "}} {
+		if _, err := newServeAuth(tokens, nil); err == nil {
+			t.Errorf("newServeAuth(%q) = nil error, want error", tokens)
+		}
+	}
+}
+
+func TestServeAuthToken(t *testing.T) {
+	auth, err := newServeAuth([]string{"secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := auth.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name   string
+		header string
+		url    string
+		want   int
+	}{
+		{name: "bearer", header: "Bearer secret", url: "/", want: http.StatusOK},
+		{name: "query", url: "/?token=secret", want: http.StatusOK},
+		{name: "wrong token", header: "Bearer other", url: "/", want: http.StatusUnauthorized},
+		{name: "no header", url: "/", want: http.StatusUnauthorized},
+		{name: "empty bearer", header: "Bearer ", url: "/", want: http.StatusUnauthorized},
+		{name: "empty query", url: "/?token=", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
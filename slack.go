@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// Slack のスラッシュコマンド（/slack/command）の設定
+type slackConfig struct {
+	// Slack アプリの Signing Secret（省略時は環境変数 SLACK_SIGNING_SECRET）
+	SigningSecret string `json:"signing_secret,omitempty"`
+}
+
+func (c slackConfig) signingSecret() string {
+	if c.SigningSecret != "" {
+		return c.SigningSecret
+	}
+	return os.Getenv("SLACK_SIGNING_SECRET")
+}
+
+// Slack から送られたリクエストとみなす時刻のずれ（リプレイ攻撃を防ぐ）
+const slackMaxSkew = 5 * time.Minute
+
+// スラッシュコマンドの本文の上限
+const slackMaxBody = 1 << 20
+
+// Slack のスラッシュコマンドに空き状況を返すハンドラ（/agenda tomorrow など）
+//
+// Slack からのリクエストはトークンを送れないため、serve のトークンの代わりに署名を確認する。
+// 予定の件名や場所は伏せ、予定が入っている時間帯だけを返す。
+func newSlackHandler(client calendarClient, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxBody))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err := verifySlackSignature(secret, r.Header, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		date, err := parseSlackDate(form.Get("text"), time.Now().Add(-dayStart))
+		if err != nil {
+			writeJSON(w, slackResponse{ResponseType: "ephemeral", Text: err.Error()})
+			return
+		}
+		text, err := slackAvailability(r.Context(), client, date)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		writeJSON(w, slackResponse{ResponseType: "ephemeral", Text: text})
+	})
+}
+
+// スラッシュコマンドへの応答
+type slackResponse struct {
+	// ephemeral はコマンドを実行した人にだけ表示する
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// X-Slack-Signature（"v0=" + HMAC-SHA256(secret, "v0:タイムスタンプ:本文")）を確認する
+func verifySlackSignature(secret string, h http.Header, body []byte, now time.Time) error {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Missing request timestamp")
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > slackMaxSkew || d < -slackMaxSkew {
+		return fmt.Errorf("Request timestamp is too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(h.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("Invalid signature")
+	}
+	return nil
+}
+
+// コマンドの引数（空・today・今日・tomorrow・明日・YYYY-MM-DD）を日付にする
+func parseSlackDate(text string, today time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "", "today", "今日":
+		return today, nil
+	case "tomorrow", "明日":
+		return today.AddDate(0, 0, 1), nil
+	}
+	date, err := parseDateFlag(strings.TrimSpace(text))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Usage: /agenda [today|tomorrow|YYYY-MM-DD]")
+	}
+	return date, nil
+}
+
+// 1日分の予定を、件名を伏せた予定表（コードブロック）にする
+func slackAvailability(ctx context.Context, client calendarClient, date time.Time) (string, error) {
+	startTime, endTime := dayWindow(date)
+	events, err := client.ListEvents(ctx, "primary", startTime, endTime)
+	if err != nil {
+		return "", err
+	}
+	var busy []agendaEvent
+	for _, e := range selectDay(normalizeEvents("primary", events.Items), date) {
+		if e.item.Status == "cancelled" || !isBusy(e.item, false) || attendanceStatus(e.item) == "declined" {
+			continue
+		}
+		// 色も含めて予定の中身は返さない
+		busy = append(busy, e.withItem(&calendar.Event{Summary: busySummary, Start: e.item.Start, End: e.item.End}))
+	}
+	var buf bytes.Buffer
+	printAgenda(&buf, date, sortEvents(busy, "start", false), agendaOptions{lang: "ja"})
+	return "```\n" + strings.TrimRight(buf.String(), "\n") + "\n```", nil
+}
+
+// 空き状況だけを返すときの予定の件名
+const busySummary = "予定あり"
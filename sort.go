@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --sort に指定できる並べ方
+var sortKeys = []string{"start", "duration", "summary", "calendar"}
+
+// 並べ替えに使う予定の値
+type sortFields struct {
+	start    time.Time
+	duration time.Duration
+	summary  string
+	calendar string
+}
+
+func checkSortKey(key string) error {
+	for _, k := range sortKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unsupported sort key %q (expected %s)", key, strings.Join(sortKeys, ", "))
+}
+
+// key で比べ、同じなら開始時刻、長さ、件名、カレンダーの順に比べる
+//
+// どのカレンダーから取得しても、API が返す順序によらず同じ並びになる。
+func compareSortFields(a, b sortFields, key string) int {
+	byStart := func() int { return a.start.Compare(b.start) }
+	byDuration := func() int {
+		switch {
+		case a.duration < b.duration:
+			return -1
+		case a.duration > b.duration:
+			return 1
+		}
+		return 0
+	}
+	bySummary := func() int { return strings.Compare(a.summary, b.summary) }
+	byCalendar := func() int { return strings.Compare(a.calendar, b.calendar) }
+
+	order := []func() int{byStart, byDuration, bySummary, byCalendar}
+	switch key {
+	case "duration":
+		order = []func() int{byDuration, byStart, bySummary, byCalendar}
+	case "summary":
+		order = []func() int{bySummary, byStart, byDuration, byCalendar}
+	case "calendar":
+		order = []func() int{byCalendar, byStart, byDuration, bySummary}
+	}
+	for _, cmp := range order {
+		if c := cmp(); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// 予定を並べ替えた一覧を返す（元の一覧は変更しない）
+func sortEvents(events []agendaEvent, key string, reverse bool) []agendaEvent {
+	out := append([]agendaEvent{}, events...)
+	sort.SliceStable(out, func(i, j int) bool {
+		c := compareSortFields(eventSortFields(out[i]), eventSortFields(out[j]), key)
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	return out
+}
+
+func eventSortFields(e agendaEvent) sortFields {
+	return sortFields{start: e.start, duration: e.duration(), summary: e.item.Summary, calendar: e.calendarID}
+}
+
+func jsonSortFields(e jsonEvent) sortFields {
+	parse := func(s string) time.Time {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+		t, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return t
+	}
+	start, end := parse(e.Start), parse(e.End)
+	return sortFields{start: start, duration: end.Sub(start), summary: e.Summary, calendar: e.Calendar}
+}
+
+// JSON の予定を並べ替える
+func sortJSONEvents(events []jsonEvent, key string, reverse bool) {
+	fields := make([]sortFields, len(events))
+	for i, e := range events {
+		fields[i] = jsonSortFields(e)
+	}
+	sort.Stable(jsonEventSorter{events, fields, key, reverse})
+}
+
+type jsonEventSorter struct {
+	events  []jsonEvent
+	fields  []sortFields
+	key     string
+	reverse bool
+}
+
+func (s jsonEventSorter) Len() int { return len(s.events) }
+func (s jsonEventSorter) Less(i, j int) bool {
+	c := compareSortFields(s.fields[i], s.fields[j], s.key)
+	if s.reverse {
+		return c > 0
+	}
+	return c < 0
+}
+func (s jsonEventSorter) Swap(i, j int) {
+	s.events[i], s.events[j] = s.events[j], s.events[i]
+	s.fields[i], s.fields[j] = s.fields[j], s.fields[i]
+}
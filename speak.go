@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// speak の読み上げの設定
+type speakConfig struct {
+	// say、espeak、google、text（省略時は macOS なら say、espeak があれば espeak、なければ text）
+	Backend string `json:"backend,omitempty"`
+	// 声の名前（say の -v、espeak の -v、Google の voice.name）
+	Voice string `json:"voice,omitempty"`
+	// Text-to-Speech API のキー（省略時は環境変数 GOOGLE_TTS_API_KEY）
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// 読み上げる文の言い回し
+type speakPhrases struct {
+	greeting string
+	// 日付と件数（"6月14日、金曜日の予定は3件です。"）
+	count func(day time.Time, n int) string
+	none  func(day time.Time) string
+	// 時間指定の予定と終日の予定の1件
+	timed  func(start time.Time, summary string) string
+	allDay func(summary string) string
+	// Google の Text-to-Speech API の言語コード
+	languageCode string
+}
+
+var speakLanguages = map[string]speakPhrases{
+	"ja": {
+		greeting: "おはようございます。",
+		count: func(day time.Time, n int) string {
+			return fmt.Sprintf("%d月%d日、%s曜日の予定は%d件です。", day.Month(), day.Day(), weekdayNames["ja"][day.Weekday()], n)
+		},
+		none: func(day time.Time) string {
+			return fmt.Sprintf("%d月%d日、%s曜日の予定はありません。", day.Month(), day.Day(), weekdayNames["ja"][day.Weekday()])
+		},
+		timed: func(start time.Time, summary string) string {
+			if start.Minute() == 0 {
+				return fmt.Sprintf("%d時から、%s。", start.Hour(), summary)
+			}
+			return fmt.Sprintf("%d時%d分から、%s。", start.Hour(), start.Minute(), summary)
+		},
+		allDay: func(summary string) string {
+			return fmt.Sprintf("終日、%s。", summary)
+		},
+		languageCode: "ja-JP",
+	},
+	"en": {
+		greeting: "Good morning.",
+		count: func(day time.Time, n int) string {
+			events := "events"
+			if n == 1 {
+				events = "event"
+			}
+			return fmt.Sprintf("You have %d %s on %s.", n, events, day.Format("Monday, January 2"))
+		},
+		none: func(day time.Time) string {
+			return fmt.Sprintf("You have no events on %s.", day.Format("Monday, January 2"))
+		},
+		timed: func(start time.Time, summary string) string {
+			return fmt.Sprintf("At %s, %s.", start.Format("3:04 PM"), summary)
+		},
+		allDay: func(summary string) string {
+			return fmt.Sprintf("All day, %s.", summary)
+		},
+		languageCode: "en-US",
+	},
+}
+
+// 1日分の予定を文章にして読み上げる
+func setupSpeak(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Date to read out (format: YYYY-MM-DD)")
+	lang := fs.String("lang", "ja", "Language of the sentences: ja or en")
+	backend := fs.String("backend", "", "Speech backend: say, espeak, google or text (default: speak.backend in the config, or detected)")
+	voice := fs.String("voice", "", "Voice name passed to the backend (default: speak.voice in the config)")
+	output := fs.String("output", "", "With --backend google, write the MP3 to this file instead of stdout")
+	redact := fs.Bool("redact-private", false, "Hide titles of private and confidential events")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		phrases, ok := speakLanguages[*lang]
+		if !ok {
+			return fmt.Errorf("Invalid --lang %q (use ja or en)", *lang)
+		}
+		targetDate, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		sc := cfg.Speak
+		if *backend != "" {
+			sc.Backend = *backend
+		}
+		if *voice != "" {
+			sc.Voice = *voice
+		}
+		if sc.Backend == "" {
+			sc.Backend = detectSpeechBackend()
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		startTime, endTime := dayWindow(targetDate)
+		res, err := client.ListEvents(ctx, "primary", startTime, endTime)
+		if err != nil {
+			return err
+		}
+		events := standupEvents(selectDay(normalizeEvents("primary", res.Items), targetDate), time.Time{})
+		if *redact {
+			events = redactPrivate(events)
+		}
+		text := speechText(phrases, targetDate, events)
+		return sc.speak(ctx, text, phrases.languageCode, *output)
+	}
+}
+
+// 予定を読み上げる文章にする（キャンセル・欠席・「空き時間」の予定は standupEvents で除いておく）
+func speechText(p speakPhrases, day time.Time, events []agendaEvent) string {
+	sentences := []string{p.greeting}
+	if len(events) == 0 {
+		sentences = append(sentences, p.none(day))
+	} else {
+		sentences = append(sentences, p.count(day, len(events)))
+	}
+	for _, e := range events {
+		summary := strings.TrimSpace(e.item.Summary)
+		if e.allDay {
+			sentences = append(sentences, p.allDay(summary))
+		} else {
+			sentences = append(sentences, p.timed(e.start, summary))
+		}
+	}
+	sep := " "
+	if p.languageCode == "ja-JP" {
+		sep = ""
+	}
+	return strings.Join(sentences, sep)
+}
+
+// 読み上げに使うコマンド（macOS は say、espeak-ng か espeak があればそれ、なければ文章を出力するだけ）
+func detectSpeechBackend() string {
+	if runtime.GOOS == "darwin" {
+		return "say"
+	}
+	if _, err := exec.LookPath("espeak-ng"); err == nil {
+		return "espeak"
+	}
+	if _, err := exec.LookPath("espeak"); err == nil {
+		return "espeak"
+	}
+	return "text"
+}
+
+// text を読み上げる（google は MP3 を output か標準出力に書き出す）
+func (c speakConfig) speak(ctx context.Context, text, languageCode, output string) error {
+	switch c.Backend {
+	case "text":
+		fmt.Println(text)
+		return nil
+	case "say":
+		args := []string{}
+		if c.Voice != "" {
+			args = append(args, "-v", c.Voice)
+		}
+		return runSpeechCommand(exec.CommandContext(ctx, "say", args...), text)
+	case "espeak":
+		name := "espeak-ng"
+		if _, err := exec.LookPath(name); err != nil {
+			name = "espeak"
+		}
+		args := []string{"--stdin"}
+		if c.Voice != "" {
+			args = append(args, "-v", c.Voice)
+		}
+		return runSpeechCommand(exec.CommandContext(ctx, name, args...), text)
+	case "google":
+		audio, err := c.synthesize(ctx, text, languageCode)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = os.Stdout.Write(audio)
+			return err
+		}
+		return os.WriteFile(output, audio, 0o644)
+	}
+	return fmt.Errorf("Unknown speech backend %q (use say, espeak, google or text)", c.Backend)
+}
+
+func runSpeechCommand(cmd *exec.Cmd, text string) error {
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Unable to run %s: %w", cmd.Path, err)
+	}
+	return nil
+}
+
+// Google の Text-to-Speech API で text を MP3 にする
+func (c speakConfig) synthesize(ctx context.Context, text, languageCode string) ([]byte, error) {
+	key := c.APIKey
+	if key == "" {
+		key = os.Getenv("GOOGLE_TTS_API_KEY")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("The google speech backend needs speak.api_key in the config or GOOGLE_TTS_API_KEY")
+	}
+	voice := map[string]string{"languageCode": languageCode}
+	if c.Voice != "" {
+		voice["name"] = c.Voice
+	}
+	body, err := json.Marshal(map[string]any{
+		"input":       map[string]string{"text": text},
+		"voice":       voice,
+		"audioConfig": map[string]string{"audioEncoding": "MP3"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := "https://texttospeech.googleapis.com/v1/text:synthesize?" + url.Values{"key": {key}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to call Text-to-Speech API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Text-to-Speech API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		AudioContent string `json:"audioContent"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("Unable to parse Text-to-Speech response: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(out.AudioContent)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// standup の見出しと表記
+type standupLabels struct {
+	yesterday, today, none, allDay string
+}
+
+var standupLanguages = map[string]standupLabels{
+	"en": {"Yesterday", "Today", "None", "All day"},
+	"ja": {"昨日", "今日", "なし", "終日"},
+}
+
+// 前の営業日の予定と今日これからの予定を、スタンドアップのスレッドに貼れる形式で出力する
+func setupStandup(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to treat as today (format: YYYY-MM-DD)")
+	format := fs.String("format", "markdown", "Output format: markdown or slack")
+	lang := fs.String("lang", "ja", "Language of the headings: ja or en")
+	redact := fs.Bool("redact-private", false, "Hide titles of private and confidential events")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if *format != "markdown" && *format != "slack" {
+			return fmt.Errorf("Invalid --format %q (use markdown or slack)", *format)
+		}
+		labels, ok := standupLanguages[*lang]
+		if !ok {
+			return fmt.Errorf("Invalid --lang %q (use ja or en)", *lang)
+		}
+		today, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		// 月曜日は金曜日を振り返る
+		prev := today.AddDate(0, 0, -1)
+		for !isBusinessDay(prev) {
+			prev = prev.AddDate(0, 0, -1)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		events, err := fetchRange(ctx, client, prev, today)
+		if err != nil {
+			return err
+		}
+		normalized := normalizeEvents("primary", events.Items)
+		past := standupEvents(selectDay(normalized, prev), time.Time{})
+		// 今日を表示する場合は、終わった予定を除く
+		var now time.Time
+		if *dateStr == "" {
+			now = time.Now()
+		}
+		upcoming := standupEvents(selectDay(normalized, today), now)
+		if *redact {
+			past, upcoming = redactPrivate(past), redactPrivate(upcoming)
+		}
+		printStandup(os.Stdout, *format, labels, past, upcoming)
+		return nil
+	}
+}
+
+// スタンドアップに載せる予定（キャンセル・欠席・「空き時間」と、now より前に終わった予定を除く）
+func standupEvents(events []agendaEvent, now time.Time) []agendaEvent {
+	var out []agendaEvent
+	for _, e := range sortEvents(events, "start", false) {
+		item := e.item
+		if item.Status == "cancelled" || attendanceStatus(item) == "declined" || !isBusy(item, false) {
+			continue
+		}
+		if !now.IsZero() && !e.end.After(now) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// 2つの見出しと予定の箇条書きを出力する（予定がなければ「なし」）
+func printStandup(w io.Writer, format string, labels standupLabels, past, upcoming []agendaEvent) {
+	heading, bullet := "**%s**\n", "- "
+	if format == "slack" {
+		heading, bullet = "*%s*\n", "• "
+	}
+	for i, events := range [][]agendaEvent{past, upcoming} {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, heading, []string{labels.yesterday, labels.today}[i])
+		if len(events) == 0 {
+			fmt.Fprintln(w, bullet+labels.none)
+		}
+		for _, e := range events {
+			fmt.Fprintln(w, bullet+standupLine(e, labels))
+		}
+	}
+}
+
+// 「10:00-11:00 定例」（終日の予定は「終日 件名」）
+func standupLine(e agendaEvent, labels standupLabels) string {
+	summary := strings.TrimSpace(e.item.Summary)
+	if e.allDay {
+		return labels.allDay + " " + summary
+	}
+	return e.start.Format("15:04") + "-" + e.end.Format("15:04") + " " + summary
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// リマインダーの通知状況を保存するファイル
+const defaultStateFile = "reminders.json"
+
+// 通知済み・スヌーズ・確認済みの状態を保持する
+//
+// キーは reminderKey で作る「イベントID@開始時刻」（通知済みはさらに "/リード時間" 付き）。
+// 開始時刻を含めるため、予定が移動された場合は改めて通知される。
+type reminderState struct {
+	Delivered map[string]time.Time `json:"delivered"`
+	Snoozed   map[string]time.Time `json:"snoozed"`
+	Acked     map[string]time.Time `json:"acked"`
+}
+
+// reminderState を永続化するストア
+type stateStore struct {
+	backend stateBackend
+
+	mu    sync.Mutex
+	state reminderState
+}
+
+// spec はファイルのパスか Redis の URL（newStateBackend を参照）
+func openStateStore(spec string) (*stateStore, error) {
+	backend, err := newStateBackend(spec)
+	if err != nil {
+		return nil, err
+	}
+	s := &stateStore{backend: backend}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 保存先から状態を読み直す（snooze や ack コマンド、ほかの端末の常駐モードによる変更を取り込むため）
+func (s *stateStore) reload() error {
+	b, err := s.backend.load()
+	if err != nil {
+		return err
+	}
+	state, err := parseReminderState(b)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+	return nil
+}
+
+func parseReminderState(b []byte) (reminderState, error) {
+	state := reminderState{}
+	if b != nil {
+		if err := json.Unmarshal(b, &state); err != nil {
+			return reminderState{}, fmt.Errorf("Unable to parse state file: %w", err)
+		}
+	}
+	if state.Delivered == nil {
+		state.Delivered = map[string]time.Time{}
+	}
+	if state.Snoozed == nil {
+		state.Snoozed = map[string]time.Time{}
+	}
+	if state.Acked == nil {
+		state.Acked = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+// 保存先の最新の状態を update で変更して書き込む
+//
+// update はほかの端末が書き込んだ状態に対して呼ばれるため、手元で読み込んだ状態が古くても変更は失われない。
+func (s *stateStore) update(update func(state *reminderState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.backend.modify(func(old []byte) ([]byte, error) {
+		state, err := parseReminderState(old)
+		if err != nil {
+			return nil, err
+		}
+		update(&state)
+		state.prune(time.Now().AddDate(0, 0, -7))
+		b, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Unable to encode state: %w", err)
+		}
+		s.state = state
+		return append(b, '\n'), nil
+	})
+}
+
+// view で現在の状態を参照する
+func (s *stateStore) view(view func(state *reminderState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	view(&s.state)
+}
+
+// before より前に記録された状態を捨てる
+func (st *reminderState) prune(before time.Time) {
+	for _, m := range []map[string]time.Time{st.Delivered, st.Acked} {
+		for k, t := range m {
+			if t.Before(before) {
+				delete(m, k)
+			}
+		}
+	}
+	for k, t := range st.Snoozed {
+		if t.Before(before) {
+			delete(st.Snoozed, k)
+		}
+	}
+}
+
+// キーがイベントID（またはキーそのもの）に一致するかどうか
+func keyMatchesEvent(key, eventID string) bool {
+	return key == eventID || strings.HasPrefix(key, eventID+"@")
+}
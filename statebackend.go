@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gcal-daily-agenda/redis"
+)
+
+// 通知済みの状態の保存先
+//
+// 複数の端末で常駐モードを動かすときは、同じ保存先を共有すると二重に通知しない。
+type stateBackend interface {
+	// 保存されている状態（まだなければ nil）
+	load() ([]byte, error)
+	// 保存されている状態を modify で変更する
+	//
+	// 読み込みから書き込みまでの間にほかの端末が変更しても、その変更を失わないようにする。
+	modify(modify func(old []byte) ([]byte, error)) error
+}
+
+// --state の値から保存先を作る（redis:// か rediss:// で始まれば Redis、それ以外はファイル）
+func newStateBackend(spec string) (stateBackend, error) {
+	if !strings.HasPrefix(spec, "redis://") && !strings.HasPrefix(spec, "rediss://") {
+		return &fileStateBackend{path: spec}, nil
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid state URL: %w", err)
+	}
+	key := u.Query().Get("key")
+	if key == "" {
+		key = progName + ":reminders"
+	}
+	u.RawQuery = ""
+	return &redisStateBackend{url: u.String(), key: key}, nil
+}
+
+// ファイル（同期しているドライブやネットワークドライブに置いてもよい）に保存する
+type fileStateBackend struct {
+	path string
+}
+
+// ロックを待つ時間と、残っていても古いとみなすロック（落ちたプロセスのもの）の経過時間
+const (
+	stateLockWait  = 5 * time.Second
+	stateLockStale = 30 * time.Second
+)
+
+func (b *fileStateBackend) load() ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read state file: %w", err)
+	}
+	return data, nil
+}
+
+// ロックファイルを作ってからファイルを読み直して変更する
+func (b *fileStateBackend) modify(modify func(old []byte) ([]byte, error)) error {
+	unlock, err := lockFile(b.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	old, err := b.load()
+	if err != nil {
+		return err
+	}
+	data, err := modify(old)
+	if err != nil {
+		return err
+	}
+	// 書き込み途中で落ちても壊れないように一時ファイルから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+	if err != nil {
+		return fmt.Errorf("Unable to write state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Unable to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Unable to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("Unable to write state file: %w", err)
+	}
+	return nil
+}
+
+// path を排他的に作成してロックし、ロックを外す関数を返す
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(stateLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("Unable to lock state file: %w", err)
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > stateLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Unable to lock state file: %s is held by another process", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Redis の1つのキーに保存する
+type redisStateBackend struct {
+	url, key string
+}
+
+// Redis への1回の操作の時間の上限
+const redisTimeout = 5 * time.Second
+
+// 同時に変更されて書き込みが中止されたときに読み直す回数
+const redisMaxRetries = 10
+
+func (b *redisStateBackend) dial() (*redis.Client, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	c, err := redis.Dial(ctx, b.url)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("Unable to connect to state server: %w", err)
+	}
+	return c, cancel, nil
+}
+
+func (b *redisStateBackend) load() ([]byte, error) {
+	c, cancel, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer c.Close()
+	v, err := c.Do("GET", b.key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read state: %w", err)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return []byte(v.(string)), nil
+}
+
+// WATCH したキーを読んで MULTI/EXEC で書き込む（ほかの端末が先に書き込んでいればやり直す）
+func (b *redisStateBackend) modify(modify func(old []byte) ([]byte, error)) error {
+	c, cancel, err := b.dial()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer c.Close()
+	for i := 0; i < redisMaxRetries; i++ {
+		if _, err := c.Do("WATCH", b.key); err != nil {
+			return fmt.Errorf("Unable to write state: %w", err)
+		}
+		v, err := c.Do("GET", b.key)
+		if err != nil {
+			return fmt.Errorf("Unable to read state: %w", err)
+		}
+		var old []byte
+		if v != nil {
+			old = []byte(v.(string))
+		}
+		data, err := modify(old)
+		if err != nil {
+			c.Do("UNWATCH")
+			return err
+		}
+		if _, err := c.Do("MULTI"); err != nil {
+			return fmt.Errorf("Unable to write state: %w", err)
+		}
+		if _, err := c.Do("SET", b.key, string(data)); err != nil {
+			return fmt.Errorf("Unable to write state: %w", err)
+		}
+		res, err := c.Do("EXEC")
+		if err != nil {
+			return fmt.Errorf("Unable to write state: %w", err)
+		}
+		if res != nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unable to write state: the state kept changing during %d attempts", redisMaxRetries)
+}
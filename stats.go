@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 集中して作業する時間の目標
+type focusConfig struct {
+	// 集中時間として数える予定の色（省略時は予定の種類が「サイレント モード」のものだけ）
+	Color string `json:"color,omitempty"`
+	// 1週間の目標（時間）
+	WeeklyHours float64 `json:"weekly_hours,omitempty"`
+	// 今週の予定が目標に届かないとき、1日分の予定の見出しに警告を表示する
+	Warn bool `json:"warn,omitempty"`
+}
+
+// 集中時間として数える予定か
+func (c focusConfig) isFocus(item *calendar.Event) bool {
+	return item.EventType == "focusTime" || (c.Color != "" && item.ColorId == c.Color)
+}
+
+// 期間の予定の集計
+type agendaStats struct {
+	from, to time.Time
+	meetings int
+	meeting  time.Duration
+	focus    time.Duration
+	// 会議の費用の見積もりの合計
+	cost float64
+	// タグごとの予定の時間
+	byTag map[string]time.Duration
+}
+
+// その日（または週）の会議の時間と集中時間を集計し、集中時間の目標に対する進み具合を表示する
+func setupStats(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to look at (format: YYYY-MM-DD, default today)")
+	week := fs.Bool("week", false, "Look at the whole week (Monday to Sunday) containing --date")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file (for the focus goal and meeting cost)")
+	includeFree := fs.Bool("include-free", false, "Count events marked as free (transparent) as meetings")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		from, to := date, date
+		if *week {
+			from, to = weekRange(date)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		st, err := collectStats(ctx, client, cfg.Focus, cfg.Cost, from, to, *includeFree)
+		if err != nil {
+			return err
+		}
+		goal := 0.0
+		if *week {
+			goal = cfg.Focus.WeeklyHours
+		}
+		printStats(os.Stdout, st, goal, cfg.Cost)
+		return nil
+	}
+}
+
+func collectStats(ctx context.Context, client calendarClient, focus focusConfig, cost costConfig, from, to time.Time, includeFree bool) (agendaStats, error) {
+	events, err := fetchRange(ctx, client, from, to)
+	if err != nil {
+		return agendaStats{}, err
+	}
+	st := agendaStats{from: from, to: to, byTag: map[string]time.Duration{}}
+	for _, day := range daysBetween(from, to) {
+		var meetings, focused []*calendar.Event
+		for _, item := range eventsForDay(events.Items, day) {
+			if focus.isFocus(item) {
+				focused = append(focused, item)
+			} else {
+				meetings = append(meetings, item)
+			}
+		}
+		st.meeting += busyTime(meetings, day, includeFree)
+		st.focus += busyTime(focused, day, includeFree)
+		tagged := map[string][]*calendar.Event{}
+		for _, item := range eventsForDay(events.Items, day) {
+			for _, t := range eventTags(item) {
+				tagged[strings.ToLower(t)] = append(tagged[strings.ToLower(t)], item)
+			}
+		}
+		for t, items := range tagged {
+			st.byTag[t] += busyTime(items, day, includeFree)
+		}
+		for _, item := range meetings {
+			start, _ := eventTimes(item)
+			// 日をまたぐ予定は始まった日にだけ数える
+			if item.Start.DateTime != "" && isBusy(item, includeFree) && attendanceStatus(item) != "declined" && sameDay(start, day) {
+				st.meetings++
+				c, _ := cost.eventCost(item)
+				st.cost += c
+			}
+		}
+	}
+	return st, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	b = b.In(a.Location())
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func printStats(w io.Writer, st agendaStats, goal float64, cost costConfig) {
+	period := st.from.Format("2006-01-02")
+	if !st.to.Equal(st.from) {
+		period += "〜" + st.to.Format("2006-01-02")
+	}
+	fmt.Fprintf(w, "%sの集計:\n", period)
+	fmt.Fprintf(w, "  会議: %.1f時間（%d件）\n", st.meeting.Hours(), st.meetings)
+	if goal > 0 {
+		fmt.Fprintf(w, "  集中: %.1f時間 / 目標 %.1f時間（%.0f%%）\n", st.focus.Hours(), goal, 100*st.focus.Hours()/goal)
+	} else {
+		fmt.Fprintf(w, "  集中: %.1f時間\n", st.focus.Hours())
+	}
+	if cost.enabled() {
+		fmt.Fprintf(w, "  会議の費用: %s\n", cost.format(st.cost))
+	}
+	if len(st.byTag) > 0 {
+		fmt.Fprintln(w, "  タグ別:")
+		for _, t := range sortedTagKeys(st.byTag) {
+			fmt.Fprintf(w, "    #%s %.1f時間\n", t, st.byTag[t].Hours())
+		}
+	}
+}
+
+// 今週の集中時間の予定が目標に届かない場合の警告（届いていれば空）
+func focusWarning(ctx context.Context, client calendarClient, focus focusConfig, date time.Time) (string, error) {
+	if !focus.Warn || focus.WeeklyHours <= 0 {
+		return "", nil
+	}
+	from, to := weekRange(date)
+	st, err := collectStats(ctx, client, focus, costConfig{}, from, to, false)
+	if err != nil {
+		return "", err
+	}
+	if st.focus.Hours() >= focus.WeeklyHours {
+		return "", nil
+	}
+	return fmt.Sprintf("⚠ 今週の集中時間は %.1f時間の予定です（目標 %.1f時間まであと %.1f時間）",
+		st.focus.Hours(), focus.WeeklyHours, focus.WeeklyHours-st.focus.Hours()), nil
+}
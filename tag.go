@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 予定のタグを追加・削除する（tag <event-id> +deepwork -tentative）
+//
+// タグは拡張プロパティに保存する。説明欄の "#tag" は変更しない。
+func setupTag(fs *flag.FlagSet) func(args []string) error {
+	calendarID := fs.String("calendar", "primary", "Calendar that contains the event")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("Usage: %s tag <event-id> +tag [-tag ...]", progName)
+		}
+		var add, remove []string
+		for _, a := range args[1:] {
+			if a == "" {
+				return fmt.Errorf("Invalid tag %q (expected +tag or -tag)", a)
+			}
+			name := strings.TrimPrefix(a[1:], "#")
+			switch {
+			case strings.HasPrefix(a, "+") && name != "":
+				add = append(add, name)
+			case strings.HasPrefix(a, "-") && name != "":
+				remove = append(remove, name)
+			default:
+				return fmt.Errorf("Invalid tag %q (expected +tag or -tag)", a)
+			}
+			if strings.Contains(name, ",") {
+				return fmt.Errorf("Invalid tag %q (tags cannot contain commas)", a)
+			}
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		event, err := client.GetEvent(ctx, *calendarID, args[0])
+		if err != nil {
+			return err
+		}
+		updated := *event
+		updated.ExtendedProperties = withTags(event.ExtendedProperties, add, remove)
+		if _, err := client.UpdateEvent(ctx, *calendarID, &updated); err != nil {
+			return err
+		}
+		tags := eventTags(&updated)
+		if len(tags) == 0 {
+			fmt.Printf("「%s」のタグ: なし\n", event.Summary)
+			return nil
+		}
+		fmt.Printf("「%s」のタグ: %s\n", event.Summary, formatTags(tags))
+		return nil
+	}
+}
+
+// 拡張プロパティのタグに add を加え、remove を除いたものを返す（元の拡張プロパティは変更しない）
+//
+// 大文字と小文字は区別せずに比べ、既にあるタグは元の書き方のまま残す。
+func withTags(props *calendar.EventExtendedProperties, add, remove []string) *calendar.EventExtendedProperties {
+	out := &calendar.EventExtendedProperties{Private: map[string]string{}}
+	var current string
+	if props != nil {
+		out.Shared = props.Shared
+		for k, v := range props.Private {
+			out.Private[k] = v
+		}
+		current = props.Private[tagsProperty]
+	}
+
+	var tags []string
+	has := func(t string) bool {
+		for _, x := range tags {
+			if strings.EqualFold(x, t) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range strings.Split(current, ",") {
+		if t = strings.TrimSpace(t); t != "" && !has(t) {
+			tags = append(tags, t)
+		}
+	}
+	for _, t := range add {
+		if !has(t) {
+			tags = append(tags, t)
+		}
+	}
+	kept := tags[:0]
+	for _, t := range tags {
+		removed := false
+		for _, r := range remove {
+			if strings.EqualFold(t, r) {
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(kept) == 0 {
+		// 予定全体を送り直すので、キーを除けばプロパティも削除される
+		delete(out.Private, tagsProperty)
+		return out
+	}
+	out.Private[tagsProperty] = strings.Join(kept, ",")
+	return out
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// タグを保存する非公開の拡張プロパティ（カンマ区切り）
+const tagsProperty = "gcalDailyAgendaTags"
+
+// 説明欄の "#deepwork" の形式のタグ（"#123" のような数字だけのものは課題番号として扱う）
+var descriptionTagPattern = regexp.MustCompile(`(?:^|[\s(>])#([\p{L}\p{N}_-]*[\p{L}_-][\p{L}\p{N}_-]*)`)
+
+// 予定のタグ（拡張プロパティのタグ、説明欄のタグの順に、大文字と小文字を区別せず重複を除く）
+func eventTags(item *calendar.Event) []string {
+	var tags []string
+	seen := map[string]bool{}
+	add := func(t string) {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[strings.ToLower(t)] {
+			return
+		}
+		seen[strings.ToLower(t)] = true
+		tags = append(tags, t)
+	}
+	if p := item.ExtendedProperties; p != nil {
+		for _, t := range strings.Split(p.Private[tagsProperty], ",") {
+			add(t)
+		}
+	}
+	for _, m := range descriptionTagPattern.FindAllStringSubmatch(item.Description, -1) {
+		add(m[1])
+	}
+	return tags
+}
+
+// tags のいずれかが付いた予定だけを返す（tags が空ならすべて）
+func filterTags(events []agendaEvent, tags []string) []agendaEvent {
+	if len(tags) == 0 {
+		return events
+	}
+	var out []agendaEvent
+	for _, e := range events {
+		if hasAnyTag(e.item, tags) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func hasAnyTag(item *calendar.Event, tags []string) bool {
+	for _, t := range eventTags(item) {
+		for _, want := range tags {
+			if strings.EqualFold(t, strings.TrimPrefix(want, "#")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// タグの表示（"#deepwork #client-a"）
+func formatTags(tags []string) string {
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = "#" + t
+	}
+	return strings.Join(out, " ")
+}
+
+// タグを名前順に並べたキー（stats のタグ別の集計用）
+func sortedTagKeys(m map[string]time.Duration) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// チームの予定表に出す1人分の予定の入っている時間帯
+type teamBlock struct {
+	interval interval
+	// "集中"（フォーカスタイム）や "不在"。通常の予定は空
+	kind string
+}
+
+// 自分とチームのメンバーの1日の予定を、件名を伏せて時間帯だけ並べて表示する
+func setupTeam(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to show (format: YYYY-MM-DD, default today)")
+	opts := availabilityOptions{lang: "ja"}
+	workStart := fs.String("work-start", "09:00", "Start of working hours for common free time (HH:MM)")
+	workEnd := fs.String("work-end", "18:00", "End of working hours for common free time (HH:MM)")
+	fs.DurationVar(&opts.minSlot, "min-slot", 30*time.Minute, "Minimum length of a common free slot")
+	fs.BoolVar(&opts.includeFree, "include-free", false, "Treat events marked as free (transparent) as busy")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Team) == 0 {
+			return fmt.Errorf("No team members in %s (add \"team\": [{\"name\": ..., \"calendar\": ...}])", *configPath)
+		}
+		if opts.workStart, err = parseClock(*workStart); err != nil {
+			return err
+		}
+		if opts.workEnd, err = parseClock(*workEnd); err != nil {
+			return err
+		}
+		if opts.workEnd <= opts.workStart {
+			return fmt.Errorf("--work-end must be after --work-start")
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		members := append([]namedCalendar{{Name: "自分", Calendar: "primary"}}, cfg.Team...)
+		return runTeam(ctx, client, os.Stdout, day, time.Now(), members, opts)
+	}
+}
+
+func runTeam(ctx context.Context, client calendarClient, w io.Writer, day, now time.Time, members []namedCalendar, opts availabilityOptions) error {
+	next := day.AddDate(0, 0, 1)
+	fmt.Fprintf(w, "%s(%s)のチームの予定:\n", day.Format("2006-01-02"), weekdayNames["ja"][day.Weekday()])
+
+	// 名前の幅をそろえて予定の列を合わせる
+	var names []string
+	for _, m := range members {
+		names = append(names, m.Name+":")
+	}
+	nameWidth := maxWidth(names)
+
+	var busy []interval
+	for _, m := range members {
+		blocks, err := memberBlocks(ctx, client, m.Calendar, day, next, opts.includeFree)
+		if err != nil {
+			if isAuthError(err) {
+				return err
+			}
+			slog.Warn("Skipping team calendar", "name", m.Name, "calendar", m.Calendar, "error", err)
+			fmt.Fprintf(w, "%s 取得できませんでした\n", padRight(m.Name+":", nameWidth))
+			continue
+		}
+		for _, b := range blocks {
+			busy = append(busy, b.interval)
+		}
+		fmt.Fprintf(w, "%s %s\n", padRight(m.Name+":", nameWidth), formatTeamBlocks(blocks, day))
+	}
+
+	// 過去や未来の日は現在時刻で空き時間を切り詰めない
+	if now.Before(day) || !now.Before(next) {
+		now = day
+	}
+	var ranges []string
+	for _, s := range freeSlots(day, now, busy, opts) {
+		ranges = append(ranges, s.start.Format("15:04")+"〜"+s.end.Format("15:04"))
+	}
+	if len(ranges) == 0 {
+		fmt.Fprintln(w, "全員の空き時間: なし")
+		return nil
+	}
+	fmt.Fprintf(w, "全員の空き時間: %s\n", strings.Join(ranges, "、"))
+	return nil
+}
+
+// カレンダーの day から next までの予定の時間帯
+//
+// 予定の詳細を見る権限がないカレンダーは FreeBusy で予定の入っている時間帯だけを取得する（「空き時間」の予定は含まれない）。
+func memberBlocks(ctx context.Context, client calendarClient, calendarID string, day, next time.Time, includeFree bool) ([]teamBlock, error) {
+	events, err := client.ListEvents(ctx, calendarID, day, next)
+	if err != nil {
+		if isAuthError(err) {
+			return nil, err
+		}
+		busy, fbErr := client.FreeBusy(ctx, []string{calendarID}, day, next)
+		if fbErr != nil {
+			return nil, err
+		}
+		var blocks []teamBlock
+		for _, iv := range mergeIntervals(busy) {
+			blocks = append(blocks, teamBlock{interval: iv})
+		}
+		return blocks, nil
+	}
+
+	var blocks []teamBlock
+	for _, item := range events.Items {
+		if item.Status == "cancelled" || attendanceStatus(item) == "declined" {
+			continue
+		}
+		var kind string
+		switch {
+		case isAbsence(item):
+			kind = "不在"
+		case item.Start.DateTime == "" || !isBusy(item, includeFree):
+			continue
+		case item.EventType == "focusTime":
+			kind = "集中"
+		}
+		iv := absenceInterval(item)
+		if !iv.start.Before(next) || !iv.end.After(day) {
+			continue
+		}
+		blocks = append(blocks, teamBlock{interval: iv, kind: kind})
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].interval.start.Before(blocks[j].interval.start) })
+	return blocks, nil
+}
+
+// "09:00-10:00、13:00-15:00（集中）"
+func formatTeamBlocks(blocks []teamBlock, day time.Time) string {
+	if len(blocks) == 0 {
+		return "予定なし"
+	}
+	next := day.AddDate(0, 0, 1)
+	var parts []string
+	for _, b := range blocks {
+		var s string
+		if !b.interval.start.After(day) && !b.interval.end.Before(next) {
+			s = "終日"
+		} else {
+			s = clampClock(b.interval.start, day, next) + "-" + clampClock(b.interval.end, day, next)
+		}
+		if b.kind != "" {
+			s += "（" + b.kind + "）"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "、")
+}
+
+// day の範囲に収めた時刻（翌日の 0 時は 24:00）
+func clampClock(t, day, next time.Time) string {
+	switch {
+	case !t.After(day):
+		return "00:00"
+	case !t.Before(next):
+		return "24:00"
+	}
+	return t.Format("15:04")
+}
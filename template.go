@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 設定ファイルのテンプレートから1日分の予定を作成する（template apply 名前）
+func setupTemplate(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to create the events on (format: YYYY-MM-DD, default today)")
+	calendarID := fs.String("calendar", "primary", "Calendar to create the events on")
+	dryRun := fs.Bool("dry-run", false, "Print the changes without applying them")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(args) == 1 && args[0] == "list":
+			printTemplates(cfg.Templates)
+			return nil
+		case len(args) == 2 && args[0] == "apply":
+		default:
+			return fmt.Errorf("Usage: %s template list | template apply <name> [--date YYYY-MM-DD] [--dry-run]", progName)
+		}
+
+		name := args[1]
+		specs, ok := cfg.Templates[name]
+		if !ok {
+			return fmt.Errorf("No template named %q in %s", name, *configPath)
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := newWriteClient(ctx, *fromFixture, calendar.CalendarEventsScope)
+		if err != nil {
+			return err
+		}
+		res, err := applySpecs(ctx, client, os.Stdout, *calendarID, date, specs, applyOptions{source: "template/" + name, dryRun: *dryRun})
+		if err != nil {
+			return err
+		}
+		res.print(os.Stdout)
+		return nil
+	}
+}
+
+func printTemplates(templates map[string][]eventSpec) {
+	if len(templates) == 0 {
+		fmt.Println("テンプレートはありません。")
+		return
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s:\n", name)
+		for _, spec := range templates[name] {
+			fmt.Printf("  %s\n", spec)
+		}
+	}
+}
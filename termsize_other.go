@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+// 桁数を取得できない環境では幅を制限しない
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 予定の一覧の見た目（端末の色、色の代わりの記号、見出しの強調）
+type theme struct {
+	// カラーIDごとの文字色（SGR のパラメータ、"" は色を付けない）
+	colors map[string]string
+	// カラーIDごとに色名の前に付ける記号（色を見分けにくくても分類できるように、"" はデフォルトの色）
+	symbols map[string]string
+	// 日付の見出しの強調（SGR のパラメータ）
+	heading string
+	// 端末でも ANSI の色を使わない
+	monochrome bool
+}
+
+// 色を見分けにくい人のために、カラーIDごとに形の違う記号を付ける
+var shapeSymbols = map[string]string{
+	"":   "・",
+	"1":  "◇",
+	"2":  "▲",
+	"3":  "◆",
+	"4":  "■",
+	"5":  "★",
+	"6":  "●",
+	"7":  "▼",
+	"8":  "○",
+	"9":  "□",
+	"10": "△",
+	"11": "✚",
+}
+
+var themes = map[string]theme{
+	// Google カレンダーの色に近い 256 色
+	"default": {
+		colors: map[string]string{
+			"1": "38;5;147", "2": "38;5;122", "3": "38;5;183", "4": "38;5;210", "5": "38;5;221", "6": "38;5;215",
+			"7": "38;5;80", "8": "38;5;253", "9": "38;5;69", "10": "38;5;71", "11": "38;5;160",
+		},
+		heading: "1",
+	},
+	// 基本の16色の明るい色を太字で使い、見出しに下線を引く
+	"high-contrast": {
+		colors: map[string]string{
+			"1": "1;94", "2": "1;92", "3": "1;95", "4": "1;91", "5": "1;93", "6": "1;33",
+			"7": "1;96", "8": "1;97", "9": "1;34", "10": "1;32", "11": "1;31",
+		},
+		heading: "1;4",
+	},
+	// 色覚の違いによらず見分けやすい Okabe-Ito の配色と記号
+	"colorblind": {
+		colors: map[string]string{
+			"1": "38;5;117", "2": "38;5;36", "3": "38;5;175", "4": "38;5;166", "5": "38;5;227", "6": "38;5;214",
+			"7": "38;5;117", "8": "38;5;250", "9": "38;5;32", "10": "38;5;36", "11": "38;5;166",
+		},
+		symbols: shapeSymbols,
+		heading: "1",
+	},
+	// 色を使わず記号で区別する
+	"monochrome": {
+		symbols:    shapeSymbols,
+		monochrome: true,
+	},
+}
+
+// テーマの名前からテーマを返す（"" は default）
+func lookupTheme(name string) (theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	t, ok := themes[name]
+	if !ok {
+		var names []string
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return theme{}, fmt.Errorf("Unknown theme %q (expected %s)", name, strings.Join(names, ", "))
+	}
+	return t, nil
+}
+
+// ANSI の色を出力するか（標準出力が端末で、NO_COLOR が設定されていないとき）
+func useANSIColors(t theme) bool {
+	return !t.monochrome && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// 「【色名】」に記号と色を付ける（ansi でなければ記号だけ）
+func (t theme) colorLabel(colorID string, ansi bool) string {
+	label := t.symbols[colorID] + "【" + colorName(colorID) + "】"
+	return t.sgr(t.colors[colorID], label, ansi)
+}
+
+// 日付の見出しを強調する
+func (t theme) emphasize(s string, ansi bool) string {
+	return t.sgr(t.heading, s, ansi)
+}
+
+func (t theme) sgr(params, s string, ansi bool) string {
+	if !ansi || params == "" {
+		return s
+	}
+	return "\x1b[" + params + "m" + s + "\x1b[0m"
+}
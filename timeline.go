@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --format timeline の1行で表す時間
+const timelineStep = 30 * time.Minute
+
+// 予定の列の最小の表示幅（入りきらない列は「+N」にまとめる）
+const timelineMinCell = 6
+
+// --max-width を指定しない場合の表示幅
+const timelineDefaultWidth = 60
+
+// 1日分の予定を、30分を1行とする時間割のように表示する
+//
+// 重なる予定はカレンダーのアプリと同じように横に並べる。終日の予定は時間割の上に表示する。
+func printTimeline(w io.Writer, targetDate time.Time, events []agendaEvent, opts agendaOptions) {
+	width := opts.maxWidth
+	if width <= 0 {
+		width = timelineDefaultWidth
+	}
+	displayDate := formatDateHeader(targetDate, opts.lang, opts.era)
+	fmt.Fprintln(w, opts.theme.emphasize(displayDate+"の予定:", opts.ansi))
+	locations, events := splitWorkingLocations(events)
+	if where := formatWorkingLocations(locations); where != "" {
+		fmt.Fprintln(w, where)
+	}
+	for _, warning := range opts.warnings {
+		fmt.Fprintln(w, warning)
+	}
+	if len(events) == 0 {
+		fmt.Fprintf(w, "%sの予定はありません。\n", displayDate)
+		return
+	}
+	for _, l := range timelineLines(targetDate, events, width) {
+		fmt.Fprintln(w, l)
+	}
+}
+
+// 時間割の各行（終日の予定の行を含む）
+func timelineLines(targetDate time.Time, events []agendaEvent, width int) []string {
+	gutter := func(label string) string { return padRight(label, len("15:04")) + " │" }
+	area := width - displayWidth(gutter(""))
+
+	var lines []string
+	var timed []agendaEvent
+	for _, e := range events {
+		if e.allDay {
+			lines = append(lines, strings.TrimRight(gutter("終日")+" "+truncateWidth(strings.TrimSpace(e.item.Summary), area-1), " "))
+			continue
+		}
+		timed = append(timed, e)
+	}
+	if len(timed) == 0 {
+		return lines
+	}
+
+	// 1日の範囲に収めてから、予定が入っている時間の前後を1時間単位に広げた範囲を行にする
+	dayBegin := atClock(targetDate, dayStart)
+	dayEnd := atClock(targetDate.AddDate(0, 0, 1), dayStart)
+	spans := make([][2]int, len(timed))
+	first, last := -1, 0
+	for i, e := range timed {
+		spans[i] = timelineSpan(e, dayBegin, dayEnd)
+		if first < 0 || spans[i][0] < first {
+			first = spans[i][0]
+		}
+		last = max(last, spans[i][1])
+	}
+	perHour := int(time.Hour / timelineStep)
+	first -= first % perHour
+	if last%perHour != 0 {
+		last += perHour - last%perHour
+	}
+	last = min(last, int(dayEnd.Sub(dayBegin)/timelineStep))
+
+	cols, widths := assignColumns(spans)
+	for row := first; row < last; row++ {
+		at := dayBegin.Add(time.Duration(row) * timelineStep)
+		label := ""
+		if at.Minute() == 0 {
+			label = at.Format("15:04")
+		}
+		var active []int
+		for i, sp := range spans {
+			if sp[0] <= row && row < sp[1] {
+				active = append(active, i)
+			}
+		}
+		if len(active) == 0 {
+			lines = append(lines, gutter(label))
+			continue
+		}
+		lines = append(lines, strings.TrimRight(gutter(label)+" "+timelineRow(timed, spans, cols, widths[active[0]], active, row, area-1), " "))
+	}
+	return lines
+}
+
+// 予定が占める行の範囲 [start, end)
+//
+// 1日の範囲に収め、途中から始まる行や途中で終わる行も含める。長さのない予定も1行を占める。
+func timelineSpan(e agendaEvent, dayBegin, dayEnd time.Time) [2]int {
+	start, end := e.start, e.end
+	if start.Before(dayBegin) {
+		start = dayBegin
+	}
+	if end.After(dayEnd) {
+		end = dayEnd
+	}
+	s := int(start.Sub(dayBegin) / timelineStep)
+	n := int((end.Sub(dayBegin) + timelineStep - 1) / timelineStep)
+	if n <= s {
+		n = s + 1
+	}
+	return [2]int{s, n}
+}
+
+// 時間割の1行のうち、予定を並べた部分
+//
+// 同じ行の予定はすべて同じまとまりに属するため、列の数は n で揃う。
+// 表示幅に入りきらない列は、最後の列に「+N」（入りきらない予定の数）とまとめて表示する。
+func timelineRow(events []agendaEvent, spans [][2]int, cols []int, n int, active []int, row, area int) string {
+	shown := min(n, max(area/timelineMinCell, 1))
+	cell := area / shown
+	byCol := make(map[int]int, len(active))
+	hidden := 0
+	for _, i := range active {
+		byCol[cols[i]] = i
+		if cols[i] >= shown-1 {
+			hidden++
+		}
+	}
+	var b strings.Builder
+	for c := 0; c < shown; c++ {
+		var text string
+		i, ok := byCol[c]
+		switch {
+		case c == shown-1 && n > shown && (hidden > 1 || !ok):
+			if hidden > 0 {
+				text = fmt.Sprintf("+%d", hidden)
+			}
+		case !ok:
+		case spans[i][0] == row:
+			text = "┃" + strings.TrimSpace(events[i].item.Summary)
+		default:
+			text = "┃"
+		}
+		b.WriteString(padRight(truncateWidth(text, cell-1), cell))
+	}
+	return b.String()
+}
+
+// 行の範囲 [start, end) が重なる予定を横に並べるため、各予定の列と、その予定を含むまとまりの列の数を返す
+//
+// 開始の早い順（同じなら長い順、それも同じなら元の順）に、空いている一番左の列に置く。
+// 重なりでつながった予定をひとまとまりとし、まとまりの中の予定は同じ列の数で表示する。
+func assignColumns(spans [][2]int) (cols, widths []int) {
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := spans[order[a]], spans[order[b]]
+		if sa[0] != sb[0] {
+			return sa[0] < sb[0]
+		}
+		return sa[1] > sb[1]
+	})
+
+	cols = make([]int, len(spans))
+	widths = make([]int, len(spans))
+	var colEnd, members []int
+	clusterEnd := 0
+	flush := func() {
+		for _, m := range members {
+			widths[m] = len(colEnd)
+		}
+		colEnd, members = nil, nil
+	}
+	for _, i := range order {
+		start, end := spans[i][0], spans[i][1]
+		if len(members) > 0 && start >= clusterEnd {
+			flush()
+		}
+		c := 0
+		for c < len(colEnd) && colEnd[c] > start {
+			c++
+		}
+		if c == len(colEnd) {
+			colEnd = append(colEnd, end)
+		} else {
+			colEnd[c] = end
+		}
+		cols[i] = c
+		members = append(members, i)
+		if len(members) == 1 || end > clusterEnd {
+			clusterEnd = end
+		}
+	}
+	flush()
+	return cols, widths
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 時間割に並べる予定の行の範囲（終日の予定は除き、元の位置を返す）
+func patternSpans(events []agendaEvent) ([][2]int, []int) {
+	day := date(2024, 6, 14)
+	dayBegin, dayEnd := atClock(day, dayStart), atClock(day.AddDate(0, 0, 1), dayStart)
+	var spans [][2]int
+	var index []int
+	for i, e := range events {
+		if e.allDay {
+			continue
+		}
+		spans = append(spans, timelineSpan(e, dayBegin, dayEnd))
+		index = append(index, i)
+	}
+	return spans, index
+}
+
+func TestAssignColumnsPatterns(t *testing.T) {
+	for _, tt := range overlapPatterns {
+		t.Run(tt.name, func(t *testing.T) {
+			events := patternEvents(t, tt.events)
+			spans, index := patternSpans(events)
+			cols, widths := assignColumns(spans)
+			gotCols := slices.Repeat([]int{-1}, len(events))
+			gotWidths := slices.Repeat([]int{-1}, len(events))
+			for k, i := range index {
+				gotCols[i], gotWidths[i] = cols[k], widths[k]
+			}
+			if !slices.Equal(gotCols, tt.wantColumns) || !slices.Equal(gotWidths, tt.wantWidths) {
+				t.Errorf("assignColumns(%v) = %v, %v, want %v, %v", tt.events, gotCols, gotWidths, tt.wantColumns, tt.wantWidths)
+			}
+		})
+	}
+}
+
+func TestAssignColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		spans      [][2]int
+		wantCols   []int
+		wantWidths []int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:       "single",
+			spans:      [][2]int{{2, 4}},
+			wantCols:   []int{0},
+			wantWidths: []int{1},
+		},
+		{
+			// 同じ範囲の予定は元の順に左から並べる
+			name:       "all identical",
+			spans:      [][2]int{{2, 4}, {2, 4}, {2, 4}, {2, 4}},
+			wantCols:   []int{0, 1, 2, 3},
+			wantWidths: []int{4, 4, 4, 4},
+		},
+		{
+			// 開始が同じなら長い予定を左に置く
+			name:       "same start",
+			spans:      [][2]int{{2, 3}, {2, 6}, {2, 4}},
+			wantCols:   []int{2, 0, 1},
+			wantWidths: []int{3, 3, 3},
+		},
+		{
+			// 階段状につながる予定は2列で足りる
+			name:       "staircase",
+			spans:      [][2]int{{0, 2}, {1, 3}, {2, 4}, {3, 5}, {4, 6}},
+			wantCols:   []int{0, 1, 0, 1, 0},
+			wantWidths: []int{2, 2, 2, 2, 2},
+		},
+		{
+			// 重なりが途切れたら列の数を数え直す
+			name:       "separate clusters",
+			spans:      [][2]int{{0, 2}, {1, 3}, {1, 2}, {3, 4}, {6, 8}, {7, 9}},
+			wantCols:   []int{0, 1, 2, 0, 0, 1},
+			wantWidths: []int{3, 3, 3, 1, 2, 2},
+		},
+		{
+			// 長い予定がまとまりをつなぎ、空いた列は再び使う
+			name:       "long event bridges",
+			spans:      [][2]int{{0, 10}, {1, 2}, {3, 4}, {5, 6}, {5, 7}},
+			wantCols:   []int{0, 1, 1, 2, 1},
+			wantWidths: []int{3, 3, 3, 3, 3},
+		},
+		{
+			// 左の列が空いたら、右の列が埋まっていても左に置く
+			name:       "reuse leftmost",
+			spans:      [][2]int{{0, 2}, {0, 6}, {2, 4}},
+			wantCols:   []int{1, 0, 1},
+			wantWidths: []int{2, 2, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, widths := assignColumns(tt.spans)
+			if !slices.Equal(cols, tt.wantCols) {
+				t.Errorf("columns = %v, want %v", cols, tt.wantCols)
+			}
+			if !slices.Equal(widths, tt.wantWidths) {
+				t.Errorf("widths = %v, want %v", widths, tt.wantWidths)
+			}
+		})
+	}
+}
+
+// どんな重なり方でも、同じ行の予定が同じ列に入らず、同じ行の予定は同じ列の数になる
+func TestAssignColumnsInvariants(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		spans := make([][2]int, r.Intn(12)+1)
+		for i := range spans {
+			s := r.Intn(20)
+			spans[i] = [2]int{s, s + 1 + r.Intn(6)}
+		}
+		cols, widths := assignColumns(spans)
+		for row := 0; row < 30; row++ {
+			used := map[int]bool{}
+			width := -1
+			for i, sp := range spans {
+				if sp[0] > row || row >= sp[1] {
+					continue
+				}
+				if used[cols[i]] {
+					t.Fatalf("%v: two events in column %d at row %d (columns %v)", spans, cols[i], row, cols)
+				}
+				used[cols[i]] = true
+				if cols[i] >= widths[i] {
+					t.Fatalf("%v: column %d is outside width %d", spans, cols[i], widths[i])
+				}
+				if width >= 0 && widths[i] != width {
+					t.Fatalf("%v: different widths %d and %d at row %d", spans, width, widths[i], row)
+				}
+				width = widths[i]
+			}
+		}
+		// 同じ入力には同じ結果を返す
+		again, _ := assignColumns(spans)
+		if !slices.Equal(cols, again) {
+			t.Fatalf("%v: columns changed between runs: %v, %v", spans, cols, again)
+		}
+	}
+}
+
+func TestTimelineRow(t *testing.T) {
+	events := patternEvents(t, []string{"10:00-11:00", "10:00-11:00", "10:00-10:30", "10:00-10:30"})
+	for i, name := range []string{"A", "B", "C", "D"} {
+		item := *events[i].item
+		item.Summary = name
+		events[i].item = &item
+	}
+	spans, _ := patternSpans(events)
+	cols, widths := assignColumns(spans)
+	tests := []struct {
+		name string
+		row  int
+		area int
+		want string
+	}{
+		{name: "all columns fit", row: 20, area: 40, want: "┃A        ┃B        ┃C        ┃D"},
+		{name: "continuing rows", row: 21, area: 40, want: "┃         ┃"},
+		// 入りきらない列は最後の列に「+N」とまとめる
+		{name: "overflow", row: 20, area: 18, want: "┃A    ┃B    +2"},
+		{name: "single column", row: 20, area: 6, want: "+4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var active []int
+			for i, sp := range spans {
+				if sp[0] <= tt.row && tt.row < sp[1] {
+					active = append(active, i)
+				}
+			}
+			got := strings.TrimRight(timelineRow(events, spans, cols, widths[active[0]], active, tt.row, tt.area), " ")
+			if got != tt.want {
+				t.Errorf("timelineRow(row %d, area %d) = %q, want %q", tt.row, tt.area, got, tt.want)
+			}
+		})
+	}
+}
+
+// 多くの予定が重なる日の列の割り当てと、1行の組み立て
+func BenchmarkTimeline(b *testing.B) {
+	day := date(2024, 6, 1)
+	for _, n := range []int{50, 500} {
+		events := selectDay(normalizeEvents("primary", benchmarkItems(n, 1)), day)
+		events = slices.DeleteFunc(events, func(e agendaEvent) bool { return e.allDay })
+		dayBegin, dayEnd := atClock(day, dayStart), atClock(day.AddDate(0, 0, 1), dayStart)
+		spans := make([][2]int, len(events))
+		for i, e := range events {
+			spans[i] = timelineSpan(e, dayBegin, dayEnd)
+		}
+		b.Run(fmt.Sprintf("assignColumns/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				assignColumns(spans)
+			}
+		})
+		cols, widths := assignColumns(spans)
+		// 最も多くの予定が重なる行
+		var row int
+		var active []int
+		for r := 0; r < 48; r++ {
+			var a []int
+			for i, sp := range spans {
+				if sp[0] <= r && r < sp[1] {
+					a = append(a, i)
+				}
+			}
+			if len(a) > len(active) {
+				row, active = r, a
+			}
+		}
+		b.Run(fmt.Sprintf("timelineRow/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				timelineRow(events, spans, cols, widths[active[0]], active, row, 80)
+			}
+		})
+	}
+}
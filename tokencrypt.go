@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 暗号化した token.json の内容
+//
+// 読み込むときは --encrypt-token の有無によらず、この形式なら復号する。
+type encryptedToken struct {
+	// passphrase（scrypt と XChaCha20-Poly1305）か age
+	Encrypted string `json:"encrypted"`
+	Salt      []byte `json:"salt,omitempty"`
+	Nonce     []byte `json:"nonce,omitempty"`
+	Data      []byte `json:"data"`
+}
+
+// トークンの暗号化に使う age の秘密鍵のファイルとパスフレーズの環境変数（秘密鍵があれば age を使う）
+const (
+	tokenAgeIdentityEnv = "GCAL_TOKEN_AGE_IDENTITY"
+	tokenPassphraseEnv  = "GCAL_TOKEN_PASSPHRASE"
+)
+
+var (
+	tokenPassphraseOnce sync.Once
+	tokenPassphrase     []byte
+	tokenPassphraseErr  error
+)
+
+// token.json を読み込む（暗号化されていれば復号する）
+func readTokenFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	enc, ok := parseEncryptedToken(b)
+	if !ok {
+		return b, nil
+	}
+	switch enc.Encrypted {
+	case "passphrase":
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, err := scrypt.Key(pass, enc.Salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := aead.Open(nil, enc.Nonce, enc.Data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decrypt %s: wrong passphrase or corrupted file", path)
+		}
+		return plain, nil
+	case "age":
+		identity := os.Getenv(tokenAgeIdentityEnv)
+		if identity == "" {
+			return nil, fmt.Errorf("%s is encrypted with age; set %s to the identity file", path, tokenAgeIdentityEnv)
+		}
+		return runAge(enc.Data, "-d", "-i", identity)
+	}
+	return nil, fmt.Errorf("Unsupported encryption %q in %s", enc.Encrypted, path)
+}
+
+// token.json に書き込む（--encrypt-token を付けたか、すでに暗号化されていれば暗号化する）
+func writeTokenFile(path string, plain []byte) error {
+	data := plain
+	if globalOpts.encryptToken || tokenFileEncrypted(path) {
+		enc, err := encryptToken(plain)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt oauth token: %w", err)
+		}
+		if data, err = json.Marshal(enc); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("Unable to cache oauth token: %w", err)
+	}
+	return nil
+}
+
+// path が暗号化した token.json か
+func tokenFileEncrypted(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	_, ok := parseEncryptedToken(b)
+	return ok
+}
+
+func parseEncryptedToken(b []byte) (*encryptedToken, bool) {
+	var enc encryptedToken
+	if json.Unmarshal(b, &enc) != nil || enc.Encrypted == "" {
+		return nil, false
+	}
+	return &enc, true
+}
+
+// age の秘密鍵があれば age で、なければパスフレーズで暗号化する
+func encryptToken(plain []byte) (*encryptedToken, error) {
+	if identity := os.Getenv(tokenAgeIdentityEnv); identity != "" {
+		// -i を付けて暗号化すると、秘密鍵に対応する公開鍵で暗号化される
+		data, err := runAge(plain, "-e", "-i", identity)
+		if err != nil {
+			return nil, err
+		}
+		return &encryptedToken{Encrypted: "age", Data: data}, nil
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(pass, salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedToken{Encrypted: "passphrase", Salt: salt, Nonce: nonce, Data: aead.Seal(nil, nonce, plain, nil)}, nil
+}
+
+func runAge(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to run age: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// トークンのパスフレーズ（環境変数 GCAL_TOKEN_PASSPHRASE、なければ端末で1回だけ入力する）
+func passphrase() ([]byte, error) {
+	tokenPassphraseOnce.Do(func() {
+		if p := os.Getenv(tokenPassphraseEnv); p != "" {
+			tokenPassphrase = []byte(p)
+			return
+		}
+		if !isTerminal(os.Stdin) {
+			tokenPassphraseErr = fmt.Errorf("%w: %s is needed to decrypt or encrypt the token without a terminal", errAuthRequired, tokenPassphraseEnv)
+			return
+		}
+		fmt.Fprint(os.Stderr, "token.json のパスフレーズ: ")
+		tokenPassphrase, tokenPassphraseErr = readNoEcho()
+		fmt.Fprintln(os.Stderr)
+		if tokenPassphraseErr == nil && len(tokenPassphrase) == 0 {
+			tokenPassphraseErr = errors.New("Empty passphrase")
+		}
+	})
+	return tokenPassphrase, tokenPassphraseErr
+}
+
+// 入力した文字を表示せずに1行読み込む（stty がない環境ではそのまま読み込む）
+func readNoEcho() ([]byte, error) {
+	if runtime.GOOS != "windows" {
+		stty := func(arg string) error {
+			cmd := exec.Command("stty", arg)
+			cmd.Stdin = os.Stdin
+			return cmd.Run()
+		}
+		if stty("-echo") == nil {
+			defer stty("echo")
+		}
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("Unable to read passphrase: %w", err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// タイムトラッキングのサービスの設定
+type trackingConfig struct {
+	// "toggl" または "clockify"
+	Provider string `json:"provider,omitempty"`
+	// 省略時は環境変数 TRACKING_API_TOKEN
+	APIToken string `json:"api_token,omitempty"`
+	// Clockify のワークスペース（省略時はユーザーの現在のワークスペース）
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// 記録された作業時間
+type trackedEntry struct {
+	description string
+	interval    interval
+}
+
+// これ以上の割合が重なっていれば、予定と記録が対応しているとみなす
+const trackedOverlap = 0.5
+
+// 予定と Toggl / Clockify の記録を突き合わせ、記録のない予定と予定にない記録を表示する
+func setupTrackDiff(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Day to compare (format: YYYY-MM-DD, default today)")
+	configPath := fs.String("config", defaultConfigFile, "Path to the config file")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		tc := cfg.Tracking
+		if tc.APIToken == "" {
+			tc.APIToken = os.Getenv("TRACKING_API_TOKEN")
+		}
+		if tc.Provider != "toggl" && tc.Provider != "clockify" {
+			return fmt.Errorf("Set \"tracking\": {\"provider\": \"toggl\" or \"clockify\"} in %s", *configPath)
+		}
+		if tc.APIToken == "" {
+			return fmt.Errorf("No API token for %s (set tracking.api_token or TRACKING_API_TOKEN)", tc.Provider)
+		}
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		startTime, endTime := dayWindow(day)
+		events, err := client.ListEvents(ctx, "primary", startTime, endTime)
+		if err != nil {
+			return err
+		}
+		entries, err := fetchTimeEntries(ctx, tc, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		printTrackDiff(os.Stdout, day, eventsForDay(events.Items, day), entries)
+		return nil
+	}
+}
+
+func fetchTimeEntries(ctx context.Context, tc trackingConfig, from, to time.Time) ([]trackedEntry, error) {
+	switch tc.Provider {
+	case "clockify":
+		return clockifyEntries(ctx, tc, from, to)
+	default:
+		return togglEntries(ctx, tc, from, to)
+	}
+}
+
+// Toggl Track API v9 の自分の記録
+func togglEntries(ctx context.Context, tc trackingConfig, from, to time.Time) ([]trackedEntry, error) {
+	q := url.Values{"start_date": {from.Format(time.RFC3339)}, "end_date": {to.Format(time.RFC3339)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.track.toggl.com/api/v9/me/time_entries?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(tc.APIToken, "api_token")
+	var resp []struct {
+		Description string     `json:"description"`
+		Start       time.Time  `json:"start"`
+		Stop        *time.Time `json:"stop"`
+	}
+	if err := getTrackingJSON(req, &resp); err != nil {
+		return nil, err
+	}
+	var out []trackedEntry
+	for _, e := range resp {
+		out = append(out, newTrackedEntry(e.Description, e.Start, e.Stop))
+	}
+	return out, nil
+}
+
+// Clockify API v1 の自分の記録
+func clockifyEntries(ctx context.Context, tc trackingConfig, from, to time.Time) ([]trackedEntry, error) {
+	const base = "https://api.clockify.me/api/v1"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", tc.APIToken)
+	var user struct {
+		ID              string `json:"id"`
+		ActiveWorkspace string `json:"activeWorkspace"`
+	}
+	if err := getTrackingJSON(req, &user); err != nil {
+		return nil, err
+	}
+	workspace := tc.Workspace
+	if workspace == "" {
+		workspace = user.ActiveWorkspace
+	}
+
+	q := url.Values{
+		"start":     {from.UTC().Format("2006-01-02T15:04:05Z")},
+		"end":       {to.UTC().Format("2006-01-02T15:04:05Z")},
+		"page-size": {"500"},
+	}
+	path := fmt.Sprintf("%s/workspaces/%s/user/%s/time-entries?%s", base, url.PathEscape(workspace), url.PathEscape(user.ID), q.Encode())
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", tc.APIToken)
+	var resp []struct {
+		Description  string `json:"description"`
+		TimeInterval struct {
+			Start time.Time  `json:"start"`
+			End   *time.Time `json:"end"`
+		} `json:"timeInterval"`
+	}
+	if err := getTrackingJSON(req, &resp); err != nil {
+		return nil, err
+	}
+	var out []trackedEntry
+	for _, e := range resp {
+		out = append(out, newTrackedEntry(e.Description, e.TimeInterval.Start, e.TimeInterval.End))
+	}
+	return out, nil
+}
+
+// 計測中の記録（終了時刻がない）は現在時刻までとして扱う
+func newTrackedEntry(description string, start time.Time, stop *time.Time) trackedEntry {
+	end := time.Now()
+	if stop != nil {
+		end = *stop
+	}
+	return trackedEntry{description: description, interval: interval{start: start.In(time.Local), end: end.In(time.Local)}}
+}
+
+func getTrackingJSON(req *http.Request, v any) error {
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve time entries: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Unable to retrieve time entries: %s: %s", resp.Status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Unable to parse time entries: %w", err)
+	}
+	return nil
+}
+
+// iv のうち ivs と重なっている時間
+func overlapDuration(iv interval, ivs []interval) time.Duration {
+	var total time.Duration
+	for _, o := range mergeIntervals(ivs) {
+		start, end := iv.start, iv.end
+		if o.start.After(start) {
+			start = o.start
+		}
+		if o.end.Before(end) {
+			end = o.end
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+func printTrackDiff(w io.Writer, day time.Time, items []*calendar.Event, entries []trackedEntry) {
+	var scheduled []*calendar.Event
+	var scheduledIvs, trackedIvs []interval
+	for _, item := range items {
+		if item.Start.DateTime == "" || !isBusy(item, false) || attendanceStatus(item) == "declined" {
+			continue
+		}
+		start, end := eventTimes(item)
+		scheduled = append(scheduled, item)
+		scheduledIvs = append(scheduledIvs, interval{start: start, end: end})
+	}
+	for _, e := range entries {
+		trackedIvs = append(trackedIvs, e.interval)
+	}
+
+	fmt.Fprintf(w, "%sの予定と記録の照合:\n", day.Format("2006-01-02"))
+	fmt.Fprintln(w, "記録のない予定:")
+	n := 0
+	for i, item := range scheduled {
+		iv := scheduledIvs[i]
+		if iv.duration() > 0 && float64(overlapDuration(iv, trackedIvs)) >= trackedOverlap*float64(iv.duration()) {
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", formatEvent(item))
+		n++
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "  なし")
+	}
+
+	fmt.Fprintln(w, "予定にない記録:")
+	n = 0
+	for _, e := range entries {
+		iv := e.interval
+		if iv.duration() <= 0 || float64(overlapDuration(iv, scheduledIvs)) >= trackedOverlap*float64(iv.duration()) {
+			continue
+		}
+		desc := e.description
+		if desc == "" {
+			desc = "（説明なし）"
+		}
+		fmt.Fprintf(w, "  %s-%s %s\n", iv.start.Format("15:04"), iv.end.Format("15:04"), desc)
+		n++
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "  なし")
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+	"google.golang.org/api/calendar/v3"
+)
+
+// 表示する前に予定に適用する変換（件名の変更、予定の除外、タグの追加）
+//
+// 指定した条件すべてに一致する予定に適用する。リマインダーのルールと違い、一致したルールは上から順にすべて適用する。
+type transformRule struct {
+	Color    string `json:"color,omitempty"`
+	Calendar string `json:"calendar,omitempty"`
+	// 件名に一致する正規表現
+	Match string `json:"match,omitempty"`
+	// 新しい件名（Match の "$1" などを使える）
+	Rename string `json:"rename,omitempty"`
+	// 予定を表示しない
+	Drop bool `json:"drop,omitempty"`
+	// 説明欄に "#タグ" として追加する
+	Tags []string `json:"tags,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Match の正規表現をコンパイルする
+func compileTransforms(rules []transformRule) ([]transformRule, error) {
+	out := make([]transformRule, len(rules))
+	for i, r := range rules {
+		if r.Match != "" {
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid transform match %q: %w", r.Match, err)
+			}
+			r.re = re
+		}
+		out[i] = r
+	}
+	return out, nil
+}
+
+func (r transformRule) matches(calendarID string, item *calendar.Event) bool {
+	if r.Color != "" && r.Color != item.ColorId {
+		return false
+	}
+	if r.Calendar != "" && r.Calendar != calendarID {
+		return false
+	}
+	return r.re == nil || r.re.MatchString(item.Summary)
+}
+
+// 変換を適用した予定を返す（元の予定は変更しない）
+func applyTransforms(rules []transformRule, events []agendaEvent) []agendaEvent {
+	if len(rules) == 0 {
+		return events
+	}
+	out := make([]agendaEvent, 0, len(events))
+	for _, e := range events {
+		if item := transformEvent(rules, e.calendarID, e.item); item != nil {
+			out = append(out, e.withItem(item))
+		}
+	}
+	return out
+}
+
+// 予定に変換を適用する（除外する場合は nil）
+func transformEvent(rules []transformRule, calendarID string, item *calendar.Event) *calendar.Event {
+	var copied *calendar.Event
+	for _, r := range rules {
+		cur := item
+		if copied != nil {
+			cur = copied
+		}
+		if !r.matches(calendarID, cur) {
+			continue
+		}
+		if r.Drop {
+			return nil
+		}
+		if copied == nil {
+			c := *item
+			copied = &c
+		}
+		if r.Rename != "" {
+			if r.re != nil {
+				copied.Summary = r.re.ReplaceAllString(copied.Summary, r.Rename)
+			} else {
+				copied.Summary = r.Rename
+			}
+		}
+		for _, t := range r.Tags {
+			addDescriptionTag(copied, t)
+		}
+	}
+	if copied != nil {
+		return copied
+	}
+	return item
+}
+
+// 説明欄に "#タグ" を追加する（すでにあれば何もしない）
+func addDescriptionTag(item *calendar.Event, t string) {
+	tag := "#" + t
+	if !strings.Contains(item.Description, tag) {
+		item.Description = strings.TrimSpace(item.Description + " " + tag)
+	}
+}
+
+// 1件の予定の変換で実行できるステップ数の上限（無限ループで表示が止まらないように）
+const transformScriptMaxSteps = 1_000_000
+
+// 設定ファイルの transform_script で指定した Starlark のスクリプト
+//
+// スクリプトには transform(event) を定義する。event は予定を表す dict で、
+// 変更した dict を返すと件名などを置き換え、None を返すと予定を表示しない。
+type transformScript struct {
+	path string
+	fn   *starlark.Function
+}
+
+// スクリプトを読み込み、transform(event) を取り出す（path が空なら nil）
+func loadTransformScript(path string) (*transformScript, error) {
+	if path == "" {
+		return nil, nil
+	}
+	thread := &starlark.Thread{Name: "transform"}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load transform script %s: %w", path, err)
+	}
+	fn, ok := globals["transform"].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("Transform script %s must define transform(event)", path)
+	}
+	if fn.NumParams() != 1 {
+		return nil, fmt.Errorf("transform in %s must take exactly one argument (the event)", path)
+	}
+	return &transformScript{path: path, fn: fn}, nil
+}
+
+// スクリプトで変換した予定を返す（元の予定は変更しない）
+//
+// スクリプトがエラーになった予定は、表示から漏れないよう変換せずに残す。
+func (s *transformScript) apply(events []agendaEvent) []agendaEvent {
+	if s == nil {
+		return events
+	}
+	out := make([]agendaEvent, 0, len(events))
+	for _, e := range events {
+		item, err := s.transform(e)
+		if err != nil {
+			slog.Warn("Unable to run transform script", "path", s.path, "event", e.item.Summary, "error", err)
+			out = append(out, e)
+			continue
+		}
+		if item != nil {
+			out = append(out, e.withItem(item))
+		}
+	}
+	return out
+}
+
+// 1件の予定に transform(event) を実行する（除外する場合は nil）
+func (s *transformScript) transform(e agendaEvent) (*calendar.Event, error) {
+	tags := eventTags(e.item)
+	tagValues := make([]starlark.Value, len(tags))
+	for i, t := range tags {
+		tagValues[i] = starlark.String(t)
+	}
+	event := starlark.NewDict(9)
+	for k, v := range map[string]starlark.Value{
+		"summary":     starlark.String(e.item.Summary),
+		"description": starlark.String(e.item.Description),
+		"location":    starlark.String(e.item.Location),
+		"color":       starlark.String(e.item.ColorId),
+		"calendar":    starlark.String(e.calendarID),
+		"start":       starlark.String(e.start.Format(time.RFC3339)),
+		"end":         starlark.String(e.end.Format(time.RFC3339)),
+		"all_day":     starlark.Bool(e.allDay),
+		"tags":        starlark.NewList(tagValues),
+	} {
+		if err := event.SetKey(starlark.String(k), v); err != nil {
+			return nil, err
+		}
+	}
+
+	thread := &starlark.Thread{Name: "transform"}
+	thread.SetMaxExecutionSteps(transformScriptMaxSteps)
+	result, err := starlark.Call(thread, s.fn, starlark.Tuple{event}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if result == starlark.None {
+		return nil, nil
+	}
+	d, ok := result.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("transform must return a dict or None, got %s", result.Type())
+	}
+
+	item := *e.item
+	for key, field := range map[string]*string{
+		"summary":     &item.Summary,
+		"description": &item.Description,
+		"location":    &item.Location,
+	} {
+		v, found, err := d.Get(starlark.String(key))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		str, ok := starlark.AsString(v)
+		if !ok {
+			return nil, fmt.Errorf("event[%q] must be a string, got %s", key, v.Type())
+		}
+		*field = str
+	}
+	v, found, err := d.Get(starlark.String("tags"))
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		iter, ok := v.(starlark.Iterable)
+		if !ok {
+			return nil, fmt.Errorf("event[\"tags\"] must be a list, got %s", v.Type())
+		}
+		it := iter.Iterate()
+		defer it.Done()
+		var t starlark.Value
+		for it.Next(&t) {
+			str, ok := starlark.AsString(t)
+			if !ok {
+				return nil, fmt.Errorf("event[\"tags\"] must contain strings, got %s", t.Type())
+			}
+			if str = strings.TrimPrefix(strings.TrimSpace(str), "#"); str != "" && !hasAnyTag(&item, []string{str}) {
+				addDescriptionTag(&item, str)
+			}
+		}
+	}
+	return &item, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []transformRule
+		// 変換後の予定の ID と件名
+		want [][2]string
+		// 説明欄を確かめる予定の ID と説明欄
+		description [2]string
+	}{
+		{
+			name: "no rules",
+			want: [][2]string{{"standup", "定例"}, {"lunch", "Lunch"}, {"wip", "[WIP] 設計レビュー"}},
+		},
+		{
+			name:  "rename with match group",
+			rules: []transformRule{{Match: `^\[WIP\] (.*)$`, Rename: "$1"}},
+			want:  [][2]string{{"standup", "定例"}, {"lunch", "Lunch"}, {"wip", "設計レビュー"}},
+		},
+		{
+			name:  "rename by color",
+			rules: []transformRule{{Color: "2", Rename: "朝会"}},
+			want:  [][2]string{{"standup", "朝会"}, {"lunch", "Lunch"}, {"wip", "[WIP] 設計レビュー"}},
+		},
+		{
+			name:  "drop",
+			rules: []transformRule{{Match: "(?i)^lunch$", Drop: true}},
+			want:  [][2]string{{"standup", "定例"}, {"wip", "[WIP] 設計レビュー"}},
+		},
+		{
+			name:  "other calendar",
+			rules: []transformRule{{Calendar: "team@example.com", Drop: true}},
+			want:  [][2]string{{"standup", "定例"}, {"lunch", "Lunch"}, {"wip", "[WIP] 設計レビュー"}},
+		},
+		{
+			// 一致したルールは上から順にすべて適用する
+			name: "rules in order",
+			rules: []transformRule{
+				{Match: `^\[WIP\] (.*)$`, Rename: "$1", Tags: []string{"wip"}},
+				{Match: "^設計", Tags: []string{"design", "wip"}},
+			},
+			want:        [][2]string{{"standup", "定例"}, {"lunch", "Lunch"}, {"wip", "設計レビュー"}},
+			description: [2]string{"wip", "資料は共有フォルダ #wip #design"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := loadTestEvents(t, "testdata/day.json")
+			events = slices.DeleteFunc(events, func(e agendaEvent) bool { return e.item.ColorId == "5" || e.item.Id == "release" || e.allDay })
+			rules, err := compileTransforms(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := applyTransforms(rules, events)
+			var got [][2]string
+			for _, e := range out {
+				got = append(got, [2]string{e.item.Id, e.item.Summary})
+				if e.item.Id == tt.description[0] && e.item.Description != tt.description[1] {
+					t.Errorf("description of %s = %q, want %q", e.item.Id, e.item.Description, tt.description[1])
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("applyTransforms = %v, want %v", got, tt.want)
+			}
+			// 元の予定は変更しない
+			if events[2].item.Summary != "[WIP] 設計レビュー" || events[2].item.Description != "資料は共有フォルダ" {
+				t.Errorf("applyTransforms changed the original event: %q %q", events[2].item.Summary, events[2].item.Description)
+			}
+		})
+	}
+}
+
+func TestCompileTransformsInvalidMatch(t *testing.T) {
+	if _, err := compileTransforms([]transformRule{{Match: "("}}); err == nil {
+		t.Error("compileTransforms accepted an invalid regexp")
+	}
+}
+
+func TestTransformScript(t *testing.T) {
+	script := `
+def transform(event):
+    if event["summary"] == "Lunch":
+        return None
+    if event["calendar"] == "primary" and not event["all_day"] and event["start"].startswith("2024-06-14T10:"):
+        event["summary"] = "朝会"
+        event["tags"] = event["tags"] + ["morning"]
+    return event
+`
+	path := filepath.Join(t.TempDir(), "transform.star")
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadTransformScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := loadTestEvents(t, "testdata/day.json")
+	out := s.apply(events)
+	if len(out) != len(events)-1 {
+		t.Fatalf("apply returned %d events, want %d", len(out), len(events)-1)
+	}
+	if got := out[0].item; got.Summary != "朝会" || got.Description != "#morning" {
+		t.Errorf("transformed event = %q %q, want 朝会 #morning", got.Summary, got.Description)
+	}
+	if events[0].item.Summary != "定例" {
+		t.Errorf("transform changed the original event: %q", events[0].item.Summary)
+	}
+	for _, e := range out {
+		if e.item.Summary == "Lunch" {
+			t.Error("the event dropped by the script is still listed")
+		}
+	}
+}
+
+func TestLoadTransformScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"syntax error", "def transform(event):\n  return (\n"},
+		{"no transform", "def other(event):\n  return event\n"},
+		{"wrong arity", "def transform(event, extra):\n  return event\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transform.star")
+			if err := os.WriteFile(path, []byte(tt.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadTransformScript(path); err == nil {
+				t.Errorf("loadTransformScript accepted %q", tt.script)
+			}
+		})
+	}
+}
+
+// スクリプトがエラーになった予定は変換せずに残す
+func TestTransformScriptRuntimeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transform.star")
+	if err := os.WriteFile(path, []byte("def transform(event):\n  return 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadTransformScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := loadTestEvents(t, "testdata/day.json")
+	if got := eventIDs(s.apply(events)); !slices.Equal(got, eventIDs(events)) {
+		t.Errorf("apply = %v, want the events unchanged", got)
+	}
+}
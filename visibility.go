@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 非公開の予定の件名の代わりに表示する文字列
+const privateSummary = "非公開の予定"
+
+// 公開設定が「非公開」の予定か
+func isPrivate(item *calendar.Event) bool {
+	return item.Visibility == "private" || item.Visibility == "confidential"
+}
+
+// 予定の時間帯を「予定あり」として扱うか（「空き時間」に設定された予定は、includeFree（--include-free）でなければ除く）
+func isBusy(item *calendar.Event, includeFree bool) bool {
+	return includeFree || item.Transparency != "transparent"
+}
+
+// 非公開の予定の件名や場所を伏せた一覧を返す（元の予定は変更しない）
+func redactPrivate(events []agendaEvent) []agendaEvent {
+	out := make([]agendaEvent, len(events))
+	for i, e := range events {
+		item := e.item
+		if !isPrivate(item) {
+			out[i] = e
+			continue
+		}
+		redacted := *item
+		redacted.Summary = privateSummary
+		redacted.Description = ""
+		redacted.Location = ""
+		redacted.HangoutLink = ""
+		redacted.ConferenceData = nil
+		redacted.ExtendedProperties = nil
+		out[i] = e.withItem(&redacted)
+	}
+	return out
+}
+
+// JSON 出力用の予定の件名や場所を伏せる
+func redactPrivateJSON(events []jsonEvent) {
+	for i := range events {
+		if events[i].Private {
+			events[i].Summary = privateSummary
+			events[i].Location = ""
+			events[i].MapsURL = ""
+			events[i].Tags = nil
+		}
+	}
+}
+
+// FreeBusy では除かれる「空き時間」の時間指定の予定の時間帯（--include-free 用）
+func freeEventIntervals(ctx context.Context, client calendarClient, calendarID string, timeMin, timeMax time.Time) ([]interval, error) {
+	events, err := client.ListEvents(ctx, calendarID, timeMin, timeMax)
+	if err != nil {
+		return nil, err
+	}
+	var out []interval
+	for _, item := range events.Items {
+		if isBusy(item, false) || item.Start.DateTime == "" || item.Status == "cancelled" || attendanceStatus(item) == "declined" {
+			continue
+		}
+		start, end := eventTimes(item)
+		out = append(out, interval{start: start.In(timeMin.Location()), end: end.In(timeMin.Location())})
+	}
+	return out, nil
+}
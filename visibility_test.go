@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 10:00-11:00 の通常の予定と、11:00-12:30 の「空き時間」の予定（仮押さえ）
+func freeTestEvents() *calendar.Events {
+	return &calendar.Events{Items: []*calendar.Event{
+		{
+			Id:      "meeting",
+			Summary: "定例",
+			Start:   &calendar.EventDateTime{DateTime: "2024-06-14T10:00:00+09:00"},
+			End:     &calendar.EventDateTime{DateTime: "2024-06-14T11:00:00+09:00"},
+		},
+		{
+			Id:           "hold",
+			Summary:      "仮押さえ",
+			Transparency: "transparent",
+			Start:        &calendar.EventDateTime{DateTime: "2024-06-14T11:00:00+09:00"},
+			End:          &calendar.EventDateTime{DateTime: "2024-06-14T12:30:00+09:00"},
+		},
+	}}
+}
+
+func TestIsBusy(t *testing.T) {
+	opaque := &calendar.Event{}
+	free := &calendar.Event{Transparency: "transparent"}
+	tests := []struct {
+		name        string
+		item        *calendar.Event
+		includeFree bool
+		want        bool
+	}{
+		{"opaque", opaque, false, true},
+		{"free", free, false, false},
+		{"free with include free", free, true, true},
+		{"opaque with include free", opaque, true, true},
+	}
+	for _, tt := range tests {
+		if got := isBusy(tt.item, tt.includeFree); got != tt.want {
+			t.Errorf("%s: isBusy = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// stats と heatmap は --include-free で「空き時間」の予定も会議時間に数える
+func TestCollectStatsIncludeFree(t *testing.T) {
+	client := &fakeClient{events: freeTestEvents()}
+	day := date(2024, 6, 14)
+	tests := []struct {
+		includeFree  bool
+		wantMeetings int
+		wantMeeting  time.Duration
+	}{
+		{false, 1, time.Hour},
+		{true, 2, 2*time.Hour + 30*time.Minute},
+	}
+	for _, tt := range tests {
+		st, err := collectStats(context.Background(), client, focusConfig{}, costConfig{}, day, day, tt.includeFree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.meetings != tt.wantMeetings || st.meeting != tt.wantMeeting {
+			t.Errorf("includeFree=%v: meetings = %d (%v), want %d (%v)", tt.includeFree, st.meetings, st.meeting, tt.wantMeetings, tt.wantMeeting)
+		}
+		if got := busyTime(freeTestEvents().Items, day, tt.includeFree); got != tt.wantMeeting {
+			t.Errorf("includeFree=%v: busyTime = %v, want %v", tt.includeFree, got, tt.wantMeeting)
+		}
+	}
+}
+
+// team は --include-free で「空き時間」の予定もメンバーの予定の入っている時間帯に含める
+func TestMemberBlocksIncludeFree(t *testing.T) {
+	client := &fakeClient{events: freeTestEvents()}
+	day := date(2024, 6, 14)
+	tests := []struct {
+		includeFree bool
+		want        string
+	}{
+		{false, "10:00-11:00"},
+		{true, "10:00-11:00、11:00-12:30"},
+	}
+	for _, tt := range tests {
+		blocks, err := memberBlocks(context.Background(), client, "primary", day, day.AddDate(0, 0, 1), tt.includeFree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatTeamBlocks(blocks, day); got != tt.want {
+			t.Errorf("includeFree=%v: blocks = %q, want %q", tt.includeFree, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"text/template"
+	"time"
+
+	"gcal-daily-agenda/schema"
+)
+
+// 予定の変更を通知する Webhook の設定
+type webhookConfig struct {
+	URL string `json:"url"`
+	// ペイロードの text/template（省略時は webhookPayload をそのまま JSON にする）
+	Template string `json:"template,omitempty"`
+	// 追加するヘッダー（認可用のトークンなど）
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// 変更の種類
+const (
+	changeAdded     = "added"
+	changeCancelled = "cancelled"
+	changeMoved     = "moved"
+)
+
+// 1件の予定の変更
+type agendaChange struct {
+	Type  string    `json:"type"`
+	Event jsonEvent `json:"event"`
+	// moved の場合の変更前の開始・終了
+	PreviousStart string `json:"previous_start,omitempty"`
+	PreviousEnd   string `json:"previous_end,omitempty"`
+}
+
+// テンプレートに渡すデータ
+type webhookPayload struct {
+	SchemaVersion int            `json:"schema_version"`
+	Date          string         `json:"date"`
+	Changes       []agendaChange `json:"changes"`
+	// 変更後の1日分の予定
+	Events []jsonEvent `json:"events"`
+}
+
+type webhook struct {
+	url     string
+	headers map[string]string
+	tmpl    *template.Template
+}
+
+// 設定から Webhook を作成する（URL が空なら nil）
+func newWebhook(cfg webhookConfig) (*webhook, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	h := &webhook{url: cfg.URL, headers: cfg.Headers}
+	if cfg.Template != "" {
+		// {{json .Event.Summary}} のようにして値を JSON の文字列として埋め込めるようにする
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse webhook template: %w", err)
+		}
+		h.tmpl = tmpl
+	}
+	return h, nil
+}
+
+// ペイロードを POST する
+func (h *webhook) post(ctx context.Context, payload webhookPayload) error {
+	var body bytes.Buffer
+	if h.tmpl != nil {
+		if err := h.tmpl.Execute(&body, payload); err != nil {
+			return fmt.Errorf("Unable to render webhook template: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := baseHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Unable to post webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// 前回確認したときの今日の予定
+type agendaWatcher struct {
+	date   string
+	events map[string]jsonEvent
+}
+
+// 今日の予定を取得し、前回から変わっていれば Webhook に送る
+//
+// 別の日に移動された予定は今日の予定から消えるため cancelled として扱う。
+// 起動直後と日付が変わった直後は比較する相手がないため、記録するだけにする。
+// 送信に失敗した場合は記録を更新せず、次の周期で同じ変更を送り直す。
+// 一部のカレンダーを取得できなかった場合は、その予定が消えたように見えるため比較しない。
+func (w *agendaWatcher) check(ctx context.Context, client calendarClient, h *webhook, calendarIDs []string, now time.Time) error {
+	agenda, err := fetchJSONAgenda(ctx, client, calendarIDs, now)
+	if err != nil {
+		return err
+	}
+	if len(agenda.Errors) > 0 {
+		return nil
+	}
+	events := map[string]jsonEvent{}
+	for _, e := range agenda.Events {
+		events[e.Calendar+"/"+e.ID] = e
+	}
+	if w.date != agenda.Date {
+		w.date, w.events = agenda.Date, events
+		return nil
+	}
+
+	changes := diffAgenda(w.events, events, agenda.Events)
+	if len(changes) == 0 {
+		return nil
+	}
+	slog.Info("Agenda changed", "date", agenda.Date, "changes", len(changes))
+	if err := h.post(ctx, webhookPayload{SchemaVersion: schema.Version, Date: agenda.Date, Changes: changes, Events: agenda.Events}); err != nil {
+		return err
+	}
+	w.events = events
+	return nil
+}
+
+// 前回と今回の予定を比べる（order は今回の予定を開始時刻順に並べたもの）
+func diffAgenda(prev, cur map[string]jsonEvent, order []jsonEvent) []agendaChange {
+	var changes []agendaChange
+	for _, e := range order {
+		old, ok := prev[e.Calendar+"/"+e.ID]
+		switch {
+		case !ok:
+			changes = append(changes, agendaChange{Type: changeAdded, Event: e})
+		case old.Start != e.Start || old.End != e.End:
+			changes = append(changes, agendaChange{Type: changeMoved, Event: e, PreviousStart: old.Start, PreviousEnd: old.End})
+		}
+	}
+	var cancelled []agendaChange
+	for key, old := range prev {
+		if _, ok := cur[key]; !ok {
+			cancelled = append(cancelled, agendaChange{Type: changeCancelled, Event: old})
+		}
+	}
+	sort.Slice(cancelled, func(i, j int) bool {
+		return cancelled[i].Event.Start < cancelled[j].Event.Start
+	})
+	return append(changes, cancelled...)
+}
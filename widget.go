@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"gcal-daily-agenda/schema"
+)
+
+// 1日分の予定をウィジェット向けに要約する（now は進行中・次の予定と残りの数に使う）
+func newWidget(agenda *jsonAgenda, now time.Time) *schema.Widget {
+	w := &schema.Widget{
+		SchemaVersion: schema.Version,
+		Date:          agenda.Date,
+		Count:         len(agenda.Events),
+		Events:        []schema.WidgetEvent{},
+	}
+	for _, e := range agenda.Events {
+		we := schema.WidgetEvent{
+			Title:    e.Summary,
+			Time:     "終日",
+			AllDay:   e.AllDay,
+			Color:    e.ColorHex,
+			Location: e.Location,
+		}
+		if !e.AllDay {
+			start, _ := time.Parse(time.RFC3339, e.Start)
+			end, _ := time.Parse(time.RFC3339, e.End)
+			start, end = start.In(now.Location()), end.In(now.Location())
+			we.Start, we.End = start.Format("15:04"), end.Format("15:04")
+			we.Time = we.Start + "-" + we.End
+			we.Done = !end.After(now)
+			if start.After(now) {
+				we.MinutesUntil = int(start.Sub(now).Round(time.Minute).Minutes())
+			}
+			if w.Current == nil && !now.Before(start) && now.Before(end) {
+				current := we
+				w.Current = &current
+			}
+			if w.Next == nil && start.After(now) {
+				next := we
+				w.Next = &next
+			}
+		} else if agenda.Date < now.Format("2006-01-02") {
+			we.Done = true
+		}
+		if !we.Done {
+			w.Remaining++
+		}
+		w.Events = append(w.Events, we)
+	}
+	return w
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// 端末に表示したときの幅（全角の文字は2、結合文字とゼロ幅の文字は0）
+//
+// 曖昧な幅の文字（「○」など）は多くの端末の既定に合わせて1として数える。
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r) || unicode.IsControl(r):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// 表示幅が n になるよう右側を空白で埋める（n より長ければそのまま）
+func padRight(s string, n int) string {
+	if w := displayWidth(s); w < n {
+		return s + strings.Repeat(" ", n-w)
+	}
+	return s
+}
+
+// ss のうち最も広い表示幅
+func maxWidth(ss []string) int {
+	n := 0
+	for _, s := range ss {
+		if w := displayWidth(s); w > n {
+			n = w
+		}
+	}
+	return n
+}
+
+// 表示幅が n を超える場合は末尾を「…」にして n に収める
+func truncateWidth(s string, n int) string {
+	if displayWidth(s) <= n {
+		return s
+	}
+	if n < 1 {
+		return ""
+	}
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if w+rw > n-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	return strings.TrimRight(b.String(), " ") + "…"
+}
+
+// 表示幅 n ごとに折り返す（2行目以降は indent を付ける）
+//
+// 空白があればそこで折り返し、なければ（日本語の文など）文字の途中で折り返す。
+func wrapWidth(s string, n int, indent string) []string {
+	var lines []string
+	prefix := ""
+	for {
+		limit := n - displayWidth(prefix)
+		if limit < 1 || displayWidth(s) <= limit {
+			return append(lines, prefix+s)
+		}
+		cut, lastSpace, w := 0, -1, 0
+		for i, r := range s {
+			rw := runeWidth(r)
+			if w+rw > limit {
+				cut = i
+				break
+			}
+			if r == ' ' {
+				lastSpace = i
+			}
+			w += rw
+		}
+		if lastSpace > 0 {
+			lines = append(lines, prefix+strings.TrimRight(s[:lastSpace], " "))
+			s = s[lastSpace+1:]
+		} else {
+			lines = append(lines, prefix+s[:cut])
+			s = s[cut:]
+		}
+		prefix = indent
+	}
+}
+
+// --max-width の値（"" は制限なし、auto は端末の幅か環境変数 COLUMNS）を桁数にする
+func resolveMaxWidth(mode string) (int, error) {
+	switch mode {
+	case "":
+		return 0, nil
+	case "auto":
+		if n := terminalWidth(os.Stdout); n > 0 {
+			return n, nil
+		}
+		n, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+		return n, nil
+	}
+	n, err := strconv.Atoi(mode)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid --max-width %q (expected a number of columns or auto)", mode)
+	}
+	return n, nil
+}
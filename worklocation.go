@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// 勤務場所の予定か
+func isWorkingLocation(item *calendar.Event) bool {
+	return item.EventType == "workingLocation"
+}
+
+// 勤務場所の予定を「🏢 オフィス（渋谷）」「🏠 在宅」の形式にする
+func workingLocationLabel(item *calendar.Event) string {
+	p := item.WorkingLocationProperties
+	if p == nil {
+		return "📍 " + item.Summary
+	}
+	switch p.Type {
+	case "homeOffice":
+		return "🏠 在宅"
+	case "officeLocation":
+		if o := p.OfficeLocation; o != nil && o.Label != "" {
+			return "🏢 オフィス（" + o.Label + "）"
+		} else if o != nil && o.BuildingId != "" {
+			return "🏢 オフィス（" + o.BuildingId + "）"
+		}
+		return "🏢 オフィス"
+	case "customLocation":
+		if p.CustomLocation != nil && p.CustomLocation.Label != "" {
+			return "📍 " + p.CustomLocation.Label
+		}
+	}
+	return "📍 " + item.Summary
+}
+
+// 1日の勤務場所（時間帯ごとに変わる場合は「🏠 在宅 09:00-12:00 / 🏢 オフィス 13:00-18:00」、なければ ""）
+func formatWorkingLocations(events []agendaEvent) string {
+	var parts []string
+	for _, e := range events {
+		if !isWorkingLocation(e.item) || e.item.Status == "cancelled" {
+			continue
+		}
+		label := workingLocationLabel(e.item)
+		if !e.allDay {
+			label += " " + e.start.Format("15:04") + "-" + e.end.Format("15:04")
+		}
+		parts = append(parts, label)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// 勤務場所の予定とそれ以外の予定に分ける
+func splitWorkingLocations(events []agendaEvent) (locations, others []agendaEvent) {
+	for _, e := range events {
+		if isWorkingLocation(e.item) {
+			locations = append(locations, e)
+		} else {
+			others = append(others, e)
+		}
+	}
+	return locations, others
+}
+
+// 日ごと（--week で週の各日）の勤務場所を表示する
+func setupWhere(fs *flag.FlagSet) func(args []string) error {
+	dateStr := fs.String("date", "", "Date to show (format: YYYY-MM-DD)")
+	week := fs.Bool("week", false, "Show each day of the week containing the date")
+	lang := fs.String("lang", "ja", "Language of the weekday (en, ja)")
+	fromFixture := fs.String("from-fixture", "", "Read events from a saved API response instead of calling Google")
+	return func(args []string) error {
+		date, err := parseDateFlag(*dateStr)
+		if err != nil {
+			return err
+		}
+		names, ok := weekdayNames[*lang]
+		if !ok {
+			return fmt.Errorf("Invalid --lang %q (use ja or en)", *lang)
+		}
+		from, to := date, date
+		if *week {
+			from, to = weekRange(date)
+		}
+
+		ctx := context.Background()
+		client, err := newClient(ctx, *fromFixture)
+		if err != nil {
+			return err
+		}
+		events, err := fetchRange(ctx, client, from, to)
+		if err != nil {
+			return err
+		}
+		normalized := normalizeEvents("primary", events.Items)
+		for _, day := range daysBetween(from, to) {
+			printWhere(os.Stdout, day.Format("01-02")+"("+names[day.Weekday()]+")", selectDay(normalized, day))
+		}
+		return nil
+	}
+}
+
+func printWhere(w io.Writer, label string, events []agendaEvent) {
+	where := formatWorkingLocations(sortEvents(events, "start", false))
+	if where == "" {
+		where = "未設定"
+	}
+	fmt.Fprintf(w, "%s %s\n", label, where)
+}